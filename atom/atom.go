@@ -2,13 +2,10 @@ package atom
 
 import (
 	"encoding/base64"
-	"encoding/hex"
 	"fmt"
 	"math"
 	"math/big"
-	"strconv"
 	"strings"
-	"time"
 )
 
 // An Aura is a type hint that controls how an Atom is printed.
@@ -17,10 +14,16 @@ type Aura string
 // Atom auras.
 const (
 	AuraAtom = ""    // no aura
+	AuraC    = "c"   // UTF-32 codepoint
 	AuraD    = "d"   // date
 	AuraDA   = "da"  // absolute date
 	AuraDR   = "dr"  // relative date
+	AuraF    = "f"   // loobean
+	AuraIF   = "if"  // IPv4 address
+	AuraIS   = "is"  // IPv6 address
+	AuraN    = "n"   // nil
 	AuraP    = "p"   // phonemic base (ship name)
+	AuraQ    = "q"   // phonemic base, unscrambled
 	AuraR    = "r"   // IEEE floating-point
 	AuraRD   = "rd"  // double precision  (64 bits)
 	AuraRH   = "rh"  // half precision (16 bits)
@@ -37,6 +40,7 @@ const (
 	AuraTAS  = "tas" // ASCII text symbol (term)
 	AuraU    = "u"   // unsigned integer
 	AuraUB   = "ub"  // unsigned binary
+	AuraUC   = "uc"  // bitcoin address
 	AuraUD   = "ud"  // unsigned decimal
 	AuraUV   = "uv"  // unsigned base32
 	AuraUW   = "uw"  // unsigned base64
@@ -57,55 +61,102 @@ type Atom struct {
 var uwEnc = base64.NewEncoding("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-~")
 
 // String implements fmt.Stringer, rendering the Atom according to its aura.
+// If the aura is not supported, the atom is rendered in hex, prefixed by an
+// error marker.
 func (a Atom) String() string {
+	s, err := a.Render()
+	if err != nil {
+		return fmt.Sprintf("%%!(BADAURA=@%v)0x%x", a.aura, a.i)
+	}
+	return s
+}
+
+// Render renders the Atom according to its aura, returning an error if the
+// aura is not supported or the atom is not a valid value of that aura.
+func (a Atom) Render() (string, error) {
+	if c, ok := lookupAura(a.aura); ok {
+		return c.Format(a)
+	}
 	if a.aura.NestsIn("s") {
-		n := new(big.Int).Add(a.i, big.NewInt(1))
-		u := Atom{n.Rsh(n, 1), "u" + a.aura[1:]}
-		return "--"[a.i.Bit(0):] + u.String()
+		n := a.Signed()
+		u, err := Atom{n.Abs(n), "u" + a.aura[1:]}.Render()
+		return "--"[a.i.Bit(0):] + u, err
 	}
 
-	switch a.aura {
+	// ignore size suffixes, e.g. @tD or @uxG
+	aura := Aura(strings.TrimRight(string(a.aura), "ABCDEFGHIJKLMNOPQRSTUVWXYZ"))
+	switch aura {
 	default:
-		panic("unsupported aura")
+		return "", fmt.Errorf("unsupported aura @%v", a.aura)
+	case AuraC:
+		return "~-" + wood(utf32Runes(a.i)), nil
+	case AuraF:
+		switch {
+		case a.i.BitLen() == 0:
+			return "&", nil
+		case a.i.BitLen() == 1:
+			return "|", nil
+		}
+	case AuraIF:
+		if a.i.BitLen() <= 32 {
+			return formatIF(a.i), nil
+		}
+	case AuraIS:
+		if a.i.BitLen() <= 128 {
+			return formatIS(a.i), nil
+		}
+	case AuraN:
+		if a.i.BitLen() == 0 {
+			return "~", nil
+		}
 	case AuraP:
-		return formatP(a.i)
+		return formatP(a.i), nil
+	case AuraQ:
+		return formatQ(a.i), nil
 	case AuraDA:
-		return "~" + formatDate(a.i)
+		return "~" + formatDate(a.i), nil
 	case AuraDR:
-		return "~" + formatDuration(a.i)
+		return "~" + formatDuration(a.i), nil
 	case AuraD, AuraR:
-		return "0x" + a.i.Text(16)
+		return "0x" + a.i.Text(16), nil
 	case AuraRD:
-		return ".~" + formatFloat(math.Float64frombits(a.i.Uint64()), 64)
-	case AuraRH:
-		return ".~~" + float16(uint16(a.i.Uint64()))
+		return ".~" + formatFloat(math.Float64frombits(a.i.Uint64()), 64), nil
 	case AuraRS:
-		return "." + formatFloat(float64(math.Float32frombits(uint32(a.i.Uint64()))), 32)
-	case AuraRQ:
-		return ".~~~" + float128(a.i)
+		return "." + formatFloat(float64(math.Float32frombits(uint32(a.i.Uint64()))), 32), nil
+	case AuraRH, AuraRQ:
+		f := floatFormats[aura]
+		return f.prefix + formatBigFloat(a.i, f), nil
 	case AuraT:
-		return "'" + string(flip(a.i.Bytes())) + "'"
+		return Quote(a.Text(), '\''), nil
 	case AuraTA:
-		return "~." + string(flip(a.i.Bytes()))
+		if s := a.Text(); validKnot(s) {
+			return "~." + s, nil
+		}
+		return "~." + wood(textRunes(a.Text())), nil
 	case AuraTAS:
 		if a.i.BitLen() == 0 {
-			return "%$"
+			return "%$", nil
+		} else if s := a.Text(); validTerm(s) {
+			return "%" + s, nil
 		}
-		return "%" + string(flip(a.i.Bytes()))
+		return "`@tas`" + Quote(a.Text(), '\''), nil
 	case AuraAtom, AuraU, AuraUD:
-		return formatInt(a.i.Text(10), 3)
+		return formatInt(a.i.Text(10), 3), nil
 	case AuraUB:
-		return "0b" + formatInt(a.i.Text(2), 4)
+		return "0b" + formatInt(a.i.Text(2), 4), nil
+	case AuraUC:
+		return "0c" + formatUC(a.i), nil
 	case AuraUV:
-		return "0v" + formatInt(a.i.Text(32), 5)
+		return "0v" + formatInt(a.i.Text(32), 5), nil
 	case AuraUW:
 		if a.i.BitLen() == 0 {
-			return "0w0"
+			return "0w0", nil
 		}
-		return "0w" + formatInt(uwEnc.EncodeToString(pad(a.i.Bytes(), 3)), 5)
+		return "0w" + formatInt(uwEnc.EncodeToString(pad(a.i.Bytes(), 3)), 5), nil
 	case AuraUX:
-		return "0x" + formatInt(a.i.Text(16), 4)
+		return "0x" + formatInt(a.i.Text(16), 4), nil
 	}
+	return "", fmt.Errorf("%v is not a valid @%v", a.i, a.aura)
 }
 
 // Cast returns a copy of a with the specified aura.
@@ -116,6 +167,11 @@ func (a Atom) Cast(aura Aura) Atom {
 	}
 }
 
+// Aura returns the aura of a.
+func (a Atom) Aura() Aura {
+	return a.aura
+}
+
 // Format is shorthand for a.Cast(aura).String().
 func (a Atom) Format(aura Aura) string {
 	return a.Cast(aura).String()
@@ -139,6 +195,12 @@ func FromBytes(b []byte) Atom {
 	return New(new(big.Int).SetBytes(b))
 }
 
+// Bytes returns the big-endian byte representation of a, as accepted by
+// FromBytes.
+func (a Atom) Bytes() []byte {
+	return a.i.Bytes()
+}
+
 func pad(b []byte, n int) []byte {
 	for len(b)%n != 0 {
 		b = append([]byte{0}, b...)
@@ -183,130 +245,6 @@ func formatP(a *big.Int) string {
 	return buf.String()
 }
 
-func formatFloat(f float64, bits int) string {
-	s := strings.ToLower(strconv.FormatFloat(f, 'e', -1, bits))
-	if !strings.Contains(s, "e") {
-		return strings.TrimPrefix(s, "+") // inf or nan
-	}
-	t := strings.Split(s, "e")
-	frac := t[0]
-	sign := strings.TrimPrefix(t[1][:1], "+")
-	exp := strings.TrimLeft(t[1][1:], "0")
-	return strings.TrimSuffix(frac+"e"+sign+exp, "e")
-}
-
-func float16(bits uint16) string {
-	sign := ""
-	if bits&0x8000 != 0 {
-		sign = "-"
-	}
-	exp := int(bits & 0x7C00 >> 10)
-	frac := bits & 0x03FF
-	x := new(big.Float).SetPrec(11)
-	lead := 1
-	exponent := exp - 15
-	switch exp {
-	case 0x1F:
-		if frac == 0 {
-			return sign + "inf"
-		}
-		return "nan"
-	case 0x00:
-		if frac == 0 {
-			return sign + "0"
-		}
-		lead = 0
-		exponent = -14
-	}
-	x.Parse(fmt.Sprintf("%s0b%d.%010bp%d", sign, lead, frac, exponent), 0)
-	t := strings.Split(x.Text('e', -1), "e")
-	sign = strings.TrimLeft(t[1][:1], "+")
-	t[1] = strings.TrimLeft(t[1][1:], "0")
-	if t[1] == "" {
-		return t[0]
-	}
-	return t[0] + "e" + sign + t[1]
-}
-
-func float128(i *big.Int) string {
-	b := i.Uint64()
-	a := new(big.Int).Rsh(i, 64).Uint64()
-
-	sign := ""
-	if a&0x8000000000000000 != 0 {
-		sign = "-"
-	}
-	exp := int(a&0x7FFF000000000000) >> 48
-	frac1, frac2 := a&0x0000FFFFFFFFFFFF, b
-	x := new(big.Float).SetPrec(113)
-	lead := 1
-	exponent := exp - 16383
-	switch exp {
-	case 0x7FFF:
-		if frac1 == 0 && frac2 == 0 {
-			return sign + "inf"
-		}
-		return "nan"
-	case 0x00:
-		if frac1 == 0 && frac2 == 0 {
-			return sign + "0"
-		}
-		lead = 0
-		exponent = -16382
-	}
-	x.Parse(fmt.Sprintf("%s0b%d.%sp%d", sign, lead, fmt.Sprintf("%048b%064b", frac1, frac2), exponent), 0)
-	t := strings.Split(x.Text('e', -1), "e")
-	sign = strings.TrimLeft(t[1][:1], "+")
-	t[1] = strings.TrimLeft(t[1][1:], "0")
-	if t[1] == "" {
-		return t[0]
-	}
-	return t[0] + "e" + sign + t[1]
-}
-
-var jesus = time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
-var oneSec = new(big.Int).Lsh(big.NewInt(1), 64)
-
-func formatDate(a *big.Int) string {
-	secs, rem := new(big.Int).QuoRem(a, oneSec, new(big.Int))
-	date := time.Unix(jesus+int64(secs.Uint64())-9223372029693628800, 0).UTC().Format("2006.1.2..15.04.05")
-	frac := formatInt("."+hex.EncodeToString(rem.Bytes()), 4)
-	return strings.TrimRight(strings.TrimLeft(date, "0"), ".0") + strings.TrimRight(frac, ".0")
-}
-
-func formatDuration(a *big.Int) string {
-	if a.BitLen() == 0 {
-		return "s0"
-	}
-	secs, rem := new(big.Int).QuoRem(a, oneSec, new(big.Int))
-	mins, secRem := new(big.Int).QuoRem(secs, big.NewInt(60), new(big.Int))
-	hrs, minRem := new(big.Int).QuoRem(mins, big.NewInt(60), new(big.Int))
-	days, hrRem := new(big.Int).QuoRem(hrs, big.NewInt(24), new(big.Int))
-	var sb strings.Builder
-	if days.BitLen() > 0 {
-		sb.WriteString(".d" + days.String())
-	}
-	if hrRem.BitLen() > 0 {
-		sb.WriteString(".h" + hrRem.String())
-	}
-	if minRem.BitLen() > 0 {
-		sb.WriteString(".m" + minRem.String())
-	}
-	if secRem.BitLen() > 0 {
-		sb.WriteString(".s" + secRem.String())
-	}
-	if rem.BitLen() > 0 {
-		if sb.Len() == 0 {
-			sb.WriteString("s0")
-		}
-		buf := []byte(".0000000000000000")
-		rt := rem.Text(16)
-		copy(buf[len(buf)-len(rt):], rt)
-		sb.WriteString(formatInt(string(buf), 4))
-	}
-	return strings.TrimPrefix(sb.String(), ".")
-}
-
 func formatInt(s string, n int) string {
 	if s == "0" {
 		return "0"
@@ -1,8 +1,15 @@
 package atom
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
 	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestFormat(t *testing.T) {
@@ -42,7 +49,7 @@ func TestFormat(t *testing.T) {
 				"sx":  "--0x1c",
 				"t":   "'8'",
 				"ta":  "~.8",
-				"tas": "%8",
+				"tas": "`@tas`'8'",
 				"u":   "56",
 				"ub":  "0b11.1000",
 				"uv":  "0v1o",
@@ -92,7 +99,7 @@ func TestFormat(t *testing.T) {
 				"r":  "0x5f5e138",
 				"rd": ".~4.94065923e-316",
 				"rh": ".~~-6.68e2",
-				// "rq": ".~~~6.47517875e-4958",
+				"rq": ".~~~6.47517875e-4958",
 				"rs": ".2.3122422e-35",
 				"s":  "--50.000.028",
 				"sb": "--0b10.1111.1010.1111.0000.1001.1100",
@@ -165,8 +172,8 @@ func TestFormat(t *testing.T) {
 			dec: "8684515",
 			exp: map[Aura]string{
 				"t":   "'ツ'",
-				"ta":  "~.ツ",
-				"tas": "%ツ",
+				"ta":  "~.~30c4.",
+				"tas": "`@tas`'ツ'",
 			},
 		},
 	}
@@ -190,7 +197,10 @@ func TestFloat(t *testing.T) {
 			aura: "rq",
 			exp: map[string]string{
 				"0x0":                                ".~~~0",
-				"0x1":                                ".~~~6.475175119438025110924438958227647e-4966",
+				"0x1":                                ".~~~6e-4966",
+				"0xffffffffffffffffffffffffffff":     ".~~~3.362103143112093506262677817321752e-4932",
+				"0x10000000000000000000000000000":    ".~~~3.3621031431120935062626778173217526e-4932",
+				"0x7ffeffffffffffffffffffffffffffff": ".~~~1.189731495357231765085759326628007e4932",
 				"0x3fff0000000000000000000000000000": ".~~~1",
 				"0x80000000000000000000000000000000": ".~~~-0",
 				"0x7fff0000000000000000000000000000": ".~~~inf",
@@ -203,6 +213,10 @@ func TestFloat(t *testing.T) {
 			exp: map[string]string{
 				"0x0":        ".0",
 				"0x1":        ".1e-45",
+				"0x80000001": ".-1e-45",
+				"0x7fffff":   ".1.1754942e-38",
+				"0x800000":   ".1.1754944e-38",
+				"0x7f7fffff": ".3.4028235e38",
 				"0x3f800000": ".1",
 				"0x80000000": ".-0",
 				"0x7f800000": ".inf",
@@ -210,11 +224,26 @@ func TestFloat(t *testing.T) {
 				"0x7fc00000": ".nan",
 			},
 		},
+		{
+			aura: "rd",
+			exp: map[string]string{
+				"0x1":                ".~5e-324",
+				"0xfffffffffffff":    ".~2.225073858507201e-308",
+				"0x10000000000000":   ".~2.2250738585072014e-308",
+				"0x7fefffffffffffff": ".~1.7976931348623157e308",
+				"0xfff0000000000000": ".~-inf",
+				"0x7ff8000000000000": ".~nan",
+			},
+		},
 		{
 			aura: "rh",
 			exp: map[string]string{
 				"0x0":    ".~~0",
-				"0x1":    ".~~5.96e-8",
+				"0x1":    ".~~6e-8",
+				"0x8001": ".~~-6e-8",
+				"0x3ff":  ".~~6.1e-5",
+				"0x400":  ".~~6.104e-5",
+				"0x7bff": ".~~6.55e4",
 				"0x3c00": ".~~1",
 				"0x8000": ".~~-0",
 				"0x7c00": ".~~inf",
@@ -250,6 +279,34 @@ func TestDateAbsolute(t *testing.T) {
 			hex: "0x7ffffffe570c16800000000000000000",
 			exp: "~1.1.1",
 		},
+		{
+			hex: "0x8000000d2da5bd000000000000000000",
+			exp: "~2020.7.10",
+		},
+		{
+			hex: "0x8000000d2da5de020000000000000000",
+			exp: "~2020.7.10..02.20.50",
+		},
+		{
+			hex: "0x8000000d2da5bd000000000000000001",
+			exp: "~2020.7.10..00.00.00..0000.0000.0000.0001",
+		},
+		{
+			hex: "0x7ffffffe570ac5000000000000000000",
+			exp: "~1-.12.31",
+		},
+		{
+			hex: "0x0",
+			exp: "~292277024401-.1.1",
+		},
+		{
+			hex: "0xffffffffffffffffffffffffffffffff",
+			exp: "~292277024853.11.8..07.00.15..ffff.ffff.ffff.ffff",
+		},
+		{
+			hex: "0x100000000000000000000000000000000",
+			exp: "~292277024853.11.8..07.00.16",
+		},
 	}
 	for _, test := range testCases {
 		i, _ := new(big.Int).SetString(test.hex, 0)
@@ -257,5 +314,614 @@ func TestDateAbsolute(t *testing.T) {
 		if got != test.exp {
 			t.Errorf("`@%v`%v\nexp: %v\ngot: %v", "da", test.hex, test.exp, got)
 		}
+		if p, err := parseDate(test.exp[1:]); err != nil {
+			t.Errorf("%v: %v", test.exp, err)
+		} else if p.Cmp(i) != 0 {
+			t.Errorf("%v: round trip failed: %#x", test.exp, p)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	times := []time.Time{
+		time.Unix(0, 0),
+		time.Date(2020, 7, 7, 2, 47, 37, 7654321, time.UTC),
+		time.Date(-500, 3, 1, 0, 0, 0, 1, time.UTC),
+		time.Date(1e9, 12, 31, 23, 59, 59, 999999999, time.UTC),
+	}
+	for _, tt := range times {
+		if got := FromTime(tt).Time(); !got.Equal(tt) {
+			t.Errorf("round trip failed: exp %v, got %v", tt, got)
+		}
+	}
+	if s := FromTime(time.Unix(0, 0)).String(); s != "~1970.1.1" {
+		t.Errorf("wrong Unix epoch: %v", s)
+	}
+	if s := FromTime(time.Date(-1, 1, 1, 0, 0, 0, 0, time.UTC)).String(); s != "~2-.1.1" {
+		t.Errorf("wrong BC date: %v", s)
+	}
+	if s := FromTime(time.Unix(1, 5e8)).String(); s != "~1970.1.1..00.00.01..8000" {
+		t.Errorf("wrong fractional date: %v", s)
+	}
+	far := New(new(big.Int).Lsh(big.NewInt(1), 200)).Time()
+	if far.Year() < 1e9 {
+		t.Errorf("far-future date was not clamped: %v", far)
+	}
+	if past := New64(0).Time(); past.Year() > -1e9 || !past.Equal(time.Unix(minUnix.Int64(), 0)) {
+		t.Errorf("far-past date was not clamped: %v", past)
+	}
+	if bc, err := ParseAs(AuraDA, "~500-.3.1..12.00.00"); err != nil {
+		t.Error(err)
+	} else if exp := time.Date(-499, 3, 1, 12, 0, 0, 0, time.UTC); !bc.Time().Equal(exp) {
+		t.Errorf("wrong BC date: exp %v, got %v", exp, bc.Time())
+	}
+
+	durations := []time.Duration{0, 1, time.Second / 3, 90 * time.Minute, 1e6 * time.Hour, math.MaxInt64}
+	for _, d := range durations {
+		if got := FromDuration(d).Duration(); got != d {
+			t.Errorf("round trip failed: exp %v, got %v", d, got)
+		}
+		if p, err := parseDuration(FromDuration(d).String()[1:]); err != nil || p.Cmp(FromDuration(d).i) != 0 {
+			t.Errorf("%v: parse round trip failed: %v", d, err)
+		}
+	}
+	if s := FromDuration(25*time.Hour + time.Second/2).String(); s != "~d1.h1..8000" {
+		t.Errorf("wrong duration: %v", s)
+	}
+	if d := New(new(big.Int).Lsh(big.NewInt(1), 200)).Duration(); d != math.MaxInt64 {
+		t.Errorf("long duration was not clamped: %v", d)
+	}
+	for _, s := range []string{"2020.13.1", "2021.2.29", "2020.1.1..24.00.00", "0.1.1", "2020.1.1..00.00.00..fffff"} {
+		if _, err := parseDate(s); err == nil {
+			t.Errorf("expected error for ~%v", s)
+		}
+	}
+	for _, s := range []string{"", "s", "s1.m1", "s01", "s1..FFFF"} {
+		if _, err := parseDuration(s); err == nil {
+			t.Errorf("expected error for ~%v", s)
+		}
+	}
+}
+
+func TestDateArithmetic(t *testing.T) {
+	start := FromTime(time.Date(2020, 7, 7, 0, 0, 0, 0, time.UTC))
+	tick := New64(1).Cast(AuraDR) // 2^-64 seconds
+	end := start.Add(FromDuration(time.Hour)).Add(tick)
+	if s := end.String(); s != "~2020.7.7..01.00.00..0000.0000.0000.0001" {
+		t.Errorf("wrong sum: %v", s)
+	}
+	diff := end.Sub(start)
+	if s := diff.String(); s != "~h1..0000.0000.0000.0001" {
+		t.Errorf("wrong difference: %v", s)
+	}
+	if end.Sub(diff).Cmp(start) != 0 || start.Cmp(end) != -1 {
+		t.Error("arithmetic is inconsistent")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on underflow")
+		}
+	}()
+	start.Sub(end)
+}
+
+func TestFloatHalfExhaustive(t *testing.T) {
+	// the shortest digits that round to each value, as ++drg:rh prints
+	// them; subnormals are evenly spaced, so a subnormal power of two has
+	// no closer lower neighbor
+	golden := map[uint16]string{
+		0x0001: ".~~6e-8",
+		0x0002: ".~~1e-7",
+		0x0004: ".~~2.4e-7",
+		0x0080: ".~~7.6e-6",
+		0x0200: ".~~3.05e-5",
+		0x03fe: ".~~6.09e-5",
+		0x03ff: ".~~6.1e-5",
+		0x0400: ".~~6.104e-5",
+		0x0401: ".~~6.11e-5",
+		0x2e66: ".~~1e-1",
+		0x3555: ".~~3.333e-1",
+		0x3bff: ".~~9.995e-1",
+		0x3c00: ".~~1",
+		0x3c01: ".~~1.001",
+		0x4248: ".~~3.14",
+		0x5640: ".~~1e2",
+		0x6400: ".~~1.024e3",
+		0x7800: ".~~3.277e4",
+		0x7bfe: ".~~6.547e4",
+		0x7bff: ".~~6.55e4",
+		0x8001: ".~~-6e-8",
+		0xfbff: ".~~-6.55e4",
+	}
+	for i, exp := range golden {
+		if got := New64(uint64(i)).Cast(AuraRH).String(); got != exp {
+			t.Errorf("%#x: expected %v, got %v", i, exp, got)
+		}
+	}
+
+	// reference conversion from half to single precision
+	toFloat32 := func(h uint16) float32 {
+		sign := uint32(h>>15) << 31
+		exp := uint32(h>>10) & 0x1F
+		frac := uint32(h) & 0x3FF
+		switch exp {
+		case 0x1F:
+			return math.Float32frombits(sign | 0x7F800000 | frac<<13)
+		case 0:
+			f := float32(frac) * float32(math.Pow(2, -24))
+			if sign != 0 {
+				f = -f
+			}
+			return f
+		}
+		return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+	}
+
+	for i := 0; i < 1<<16; i++ {
+		a := New64(uint64(i)).Cast(AuraRH)
+		s := a.String()
+		p, err := ParseFloat(s)
+		if err != nil {
+			t.Fatalf("%#x: %v", i, err)
+		}
+		ref := toFloat32(uint16(i))
+		if ref != ref {
+			if s != ".~~nan" || p.i.Uint64() != 0x7e00 {
+				t.Fatalf("%#x: NaN did not round-trip: %v", i, s)
+			}
+			continue
+		} else if p.i.Uint64() != uint64(i) {
+			t.Fatalf("%#x: round trip failed: %v -> %#x", i, s, p.i)
+		}
+		// the rendered value must round to the same half when parsed as a
+		// float32 (rendered values have at most 5 significant digits)
+		f, err := strconv.ParseFloat(strings.TrimPrefix(s, ".~~"), 32)
+		if err != nil {
+			t.Fatalf("%#x: %v", i, err)
+		}
+		h, _ := NewBigFloat(big.NewFloat(float64(ref)), AuraRH)
+		if h.i.Uint64() != uint64(i) {
+			t.Fatalf("%#x: NewBigFloat(%v) = %#x", i, ref, h.i)
+		} else if math.Abs(f-float64(ref)) > math.Max(math.Abs(float64(ref))*1e-3, 0x1p-25) {
+			t.Fatalf("%#x: rendered %v, expected ~%v", i, s, ref)
+		}
+	}
+}
+
+func TestFloatConversion(t *testing.T) {
+	if s := NewFloat32(1.5).String(); s != ".1.5" {
+		t.Errorf("wrong @rs: %v", s)
+	}
+	if s := NewFloat64(-0.1).String(); s != ".~-1e-1" {
+		t.Errorf("wrong @rd: %v", s)
+	}
+	nan := math.Float64frombits(0xfff0000000000123)
+	if b := NewFloat64(nan).i.Uint64(); b != 0xfff0000000000123 {
+		t.Errorf("NaN payload was not preserved: %#x", b)
+	}
+	if _, err := NewBigFloat(big.NewFloat(1), AuraUD); err == nil {
+		t.Error("expected error for non-float aura")
+	}
+
+	q, _ := NewBigFloat(new(big.Float).SetPrec(200).SetFloat64(1), AuraRQ)
+	if s := q.String(); s != ".~~~1" || q.i.Text(16) != "3fff0000000000000000000000000000" {
+		t.Errorf("wrong @rq: %v (%x)", s, q.i)
+	}
+	inf, _ := NewBigFloat(new(big.Float).SetInf(true), AuraRH)
+	if s := inf.String(); s != ".~~-inf" {
+		t.Errorf("wrong infinity: %v", s)
+	}
+	negZero, _ := NewBigFloat(new(big.Float).Neg(new(big.Float)), AuraRS)
+	if s := negZero.String(); s != ".-0" {
+		t.Errorf("wrong negative zero: %v", s)
+	}
+}
+
+func TestParseFloat(t *testing.T) {
+	// parsing must agree with strconv, including subnormals and overflow
+	rng := rand.New(rand.NewSource(0))
+	lits := []string{"0", "1", "3.14", "1e-45", "7e-46", "1e-46", "3.4028235e38", "3.4028236e38", "1e39", "4.9e-324", "2.4e-324", "1.7976931348623157e308", "1e309", "2.2250738585072011e-308"}
+	for i := 0; i < 1000; i++ {
+		lits = append(lits, strconv.FormatFloat(rng.NormFloat64()*math.Pow(10, float64(rng.Intn(80)-40)), 'e', rng.Intn(20), 64))
+	}
+	for _, lit := range lits {
+		lit = strings.Replace(lit, "e+", "e", 1)
+		lit = strings.Replace(lit, "e-0", "e-", 1)
+		f32, _ := strconv.ParseFloat(lit, 32)
+		if a, err := ParseFloat("." + lit); err != nil {
+			t.Errorf("%v: %v", lit, err)
+		} else if a.i.Uint64() != uint64(math.Float32bits(float32(f32))) {
+			t.Errorf("@rs %v: expected %#x, got %#x", lit, math.Float32bits(float32(f32)), a.i)
+		}
+		f64, _ := strconv.ParseFloat(lit, 64)
+		if a, err := ParseFloat(".~" + lit); err != nil {
+			t.Errorf("%v: %v", lit, err)
+		} else if a.i.Uint64() != math.Float64bits(f64) {
+			t.Errorf("@rd %v: expected %#x, got %#x", lit, math.Float64bits(f64), a.i)
+		}
+	}
+
+	for _, s := range []string{".~~~1", ".~~~-inf", ".~~~nan", ".~~~6e-4966", ".~~-6e-8"} {
+		a, err := ParseFloat(s)
+		if err != nil {
+			t.Error(err)
+		} else if a.String() != s {
+			t.Errorf("round trip failed: %v -> %v", s, a)
+		}
+	}
+	for _, s := range []string{"", "1", ".", ".~", ".1.", ".1.2.3", ".-nan", ".1e", ".1.5e+3", ". 1"} {
+		if _, err := ParseFloat(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestSigned(t *testing.T) {
+	for _, i := range []int64{0, 1, -1, 2, -2, 62565, -31283, math.MaxInt64, math.MinInt64} {
+		a := NewSigned64(i)
+		if a.Signed().Int64() != i {
+			t.Errorf("%v: round trip failed: %v", i, a.Signed())
+		}
+		for _, aura := range []Aura{AuraSB, AuraSD, AuraSV, AuraSW, AuraSX} {
+			s := a.Format(aura)
+			p, err := ParseSigned(s)
+			if err != nil {
+				t.Errorf("%v: %v", s, err)
+			} else if p.Cmp(a) != 0 || p.aura != aura {
+				t.Errorf("%v: round trip failed: %v (@%v)", s, p.Signed(), p.aura)
+			}
+		}
+	}
+	if a := NewSigned64(-3); a.i.Int64() != 5 || a.String() != "-3" {
+		t.Errorf("wrong encoding of -3: %v", a.i)
+	}
+	for _, s := range []string{"-0", "-0x0", "-0b0"} {
+		if a, err := ParseSigned(s); err != nil {
+			t.Errorf("%v: %v", s, err)
+		} else if a.i.Sign() != 0 || a.String() != "-"+s {
+			t.Errorf("%v: expected zero, got %v", s, a)
+		}
+	}
+	for _, s := range []string{"", "1", "---1", "--01", "--1.00", "--0x.1", "--0xg", "-0w", "--1000"} {
+		if _, err := ParseSigned(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestSi(t *testing.T) {
+	n := NewSigned64
+	tests := []struct {
+		got  Atom
+		want int64
+	}{
+		{Si.Sum(n(5), n(-7)), -2},
+		{Si.Dif(n(5), n(-7)), 12},
+		{Si.Pro(n(-5), n(-7)), 35},
+		{Si.Fra(n(-7), n(2)), -3},
+		{Si.Fra(n(7), n(-2)), -3},
+		{Si.Rem(n(-7), n(2)), -1},
+		{Si.Rem(n(7), n(-2)), 1},
+	}
+	for i, test := range tests {
+		if test.got.Signed().Int64() != test.want {
+			t.Errorf("%v: expected %v, got %v", i, test.want, test.got.Signed())
+		}
+	}
+	if a := Si.Abs(n(-9)); a.String() != "9" {
+		t.Errorf("wrong abs: %v", a)
+	}
+	if Si.Cmp(n(-9), n(1)) != -1 || Si.Cmp(n(3), n(3)) != 0 || Si.Cmp(n(3), n(-4)) != 1 {
+		t.Error("wrong cmp")
+	}
+	if a := Si.Sum(n(1).Cast(AuraSX), n(255)); a.String() != "--0x100" {
+		t.Errorf("wrong aura: %v", a)
+	}
+	if a := Si.Sum(New64(4), n(1)); a.String() != "--3" {
+		t.Errorf("wrong aura: %v", a)
+	}
+}
+
+func TestText(t *testing.T) {
+	cords := map[string]string{
+		"foo":       "'foo'",
+		"it's":      `'it\'s'`,
+		`a\b`:       `'a\\b'`,
+		"line\n":    `'line\0a'`,
+		"ツ":         "'ツ'",
+		"\x7f":      `'\7f'`,
+		"":          "''",
+		"tab\there": `'tab\09here'`,
+	}
+	for s, exp := range cords {
+		a, err := Cord(s)
+		if err != nil {
+			t.Fatal(err)
+		} else if got := a.String(); got != exp {
+			t.Errorf("Cord(%q): expected %v, got %v", s, exp, got)
+		} else if a.Text() != s {
+			t.Errorf("Cord(%q): round trip failed", s)
+		}
+	}
+	if _, err := Cord("\xff"); err == nil {
+		t.Error("expected error for invalid UTF-8")
+	}
+	if s := FromBytes([]byte{0xff}).Format(AuraT); s != `'\ff'` {
+		t.Errorf("wrong rendering of invalid UTF-8: %v", s)
+	}
+	if s := Quote(`it's "x"`, '"'); s != `"it's \"x\""` {
+		t.Errorf("wrong tape rendering: %v", s)
+	}
+
+	for _, s := range []string{"", "foo", "a.b_c~d-0"} {
+		a, err := Knot(s)
+		if err != nil {
+			t.Error(err)
+		} else if a.String() != "~."+s {
+			t.Errorf("Knot(%q): got %v", s, a)
+		}
+	}
+	for _, s := range []string{"Foo", "a b", "ツ"} {
+		if _, err := Knot(s); err == nil {
+			t.Errorf("Knot(%q): expected error", s)
+		}
+	}
+	knots := map[string]string{
+		"Foo":   "~.~46.oo",
+		"a b.c": "~.a.b~.c",
+		"x~y!":  "~.x~~y~21.",
+	}
+	for s, exp := range knots {
+		if got := fromText(s, AuraTA).String(); got != exp {
+			t.Errorf("@ta %q: expected %v, got %v", s, exp, got)
+		}
+	}
+
+	for _, s := range []string{"", "foo", "foo-bar2"} {
+		a, err := Term(s)
+		if err != nil {
+			t.Error(err)
+		} else if s != "" && a.String() != "%"+s {
+			t.Errorf("Term(%q): got %v", s, a)
+		}
+	}
+	for _, s := range []string{"Foo", "2foo", "-foo", "foo_bar", "foo.bar"} {
+		if _, err := Term(s); err == nil {
+			t.Errorf("Term(%q): expected error", s)
+		}
+	}
+	if s := fromText("Foo", AuraTAS).String(); s != "`@tas`'Foo'" {
+		t.Errorf("wrong rendering of invalid term: %v", s)
+	}
+	if s := New64('a').Cast("tD").String(); s != "'a'" {
+		t.Errorf("wrong rendering of @tD: %v", s)
+	}
+}
+
+func TestAuras(t *testing.T) {
+	tests := []struct {
+		aura Aura
+		hex  string
+		exp  string
+	}{
+		{AuraIF, "0x7f000001", ".127.0.0.1"},
+		{AuraIF, "0x0", ".0.0.0.0"},
+		{AuraIS, "0x1", ".0.0.0.0.0.0.0.1"},
+		{AuraIS, "0x20010db8000000000000ff0000428329", ".2001.db8.0.0.0.ff00.42.8329"},
+		{AuraF, "0x0", "&"},
+		{AuraF, "0x1", "|"},
+		{AuraN, "0x0", "~"},
+		{AuraC, "0x61", "~-a"},
+		{AuraC, "0x6200000061", "~-ab"},
+		{AuraC, "0x30c4", "~-~30c4."},
+		{AuraQ, "0x0", ".~zod"},
+		{AuraQ, "0x100", ".~marzod"},
+		{AuraQ, "0x10000", ".~nec-dozzod"},
+		{AuraQ, "0x1020304", ".~marbud-wansev"},
+		{AuraUC, "0x7680adec8eabcabac676be9e83854ade0bd22cdb", "0c1BoatSLRHtKNngkdXEeobR76b53LETtpyT"},
+	}
+	for _, test := range tests {
+		i, _ := new(big.Int).SetString(test.hex, 0)
+		a := Atom{i: i, aura: test.aura}
+		got, err := a.Render()
+		if err != nil {
+			t.Errorf("@%v %v: %v", test.aura, test.hex, err)
+			continue
+		} else if got != test.exp {
+			t.Errorf("@%v %v: expected %v, got %v", test.aura, test.hex, test.exp, got)
+		}
+		p, err := ParseAs(test.aura, got)
+		if err != nil {
+			t.Errorf("@%v %v: %v", test.aura, got, err)
+		} else if p.Cmp(a) != 0 {
+			t.Errorf("@%v %v: round trip failed: %v", test.aura, got, p.i)
+		}
+	}
+
+	// invalid values fall back to hex
+	for _, a := range []Atom{New64(2).Cast(AuraF), New64(1).Cast(AuraN), New64(1 << 32).Cast(AuraIF), New64(5).Cast("zz")} {
+		if _, err := a.Render(); err == nil {
+			t.Errorf("@%v %v: expected error", a.aura, a.i)
+		}
+		if s := a.String(); !strings.HasPrefix(s, "%!(BADAURA=@") {
+			t.Errorf("@%v %v: wrong fallback %v", a.aura, a.i, s)
+		}
+	}
+
+	roundTrip := []struct {
+		aura Aura
+		s    string
+	}{
+		{AuraUD, "1.000"}, {AuraU, "1.000"}, {AuraUX, "0x1.0000"}, {AuraUW, "0w-.~~~~~"}, {AuraUB, "0b1.0000"}, {AuraUV, "0v1.00000"},
+		{AuraSD, "-5"}, {AuraSX, "--0x10"}, {AuraRS, ".1.5"}, {AuraRQ, ".~~~1"},
+		{AuraT, `'it\'s a \\ \0a'`}, {AuraTA, "~.foo.bar"}, {AuraTAS, "%foo"}, {AuraTAS, "%$"},
+		{AuraP, "~zod"}, {AuraP, "~marzod"}, {AuraP, "~dozsun-dapfel"}, {AuraP, "~bonwet-dopzod-marnec-litpub--dapper-walrus-digleg-mogbud"},
+	}
+	for _, test := range roundTrip {
+		a, err := ParseAs(test.aura, test.s)
+		if err != nil {
+			t.Errorf("@%v %v: %v", test.aura, test.s, err)
+		} else if a.String() != test.s {
+			t.Errorf("@%v %v: round trip failed: %v", test.aura, test.s, a)
+		}
+	}
+	bad := []struct {
+		aura Aura
+		s    string
+	}{
+		{AuraUX, "1.000"}, {AuraUD, "0x1"}, {AuraSX, "--1"}, {AuraRD, ".1"}, {AuraT, "'a'b'"}, {AuraT, `'\0'`},
+		{AuraTA, "~.Foo"}, {AuraTAS, "%"}, {AuraTAS, "%1"}, {AuraF, "y"}, {AuraN, "0"}, {AuraIF, ".1.2.3"},
+		{AuraIF, "1.2.3.4"}, {AuraIS, ".0.0.0.0.0.0.0.01"}, {AuraUC, "0c1BoatSLRHtKNngkdXEeobR76b53LETtpyU"},
+		{AuraP, "~zodmar"}, {AuraP, "~marzod-zod"}, {AuraQ, ".~dozzod-nec"}, {AuraC, "~-~30c4"}, {AuraDA, "~2020.2.30"}, {AuraDR, "~s1.m1"},
+	}
+	for _, test := range bad {
+		if _, err := ParseAs(test.aura, test.s); err == nil {
+			t.Errorf("@%v %v: expected error", test.aura, test.s)
+		}
+	}
+}
+
+func TestRegisterAura(t *testing.T) {
+	RegisterAura("xtest", AuraCodec{
+		Format: func(a Atom) (string, error) { return "#" + a.Format(AuraUD), nil },
+		Parse: func(s string) (*big.Int, error) {
+			i, ok := new(big.Int).SetString(strings.TrimPrefix(s, "#"), 10)
+			if !ok {
+				return nil, strconv.ErrSyntax
+			}
+			return i, nil
+		},
+	})
+	if s := New64(42).Cast("xtest").String(); s != "#42" {
+		t.Errorf("wrong custom rendering: %v", s)
+	}
+	if a, err := ParseAs("xtest", "#42"); err != nil || a.Cmp(New64(42)) != 0 {
+		t.Errorf("wrong custom parse: %v %v", a, err)
+	}
+	for _, aura := range []Aura{"xtest", AuraUD} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("@%v: expected panic", aura)
+				}
+			}()
+			RegisterAura(aura, AuraCodec{Format: func(Atom) (string, error) { return "", nil }})
+		}()
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		aura Aura
+	}{
+		{"1.000", AuraUD}, {"0x1.0000", AuraUX}, {"0b101", AuraUB}, {"0v1f", AuraUV}, {"0w-", AuraUW},
+		{"--5", AuraSD}, {"-0x5", AuraSX}, {".1.5", AuraRS}, {".~1.5", AuraRD}, {".~~1.5", AuraRH}, {".~~~1.5", AuraRQ},
+		{"'foo'", AuraT}, {"~.foo", AuraTA}, {"%foo", AuraTAS}, {"%$", AuraTAS}, {"&", AuraF}, {"%.n", AuraF}, {"~", AuraN},
+		{"~zod", AuraP}, {".~marzod", AuraQ}, {"~-a", AuraC}, {".127.0.0.1", AuraIF}, {".0.0.0.0.0.0.0.1", AuraIS},
+		{"0c1BoatSLRHtKNngkdXEeobR76b53LETtpyT", AuraUC},
+		{"~2020.1.1", AuraDA}, {"~2020.2.29..12.34.56..8000", AuraDA}, {"~292277024401-.1.1", AuraDA}, {"~1-.12.31..23.59.59", AuraDA},
+		{"~s0", AuraDR}, {"~d1.h2.m3.s4", AuraDR}, {"~m1..0001", AuraDR},
+	}
+	for _, test := range tests {
+		a, err := Parse(test.s)
+		if err != nil {
+			t.Errorf("%v: %v", test.s, err)
+			continue
+		} else if a.aura != test.aura {
+			t.Errorf("%v: expected @%v, got @%v", test.s, test.aura, a.aura)
+		}
+		if exp := strings.TrimSuffix(test.s, "..00.00.00"); test.s == "%.n" {
+			exp = "|"
+		} else if a.String() != exp {
+			t.Errorf("%v: round trip failed: %v", test.s, a)
+		}
+	}
+	for _, s := range []string{"", "1000", "0x01", "~2020.13.1", "~2021.2.29", "~2020.1.1..24.00.00", "~s", "~zodzod", "'a", ".1.2.3", "foo"} {
+		if a, err := Parse(s); err == nil {
+			t.Errorf("%q: expected error, got %v", s, a)
+		}
+	}
+	if a, _ := Parse("~1970.1.1"); a.Time().Unix() != 0 {
+		t.Error("wrong Unix epoch:", a.Time())
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	a := New64(65536).Cast(AuraUX)
+	af := Formatter(a)
+	if s := fmt.Sprintf("%v|%s|%q|%d|%x|%#X|%P|%12v|%-8v|", af, af, af, af, af, af, af, af, Formatter(New64(1))); s != `0x1.0000|0x1.0000|"0x1.0000"|65536|10000|0X10000|~doznec-dozzod|    0x1.0000|1       |` {
+		t.Error("wrong formatting:", s)
+	}
+	if s := fmt.Sprintf("%t %c", Formatter(FromBytes([]byte("oof"))), af); s != `foo %!c(atom.Atom=0x1.0000)` {
+		t.Error("wrong formatting:", s)
+	}
+
+	atoms := []Atom{a, New64(1000).Cast(AuraUD), New64(3).Cast(AuraAtom), New64(0x61).Cast("tD"), NewSigned64(-5)}
+	js, err := json.Marshal(atoms)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(js) != `["0x1.0000","1.000","3","'a'","-5"]` {
+		t.Error("wrong JSON:", string(js))
+	}
+	var got []Atom
+	if err := json.Unmarshal(js, &got); err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		if got[i].Cmp(atoms[i]) != 0 || got[i].String() != atoms[i].String() {
+			t.Errorf("JSON did not round-trip: %v != %v", got[i], atoms[i])
+		}
+	}
+
+	preserved := make([]AuraJSON, len(atoms))
+	for i := range atoms {
+		preserved[i] = AuraJSON(atoms[i])
+	}
+	js, err = json.Marshal(preserved)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(js) != `[{"aura":"ux","value":"0x1.0000"},{"aura":"ud","value":"1.000"},{"aura":"","value":"3"},{"aura":"tD","value":"'a'"},{"aura":"sd","value":"-5"}]` {
+		t.Error("wrong JSON:", string(js))
+	}
+	preserved = nil
+	if err := json.Unmarshal(js, &preserved); err != nil {
+		t.Fatal(err)
+	}
+	for i := range preserved {
+		if p := Atom(preserved[i]); p.Cmp(atoms[i]) != 0 || p.aura != atoms[i].aura {
+			t.Errorf("JSON did not round-trip: %v != %v", p, atoms[i])
+		}
+	}
+	if err := json.Unmarshal([]byte(`["0x1.0000"]`), &preserved); err != nil || Atom(preserved[0]).aura != AuraUX {
+		t.Error("failed to decode literal as AuraJSON:", preserved, err)
+	}
+
+	if err := json.Unmarshal([]byte(`[12345678901234567890123, "42"]`), &got); err != nil {
+		t.Fatal(err)
+	} else if got[0].String() != "12.345.678.901.234.567.890.123" || got[1].String() != "42" {
+		t.Error("wrong decoded numbers:", got)
+	}
+	if _, err := json.Marshal(New64(2).Cast(AuraF)); err == nil {
+		t.Error("expected error for invalid @f")
+	}
+
+	var s Atom
+	for _, src := range []interface{}{int64(65536), "65536", []byte("0x1.0000")} {
+		if err := s.Scan(src); err != nil || s.Cmp(a) != 0 {
+			t.Errorf("failed to scan %v: %v %v", src, s, err)
+		}
+	}
+	if v, _ := New64(1000).Value(); v != "1000" {
+		t.Error("wrong value:", v)
+	} else if v, _ := a.Value(); v != "0x1.0000" {
+		t.Error("wrong value:", v)
+	} else if err := s.Scan(nil); err == nil {
+		t.Error("expected error when scanning NULL")
+	}
+	if err := s.UnmarshalText([]byte("~zod")); err != nil || s.String() != "~zod" {
+		t.Error("failed to unmarshal text:", s, err)
+	} else if b, _ := s.MarshalText(); string(b) != "~zod" {
+		t.Error("wrong text:", string(b))
 	}
 }
@@ -0,0 +1,372 @@
+package atom
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An AuraCodec formats and parses atoms of a custom aura.
+type AuraCodec struct {
+	// Format renders an atom of the aura.
+	Format func(Atom) (string, error)
+	// Parse parses a literal of the aura. It may be nil.
+	Parse func(string) (*big.Int, error)
+}
+
+var (
+	auraMu       sync.RWMutex
+	auraRegistry = make(map[Aura]AuraCodec)
+)
+
+// standardAuras are the auras supported natively by Render and ParseAs.
+var standardAuras = map[Aura]bool{
+	AuraAtom: true, AuraC: true, AuraD: true, AuraDA: true, AuraDR: true,
+	AuraF: true, AuraIF: true, AuraIS: true, AuraN: true, AuraP: true,
+	AuraQ: true, AuraR: true, AuraRD: true, AuraRH: true, AuraRQ: true,
+	AuraRS: true, AuraS: true, AuraSB: true, AuraSD: true, AuraSV: true,
+	AuraSW: true, AuraSX: true, AuraT: true, AuraTA: true, AuraTAS: true,
+	AuraU: true, AuraUB: true, AuraUC: true, AuraUD: true, AuraUV: true,
+	AuraUW: true, AuraUX: true,
+}
+
+// RegisterAura registers a codec for a custom aura, which is then used by
+// Render, String, and ParseAs. It panics if the aura is a standard aura or is
+// already registered, or if c.Format is nil.
+func RegisterAura(aura Aura, c AuraCodec) {
+	auraMu.Lock()
+	defer auraMu.Unlock()
+	if c.Format == nil {
+		panic("atom: RegisterAura codec has nil Format")
+	} else if standardAuras[aura] {
+		panic("atom: RegisterAura called for standard aura @" + string(aura))
+	} else if _, ok := auraRegistry[aura]; ok {
+		panic("atom: RegisterAura called twice for aura @" + string(aura))
+	}
+	auraRegistry[aura] = c
+}
+
+func lookupAura(aura Aura) (AuraCodec, bool) {
+	auraMu.RLock()
+	defer auraMu.RUnlock()
+	c, ok := auraRegistry[aura]
+	return c, ok
+}
+
+// ParseAs parses s as a literal of the specified aura.
+func ParseAs(aura Aura, s string) (Atom, error) {
+	i, err := parseAs(aura, s)
+	if err != nil {
+		return Atom{}, err
+	}
+	return Atom{i: i, aura: aura}, nil
+}
+
+func parseAs(aura Aura, s string) (*big.Int, error) {
+	if c, ok := lookupAura(aura); ok {
+		if c.Parse == nil {
+			return nil, fmt.Errorf("aura @%v has no parser", aura)
+		}
+		return c.Parse(s)
+	}
+	// ignore size suffixes, e.g. @tD or @uxG
+	aura = Aura(strings.TrimRight(string(aura), "ABCDEFGHIJKLMNOPQRSTUVWXYZ"))
+	bad := fmt.Errorf("invalid @%v literal %q", aura, s)
+	switch aura {
+	case AuraAtom, AuraU, AuraUD, AuraUB, AuraUV, AuraUW, AuraUX:
+		i, a, err := parseUnsigned(s)
+		if err != nil {
+			return nil, err
+		} else if a != aura && !(a == AuraUD && (aura == AuraAtom || aura == AuraU)) {
+			return nil, bad
+		}
+		return i, nil
+	case AuraS, AuraSB, AuraSD, AuraSV, AuraSW, AuraSX:
+		a, err := ParseSigned(s)
+		if err != nil {
+			return nil, err
+		} else if a.aura != aura && !(a.aura == AuraSD && aura == AuraS) {
+			return nil, bad
+		}
+		return a.i, nil
+	case AuraRH, AuraRS, AuraRD, AuraRQ:
+		a, err := ParseFloat(s)
+		if err != nil {
+			return nil, err
+		} else if a.aura != aura {
+			return nil, bad
+		}
+		return a.i, nil
+	case AuraT:
+		t, err := parseCord(s)
+		if err != nil {
+			return nil, err
+		}
+		return fromText(t, aura).i, nil
+	case AuraTA:
+		if !strings.HasPrefix(s, "~.") || !validKnot(s[2:]) {
+			return nil, bad
+		}
+		return fromText(s[2:], aura).i, nil
+	case AuraTAS:
+		if s == "%$" {
+			return new(big.Int), nil
+		} else if !strings.HasPrefix(s, "%") || len(s) == 1 || !validTerm(s[1:]) {
+			return nil, bad
+		}
+		return fromText(s[1:], aura).i, nil
+	case AuraF:
+		switch s {
+		case "&", "%.y":
+			return big.NewInt(0), nil
+		case "|", "%.n":
+			return big.NewInt(1), nil
+		}
+	case AuraN:
+		if s == "~" {
+			return new(big.Int), nil
+		}
+	case AuraC:
+		if !strings.HasPrefix(s, "~-") {
+			return nil, bad
+		}
+		rs, err := unwood(s[2:])
+		if err != nil {
+			return nil, err
+		}
+		i := new(big.Int)
+		for j := len(rs) - 1; j >= 0; j-- {
+			i.Lsh(i, 32).Or(i, big.NewInt(int64(uint32(rs[j]))))
+		}
+		return i, nil
+	case AuraIF:
+		if ip := net.ParseIP(strings.TrimPrefix(s, ".")).To4(); ip != nil && s[0] == '.' && strings.Count(s, ".") == 4 {
+			return new(big.Int).SetBytes(ip), nil
+		}
+	case AuraIS:
+		groups := strings.Split(s, ".")
+		if len(groups) != 9 || groups[0] != "" {
+			return nil, bad
+		}
+		i := new(big.Int)
+		for _, g := range groups[1:] {
+			u, err := strconv.ParseUint(g, 16, 16)
+			if err != nil || g != strconv.FormatUint(u, 16) {
+				return nil, bad
+			}
+			i.Lsh(i, 16).Or(i, big.NewInt(int64(u)))
+		}
+		return i, nil
+	case AuraUC:
+		if strings.HasPrefix(s, "0c") {
+			return parseUC(s[2:])
+		}
+	case AuraDA:
+		if strings.HasPrefix(s, "~") {
+			return parseDate(s[1:])
+		}
+	case AuraDR:
+		if strings.HasPrefix(s, "~") {
+			return parseDuration(s[1:])
+		}
+	case AuraP:
+		if strings.HasPrefix(s, "~") {
+			return parsePhonemes(s[1:], true)
+		}
+	case AuraQ:
+		if strings.HasPrefix(s, ".~") {
+			return parsePhonemes(s[2:], false)
+		}
+	default:
+		return nil, fmt.Errorf("parsing @%v is not supported", aura)
+	}
+	return nil, bad
+}
+
+// parseCord parses a quoted @t literal, interpreting \\, \', and \hh escapes.
+func parseCord(s string) (string, error) {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return "", fmt.Errorf("invalid @t literal %q", s)
+	}
+	var sb strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		switch c := s[i]; c {
+		case '\'':
+			return "", fmt.Errorf("unescaped quote in @t literal %q", s)
+		case '\\':
+			if i+1 < len(s)-1 && (s[i+1] == '\\' || s[i+1] == '\'') {
+				sb.WriteByte(s[i+1])
+				i++
+				continue
+			} else if i+2 >= len(s)-1 {
+				return "", fmt.Errorf("invalid escape in @t literal %q", s)
+			}
+			b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape in @t literal %q", s)
+			}
+			sb.WriteByte(byte(b))
+			i += 2
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// leftPad pads b with leading zeros to at least n bytes.
+func leftPad(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b
+	}
+	return append(make([]byte, n-len(b)), b...)
+}
+
+func utf32Runes(i *big.Int) []rune {
+	b := flip(i.Bytes())
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	rs := make([]rune, len(b)/4)
+	for j := range rs {
+		rs[j] = rune(uint32(b[j*4]) | uint32(b[j*4+1])<<8 | uint32(b[j*4+2])<<16 | uint32(b[j*4+3])<<24)
+	}
+	return rs
+}
+
+func formatIF(i *big.Int) string {
+	return "." + net.IP(leftPad(i.Bytes(), 4)).String()
+}
+
+func formatIS(i *big.Int) string {
+	b := leftPad(i.Bytes(), 16)
+	var sb strings.Builder
+	for j := 0; j < 16; j += 2 {
+		sb.WriteByte('.')
+		sb.WriteString(strconv.FormatUint(uint64(b[j])<<8|uint64(b[j+1]), 16))
+	}
+	return sb.String()
+}
+
+func formatQ(a *big.Int) string {
+	b := a.Bytes()
+	if len(b) == 0 {
+		b = []byte{0}
+	}
+	var buf strings.Builder
+	buf.WriteString(".~")
+	if len(b)%2 == 1 {
+		buf.WriteString(suffixes[b[0]])
+		b = b[1:]
+		if len(b) > 0 {
+			buf.WriteByte('-')
+		}
+	}
+	for i := 0; i < len(b); i += 2 {
+		if i > 0 {
+			buf.WriteByte('-')
+		}
+		buf.WriteString(prefixes[b[i]] + suffixes[b[i+1]])
+	}
+	return buf.String()
+}
+
+// parsePhonemes parses the phonemes of a @p or @q; only @p uses "--" as a
+// separator. As in formatP, no scrambling is applied.
+func parsePhonemes(s string, p bool) (*big.Int, error) {
+	bad := fmt.Errorf("invalid phonemic literal %q", s)
+	if p {
+		s = strings.Replace(s, "--", "-", -1)
+	}
+	words := strings.Split(s, "-")
+	var b []byte
+	for i, w := range words {
+		switch len(w) {
+		case 3:
+			if i != 0 {
+				return nil, bad
+			}
+			j, ok := suffixIndex(w)
+			if !ok {
+				return nil, bad
+			}
+			b = append(b, j)
+		case 6:
+			j, ok1 := prefixIndex(w[:3])
+			k, ok2 := suffixIndex(w[3:])
+			if !ok1 || !ok2 {
+				return nil, bad
+			}
+			b = append(b, j, k)
+		default:
+			return nil, bad
+		}
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func prefixIndex(s string) (uint8, bool) {
+	i, ok := phonemeIndex[s]
+	return i, ok && prefixes[i] == s
+}
+
+func suffixIndex(s string) (uint8, bool) {
+	i, ok := phonemeIndex[s]
+	return i, ok && suffixes[i] == s
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func checksum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:4]
+}
+
+// formatUC renders a bitcoin address in base58check. Like Hoon, the payload is
+// padded to at least 21 bytes (a version byte and a 20-byte hash).
+func formatUC(i *big.Int) string {
+	b := leftPad(i.Bytes(), 21)
+	b = append(b, checksum(b)...)
+	n := new(big.Int).SetBytes(b)
+	var digits []byte
+	for mod := new(big.Int); n.Sign() > 0; {
+		n.QuoRem(n, big.NewInt(58), mod)
+		digits = append(digits, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		digits = append(digits, '1')
+	}
+	return string(flip(digits))
+}
+
+func parseUC(s string) (*big.Int, error) {
+	n := new(big.Int)
+	zeros := 0
+	for j, c := range []byte(s) {
+		d := strings.IndexByte(base58Alphabet, c)
+		if d < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		} else if d == 0 && n.Sign() == 0 && j == zeros {
+			zeros++
+		}
+		n.Mul(n, big.NewInt(58)).Add(n, big.NewInt(int64(d)))
+	}
+	b := append(make([]byte, zeros), n.Bytes()...)
+	if len(b) < 25 {
+		return nil, errors.New("bitcoin address is too short")
+	}
+	payload, sum := b[:len(b)-4], b[len(b)-4:]
+	if string(checksum(payload)) != string(sum) {
+		return nil, errors.New("invalid bitcoin address checksum")
+	}
+	return new(big.Int).SetBytes(payload), nil
+}
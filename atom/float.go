@@ -0,0 +1,262 @@
+package atom
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A floatFormat describes an IEEE 754 binary interchange format.
+type floatFormat struct {
+	exp, mant uint // width of exponent and (explicit) mantissa, in bits
+	prefix    string
+}
+
+var floatFormats = map[Aura]floatFormat{
+	AuraRH: {5, 10, ".~~"},
+	AuraRS: {8, 23, "."},
+	AuraRD: {11, 52, ".~"},
+	AuraRQ: {15, 112, ".~~~"},
+}
+
+// canonical NaNs, as produced by Hoon's float cores
+var floatNaN = map[Aura]*big.Int{
+	AuraRH: big.NewInt(0x7e00),
+	AuraRS: big.NewInt(0x7fc00000),
+	AuraRD: new(big.Int).SetUint64(0x7ff8000000000000),
+	AuraRQ: new(big.Int).Lsh(big.NewInt(0x7fff8), 108),
+}
+
+// NewFloat32 returns f as a @rs atom. The bits of f are preserved exactly,
+// including the sign and payload of NaNs.
+func NewFloat32(f float32) Atom {
+	return Atom{
+		i:    new(big.Int).SetUint64(uint64(math.Float32bits(f))),
+		aura: AuraRS,
+	}
+}
+
+// NewFloat64 returns f as a @rd atom. The bits of f are preserved exactly,
+// including the sign and payload of NaNs.
+func NewFloat64(f float64) Atom {
+	return Atom{
+		i:    new(big.Int).SetUint64(math.Float64bits(f)),
+		aura: AuraRD,
+	}
+}
+
+// NewBigFloat returns f, rounded to nearest-even, as an atom with the specified
+// float aura (@rh, @rs, @rd, or @rq).
+func NewBigFloat(f *big.Float, aura Aura) (Atom, error) {
+	ff, ok := floatFormats[aura]
+	if !ok {
+		return Atom{}, fmt.Errorf("@%v is not a float aura", aura)
+	}
+	var r *big.Rat
+	if !f.IsInf() {
+		r, _ = f.Rat(nil)
+	}
+	return Atom{
+		i:    encodeFloat(f.Signbit(), r, ff),
+		aura: aura,
+	}, nil
+}
+
+var floatLit = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?(e-?[0-9]+)?$`)
+
+// ParseFloat parses a Hoon float literal, such as .3.14 (@rs), .~3.14 (@rd),
+// .~~3.14 (@rh), or .~~~3.14 (@rq). Decimal values are rounded to
+// nearest-even.
+func ParseFloat(s string) (Atom, error) {
+	aura := Aura(AuraRS)
+	switch {
+	case strings.HasPrefix(s, ".~~~"):
+		aura = AuraRQ
+	case strings.HasPrefix(s, ".~~"):
+		aura = AuraRH
+	case strings.HasPrefix(s, ".~"):
+		aura = AuraRD
+	case strings.HasPrefix(s, "."):
+	default:
+		return Atom{}, fmt.Errorf("invalid float literal %q", s)
+	}
+	ff := floatFormats[aura]
+	lit := strings.TrimPrefix(s, ff.prefix)
+	neg := strings.HasPrefix(lit, "-")
+	var r *big.Rat
+	switch strings.TrimPrefix(lit, "-") {
+	case "nan":
+		if neg {
+			return Atom{}, fmt.Errorf("invalid float literal %q", s)
+		}
+		return Atom{i: new(big.Int).Set(floatNaN[aura]), aura: aura}, nil
+	case "inf":
+	default:
+		if !floatLit.MatchString(lit) {
+			return Atom{}, fmt.Errorf("invalid float literal %q", s)
+		}
+		var ok bool
+		if r, ok = new(big.Rat).SetString(lit); !ok {
+			return Atom{}, fmt.Errorf("invalid float literal %q", s)
+		}
+	}
+	return Atom{i: encodeFloat(neg, r, ff), aura: aura}, nil
+}
+
+// encodeFloat returns the IEEE bits of the value with the given sign and
+// magnitude |r|, rounded to nearest-even. A nil r denotes infinity.
+func encodeFloat(neg bool, r *big.Rat, ff floatFormat) *big.Int {
+	bias := int64(1)<<(ff.exp-1) - 1
+	infExp := big.NewInt(1<<ff.exp - 1)
+	var exp, mant *big.Int
+	if r != nil {
+		exp, mant = roundRat(new(big.Rat).Abs(r), ff)
+		if exp.Int64() > 2*bias {
+			r = nil // overflow
+		}
+	}
+	if r == nil {
+		exp, mant = infExp, new(big.Int)
+	}
+	bits := new(big.Int)
+	if neg {
+		bits.SetBit(bits, int(ff.exp+ff.mant), 1)
+	}
+	bits.Or(bits, exp.Lsh(exp, ff.mant))
+	return bits.Or(bits, mant)
+}
+
+// roundRat rounds the non-negative r to the nearest value in ff, returning
+// its biased exponent and mantissa. The exponent may exceed the format's
+// range, indicating overflow.
+func roundRat(r *big.Rat, ff floatFormat) (exp, mant *big.Int) {
+	if r.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	bias := int64(1)<<(ff.exp-1) - 1
+	num, den := r.Num(), r.Denom()
+
+	// find e such that 2^e <= r < 2^(e+1)
+	e := int64(num.BitLen() - den.BitLen())
+	if shl(num, -e).Cmp(shl(den, e)) < 0 {
+		e--
+	}
+	if e < 1-bias {
+		e = 1 - bias // subnormal
+	}
+
+	// m = round(r * 2^(mant-e))
+	n, d := shl(num, int64(ff.mant)-e), shl(den, e-int64(ff.mant))
+	m, rem := new(big.Int).QuoRem(n, d, new(big.Int))
+	switch rem.Lsh(rem, 1).Cmp(d) {
+	case 1:
+		m.Add(m, big.NewInt(1))
+	case 0:
+		if m.Bit(0) == 1 {
+			m.Add(m, big.NewInt(1))
+		}
+	}
+	if m.BitLen() > int(ff.mant)+1 {
+		m.Rsh(m, 1)
+		e++
+	}
+	if m.BitLen() <= int(ff.mant) {
+		return new(big.Int), m // subnormal
+	}
+	return big.NewInt(e + bias), m.SetBit(m, int(ff.mant), 0)
+}
+
+// shl returns x*2^n if n is positive, or x otherwise.
+func shl(x *big.Int, n int64) *big.Int {
+	if n <= 0 {
+		return x
+	}
+	return new(big.Int).Lsh(x, uint(n))
+}
+
+// decodeFloat returns the sign and value of the float whose bits are i. The
+// value is nil for infinities, and an error is returned for NaNs.
+func decodeFloat(i *big.Int, ff floatFormat) (neg bool, f *big.Float, err error) {
+	width := int(ff.exp + ff.mant + 1)
+	neg = i.Bit(width-1) == 1
+	exp := new(big.Int).Rsh(i, ff.mant)
+	exp.And(exp, big.NewInt(1<<ff.exp-1))
+	mant := new(big.Int).And(i, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), ff.mant), big.NewInt(1)))
+	bias := int64(1)<<(ff.exp-1) - 1
+
+	e := exp.Int64()
+	switch e {
+	case 1<<ff.exp - 1:
+		if mant.BitLen() != 0 {
+			return neg, nil, errors.New("NaN")
+		}
+		return neg, nil, nil
+	case 0:
+		e = 1 // subnormal; no implicit leading bit
+	default:
+		mant.SetBit(mant, int(ff.mant), 1)
+	}
+	f = new(big.Float).SetPrec(ff.mant + 1).SetInt(mant)
+	f.SetMantExp(f, int(e-bias-int64(ff.mant)))
+	if neg {
+		f.Neg(f)
+	}
+	return neg, f, nil
+}
+
+// formatBigFloat renders a float of arbitrary format, printing the shortest
+// decimal that uniquely identifies it.
+func formatBigFloat(i *big.Int, ff floatFormat) string {
+	neg, f, err := decodeFloat(i, ff)
+	sign := ""
+	if neg {
+		sign = "-"
+	}
+	switch {
+	case err != nil:
+		return "nan"
+	case f == nil:
+		return sign + "inf"
+	case f.Sign() == 0:
+		return sign + "0"
+	}
+	abs := new(big.Int).Lsh(big.NewInt(1), ff.exp+ff.mant)
+	abs.And(i, abs.Sub(abs, big.NewInt(1)))
+	t := strings.Split(shortestDecimal(f, abs, ff), "e")
+	esign := strings.TrimLeft(t[1][:1], "+")
+	t[1] = strings.TrimLeft(t[1][1:], "0")
+	if t[1] == "" {
+		return t[0]
+	}
+	return t[0] + "e" + esign + t[1]
+}
+
+// shortestDecimal returns the shortest decimal, in 'e' notation, that rounds
+// to f, whose magnitude has the bits abs in ff. (f.Text('e', -1) is not
+// always correct: it mishandles the closer lower neighbor of a power of two,
+// and knows nothing of the fixed spacing of subnormals.)
+func shortestDecimal(f *big.Float, abs *big.Int, ff floatFormat) string {
+	for n := 0; ; n++ {
+		s := f.Text('e', n)
+		r, _ := new(big.Rat).SetString(s)
+		if encodeFloat(false, r.Abs(r), ff).Cmp(abs) == 0 {
+			return s
+		}
+	}
+}
+
+func formatFloat(f float64, bits int) string {
+	s := strings.ToLower(strconv.FormatFloat(f, 'e', -1, bits))
+	if !strings.Contains(s, "e") {
+		return strings.TrimPrefix(s, "+") // inf or nan
+	}
+	t := strings.Split(s, "e")
+	frac := t[0]
+	sign := strings.TrimPrefix(t[1][:1], "+")
+	exp := strings.TrimLeft(t[1][1:], "0")
+	return strings.TrimSuffix(frac+"e"+sign+exp, "e")
+}
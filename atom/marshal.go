@@ -0,0 +1,178 @@
+package atom
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"lukechampine.com/urbit/internal/fmtutil"
+)
+
+// An AuraJSON is an Atom that is encoded in JSON as an object recording its
+// aura, e.g. {"aura":"ux","value":"0x1.0000"}. An Atom is encoded as just its
+// rendered literal, e.g. "0x1.0000"; since most literals indicate their aura,
+// this usually suffices, but AuraJSON also preserves auras that cannot be
+// inferred from their literal, such as @ (versus @ud), size suffixes, and
+// custom auras. Both types decode either form.
+type AuraJSON Atom
+
+type jsonAtom struct {
+	Aura  Aura   `json:"aura"`
+	Value string `json:"value"`
+}
+
+// MarshalText implements encoding.TextMarshaler, rendering the atom according
+// to its aura.
+func (a Atom) MarshalText() ([]byte, error) {
+	s, err := a.Render()
+	return []byte(s), err
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The aura is inferred
+// from the literal, as in Parse.
+func (a *Atom) UnmarshalText(b []byte) error {
+	p, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*a = p
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the atom as a JSON string
+// containing its rendered literal; see AuraJSON.
+func (a Atom) MarshalJSON() ([]byte, error) {
+	s, err := a.Render()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a AuraJSON) MarshalJSON() ([]byte, error) {
+	s, err := Atom(a).Render()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonAtom{a.aura, s})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *AuraJSON) UnmarshalJSON(b []byte) error {
+	return (*Atom)(a).UnmarshalJSON(b)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. In addition to the forms produced
+// by MarshalJSON, it accepts non-negative JSON integers and decimal strings
+// without dot separators, which are decoded as @ud.
+func (a *Atom) UnmarshalJSON(b []byte) error {
+	var p Atom
+	var err error
+	switch {
+	case string(b) == "null":
+		return nil
+	case len(b) > 0 && b[0] == '{':
+		var ja jsonAtom
+		if err := json.Unmarshal(b, &ja); err != nil {
+			return err
+		}
+		p, err = ParseAs(ja.Aura, ja.Value)
+	case len(b) > 0 && b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		p, err = parseLoose(s)
+	default:
+		p, err = parseLoose(string(b))
+	}
+	if err != nil {
+		return err
+	}
+	*a = p
+	return nil
+}
+
+// Value implements driver.Valuer. Atoms with aura @, @u, or @ud are stored as
+// plain decimal strings, so that they can be stored in numeric columns; all
+// other atoms are stored as their rendered literal.
+func (a Atom) Value() (driver.Value, error) {
+	switch a.aura {
+	case AuraAtom, AuraU, AuraUD:
+		return a.i.String(), nil
+	}
+	s, err := a.Render()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Scan implements sql.Scanner. It accepts non-negative integers, plain
+// decimal strings (decoded as @ud), and atom literals.
+func (a *Atom) Scan(src interface{}) error {
+	var p Atom
+	var err error
+	switch src := src.(type) {
+	case int64:
+		if src < 0 {
+			return fmt.Errorf("cannot scan negative integer %v into Atom", src)
+		}
+		p = New64(uint64(src)).Cast(AuraUD)
+	case string:
+		p, err = parseLoose(src)
+	case []byte:
+		p, err = parseLoose(string(src))
+	case nil:
+		return errors.New("cannot scan NULL into Atom")
+	default:
+		return fmt.Errorf("cannot scan %T into Atom", src)
+	}
+	if err != nil {
+		return err
+	}
+	*a = p
+	return nil
+}
+
+// parseLoose is like Parse, but also accepts decimal numbers without dot
+// separators.
+func parseLoose(s string) (Atom, error) {
+	if len(s) > 0 && strings.Trim(s, "0123456789") == "" {
+		if i, ok := new(big.Int).SetString(s, 10); ok {
+			return Atom{i: i, aura: AuraUD}, nil
+		}
+	}
+	return Parse(s)
+}
+
+// A Formatter is an Atom that implements fmt.Formatter. (Atom itself cannot,
+// since its Format method renders it with a given aura.) The %v and %s verbs
+// render the atom according to its aura, and %q renders it as a quoted Go
+// string. The integer verbs %d, %x, %X, %o, %O, and %b format the atom's
+// value, as with big.Int. Additionally, %P renders the atom as a @p (%p is
+// reserved by package fmt), and %t renders its raw bytes as text. For example:
+//
+//	fmt.Printf("%x %P", atom.Formatter(a), atom.Formatter(a))
+type Formatter Atom
+
+// Format implements fmt.Formatter.
+func (af Formatter) Format(f fmt.State, verb rune) {
+	a := Atom(af)
+	switch verb {
+	case 'v', 's', 'q':
+		fmt.Fprintf(f, fmtutil.FormatString(f, verb), a.String())
+	case 'd', 'x', 'X', 'o', 'O', 'b':
+		a.i.Format(f, verb)
+	case 'P':
+		fmt.Fprintf(f, fmtutil.FormatString(f, 's'), a.Format(AuraP))
+	case 't':
+		fmt.Fprintf(f, fmtutil.FormatString(f, 's'), a.Text())
+	default:
+		fmt.Fprintf(f, "%%!%c(atom.Atom=%v)", verb, a.String())
+	}
+}
@@ -0,0 +1,119 @@
+package atom
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// An intFormat describes how an unsigned integer aura is rendered.
+type intFormat struct {
+	aura     Aura
+	prefix   string
+	alphabet string
+	bits     uint // bits per digit, or 0 for decimal
+	group    int  // digits per dot-separated group
+}
+
+var intFormats = []intFormat{
+	{AuraUB, "0b", "01", 1, 4},
+	{AuraUV, "0v", "0123456789abcdefghijklmnopqrstuv", 5, 5},
+	{AuraUW, "0w", "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-~", 6, 5},
+	{AuraUX, "0x", "0123456789abcdef", 4, 4},
+	{AuraUD, "", "0123456789", 0, 3},
+}
+
+// parseUnsigned parses an unsigned integer literal, such as 1.000, 0x1.0000,
+// or 0w1.abcde, returning its value and aura.
+func parseUnsigned(s string) (*big.Int, Aura, error) {
+	f := intFormats[len(intFormats)-1]
+	for _, ff := range intFormats[:len(intFormats)-1] {
+		if strings.HasPrefix(s, ff.prefix) {
+			f = ff
+			break
+		}
+	}
+	digits := strings.TrimPrefix(s, f.prefix)
+	groups := strings.Split(digits, ".")
+	if len(groups[0]) == 0 || len(groups[0]) > f.group || (groups[0][0] == '0' && digits != "0") {
+		return nil, "", fmt.Errorf("invalid @%v literal %q", f.aura, s)
+	}
+	for _, g := range groups[1:] {
+		if len(g) != f.group {
+			return nil, "", fmt.Errorf("invalid @%v literal %q", f.aura, s)
+		}
+	}
+	i := new(big.Int)
+	for _, c := range strings.Join(groups, "") {
+		d := strings.IndexRune(f.alphabet, c)
+		if d < 0 {
+			return nil, "", fmt.Errorf("invalid @%v literal %q", f.aura, s)
+		}
+		if f.bits == 0 {
+			i.Mul(i, big.NewInt(10))
+		} else {
+			i.Lsh(i, f.bits)
+		}
+		i.Add(i, big.NewInt(int64(d)))
+	}
+	return i, f.aura, nil
+}
+
+// Parse parses a Hoon atom literal, inferring its aura from its syntax. For
+// example, "0x1.0000" is parsed as a @ux, "~zod" as a @p, and "%foo" as a @tas.
+// Plain decimal numbers are parsed as @ud.
+func Parse(s string) (Atom, error) {
+	aura, err := literalAura(s)
+	if err != nil {
+		return Atom{}, err
+	}
+	return ParseAs(aura, s)
+}
+
+// literalAura returns the aura indicated by the syntax of the literal s.
+func literalAura(s string) (Aura, error) {
+	bad := fmt.Errorf("invalid atom literal %q", s)
+	if s == "" {
+		return "", bad
+	}
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	switch {
+	case s == "&" || s == "|" || s == "%.y" || s == "%.n":
+		return AuraF, nil
+	case s == "~":
+		return AuraN, nil
+	case s[0] == '\'':
+		return AuraT, nil
+	case s[0] == '%':
+		return AuraTAS, nil
+	case strings.HasPrefix(s, "~."):
+		return AuraTA, nil
+	case strings.HasPrefix(s, "~-"):
+		return AuraC, nil
+	case strings.HasPrefix(s, "~"):
+		switch {
+		case len(s) > 1 && isDigit(s[1]):
+			return AuraDA, nil
+		case len(s) > 2 && strings.IndexByte("dhms", s[1]) >= 0 && isDigit(s[2]):
+			return AuraDR, nil
+		}
+		return AuraP, nil
+	case strings.HasPrefix(s, ".~") && len(s) > 2 && 'a' <= s[2] && s[2] <= 'z':
+		return AuraQ, nil
+	case strings.HasPrefix(s, "."):
+		if n := strings.Count(s, "."); n == 4 && !strings.ContainsAny(s, "~-e") {
+			return AuraIF, nil
+		} else if n == 8 && !strings.ContainsAny(s, "~-") {
+			return AuraIS, nil
+		}
+		a, err := ParseFloat(s)
+		return a.aura, err
+	case strings.HasPrefix(s, "-"):
+		a, err := ParseSigned(s)
+		return a.aura, err
+	case strings.HasPrefix(s, "0c"):
+		return AuraUC, nil
+	}
+	_, a, err := parseUnsigned(s)
+	return a, err
+}
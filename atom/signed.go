@@ -0,0 +1,114 @@
+package atom
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Signed atoms are zigzag-encoded: --n is 2n, and -n is 2n-1.
+
+// NewSigned returns i as a @sd atom.
+func NewSigned(i *big.Int) Atom {
+	z := new(big.Int).Abs(i)
+	z.Lsh(z, 1)
+	if i.Sign() < 0 {
+		z.Sub(z, big.NewInt(1))
+	}
+	return Atom{i: z, aura: AuraSD}
+}
+
+// NewSigned64 returns i as a @sd atom.
+func NewSigned64(i int64) Atom {
+	return NewSigned(big.NewInt(i))
+}
+
+// Signed returns the value of a, interpreted as a signed integer.
+func (a Atom) Signed() *big.Int {
+	z := new(big.Int).Add(a.i, big.NewInt(1))
+	z.Rsh(z, 1)
+	if a.i.Bit(0) == 1 {
+		z.Neg(z)
+	}
+	return z
+}
+
+// ParseSigned parses a signed integer literal, such as --1.000, -0x1.0000,
+// or --0b101, returning an atom with the corresponding signed aura. As in
+// Hoon, -0 is accepted as a spelling of --0.
+func ParseSigned(s string) (Atom, error) {
+	var neg bool
+	switch {
+	case strings.HasPrefix(s, "--"):
+		s = s[2:]
+	case strings.HasPrefix(s, "-"):
+		s, neg = s[1:], true
+	default:
+		return Atom{}, fmt.Errorf("invalid signed literal %q", s)
+	}
+	i, aura, err := parseUnsigned(s)
+	if err != nil {
+		return Atom{}, err
+	}
+	if neg {
+		i.Neg(i)
+	}
+	return NewSigned(i).Cast("s" + aura[1:]), nil
+}
+
+// signedAura returns the aura of the result of a signed operation on a.
+func signedAura(a Atom) Aura {
+	if a.aura.NestsIn(AuraS) {
+		return a.aura
+	}
+	return AuraSD
+}
+
+// Si provides signed arithmetic on zigzag-encoded atoms, mirroring Hoon's ++si
+// core. Results have the aura of the first operand, or @sd if that aura is
+// not signed.
+var Si si
+
+type si struct{}
+
+func (si) op(a, b Atom, fn func(x, y *big.Int) *big.Int) Atom {
+	return NewSigned(fn(a.Signed(), b.Signed())).Cast(signedAura(a))
+}
+
+// Sum returns a+b.
+func (s si) Sum(a, b Atom) Atom {
+	return s.op(a, b, func(x, y *big.Int) *big.Int { return x.Add(x, y) })
+}
+
+// Dif returns a-b.
+func (s si) Dif(a, b Atom) Atom {
+	return s.op(a, b, func(x, y *big.Int) *big.Int { return x.Sub(x, y) })
+}
+
+// Pro returns a*b.
+func (s si) Pro(a, b Atom) Atom {
+	return s.op(a, b, func(x, y *big.Int) *big.Int { return x.Mul(x, y) })
+}
+
+// Fra returns a/b, rounded toward zero. It panics if b is zero.
+func (s si) Fra(a, b Atom) Atom {
+	return s.op(a, b, func(x, y *big.Int) *big.Int { return x.Quo(x, y) })
+}
+
+// Rem returns the remainder of a/b, which has the sign of a. It panics if b
+// is zero.
+func (s si) Rem(a, b Atom) Atom {
+	return s.op(a, b, func(x, y *big.Int) *big.Int { return x.Rem(x, y) })
+}
+
+// Abs returns the absolute value of a, as an unsigned atom.
+func (si) Abs(a Atom) Atom {
+	z := a.Signed()
+	return Atom{i: z.Abs(z), aura: AuraUD}
+}
+
+// Cmp compares a and b, returning -1, 0, or +1. (Hoon's cmp:si returns the
+// same values as @s.)
+func (si) Cmp(a, b Atom) int {
+	return a.Signed().Cmp(b.Signed())
+}
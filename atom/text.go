@@ -0,0 +1,162 @@
+package atom
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cord returns s as a @t atom. s must be valid UTF-8.
+func Cord(s string) (Atom, error) {
+	if !utf8.ValidString(s) {
+		return Atom{}, fmt.Errorf("cord %q is not valid UTF-8", s)
+	}
+	return fromText(s, AuraT), nil
+}
+
+// Knot returns s as a @ta atom. s may contain only lowercase letters, digits,
+// and the characters '-', '.', '_', and '~'.
+func Knot(s string) (Atom, error) {
+	if !validKnot(s) {
+		return Atom{}, fmt.Errorf("invalid knot %q", s)
+	}
+	return fromText(s, AuraTA), nil
+}
+
+// Term returns s as a @tas atom. s must either be empty, or consist of a
+// lowercase letter followed by lowercase letters, digits, and '-'.
+func Term(s string) (Atom, error) {
+	if !validTerm(s) {
+		return Atom{}, fmt.Errorf("invalid term %q", s)
+	}
+	return fromText(s, AuraTAS), nil
+}
+
+// Text returns the bytes of a, least-significant first, as a string. This is
+// the inverse of Cord, Knot, and Term.
+func (a Atom) Text() string {
+	return string(flip(a.i.Bytes()))
+}
+
+func fromText(s string, aura Aura) Atom {
+	return Atom{
+		i:    new(big.Int).SetBytes(flip([]byte(s))),
+		aura: aura,
+	}
+}
+
+func validKnot(s string) bool {
+	for _, c := range []byte(s) {
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validTerm(s string) bool {
+	for i, c := range []byte(s) {
+		switch {
+		case 'a' <= c && c <= 'z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Quote renders s as a Hoon text literal delimited by quote, such as a cord
+// ('text') or a tape ("text"). The quote character and backslashes are escaped
+// with a backslash, and control characters and invalid UTF-8 are escaped as
+// \hh.
+func Quote(s string, quote byte) string {
+	var sb strings.Builder
+	sb.WriteByte(quote)
+	for len(s) > 0 {
+		r, n := utf8.DecodeRuneInString(s)
+		switch {
+		case r == rune(quote) || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r == 0x7f || (r == utf8.RuneError && n == 1):
+			fmt.Fprintf(&sb, "\\%02x", s[0])
+		default:
+			sb.WriteString(s[:n])
+		}
+		s = s[n:]
+	}
+	sb.WriteByte(quote)
+	return sb.String()
+}
+
+// wood escapes rs into knot-safe characters, as in Hoon's ++wood: ' ' becomes
+// '.', '.' becomes "~.", '~' becomes "~~", and any other character outside
+// [a-z0-9-] becomes '~', its hex codepoint, and '.'.
+func wood(rs []rune) string {
+	var sb strings.Builder
+	for _, r := range rs {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '-':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('.')
+		case r == '.':
+			sb.WriteString("~.")
+		case r == '~':
+			sb.WriteString("~~")
+		default:
+			sb.WriteString("~" + strconv.FormatUint(uint64(uint32(r)), 16) + ".")
+		}
+	}
+	return sb.String()
+}
+
+// unwood reverses wood.
+func unwood(s string) ([]rune, error) {
+	var rs []rune
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-':
+			rs = append(rs, rune(c))
+		case c == '.':
+			rs = append(rs, ' ')
+		case c == '~' && i+1 < len(s) && (s[i+1] == '.' || s[i+1] == '~'):
+			rs = append(rs, rune(s[i+1]))
+			i++
+		case c == '~':
+			j := strings.IndexByte(s[i:], '.')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated escape in %q", s)
+			}
+			u, err := strconv.ParseUint(s[i+1:i+j], 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape in %q", s)
+			}
+			rs = append(rs, rune(u))
+			i += j
+		default:
+			return nil, fmt.Errorf("invalid character %q in %q", c, s)
+		}
+	}
+	return rs, nil
+}
+
+// textRunes decodes s as UTF-8, treating invalid bytes as individual runes.
+func textRunes(s string) []rune {
+	var rs []rune
+	for len(s) > 0 {
+		r, n := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && n == 1 {
+			r = rune(s[0])
+		}
+		rs = append(rs, r)
+		s = s[n:]
+	}
+	return rs
+}
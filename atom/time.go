@@ -0,0 +1,334 @@
+package atom
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// @da atoms count 2^-64-second units since ~292277024401-.1.1 (i.e. the year
+// 292277024401 BC); @dr atoms count the same units.
+var (
+	oneSec    = new(big.Int).Lsh(big.NewInt(1), 64)
+	unixEpoch = new(big.Int).SetUint64(0x8000000cce9e0d80) // ~1970.1.1, in seconds
+	nsPerSec  = big.NewInt(1e9)
+
+	// the range of Unix times representable by time.Time; the minimum is
+	// mirrored, as time.Time miscomputes dates near its lower limit
+	minUnix = big.NewInt(math.MinInt64 + 62135596800)
+	maxUnix = big.NewInt(math.MaxInt64 - 62135596800)
+)
+
+// FromTime returns t as a @da atom. Sub-second precision is converted from
+// nanoseconds by rounding down, as in Hoon's (div (mul ~s1 ns) 1.000.000.000).
+func FromTime(t time.Time) Atom {
+	secs := new(big.Int).Add(unixEpoch, big.NewInt(t.Unix()))
+	return Atom{
+		i:    secs.Lsh(secs, 64).Add(secs, nsToFrac(int64(t.Nanosecond()))),
+		aura: AuraDA,
+	}
+}
+
+// Time returns a, interpreted as a @da, as a time.Time in UTC. Sub-second
+// precision is rounded to the nearest nanosecond. Dates outside the range of
+// time.Time are clamped to it.
+func (a Atom) Time() time.Time {
+	secs, frac := new(big.Int).QuoRem(a.i, oneSec, new(big.Int))
+	secs.Sub(secs, unixEpoch)
+	if secs.Cmp(maxUnix) >= 0 {
+		return time.Unix(maxUnix.Int64(), 999999999).UTC()
+	} else if secs.Cmp(minUnix) < 0 {
+		return time.Unix(minUnix.Int64(), 0).UTC()
+	}
+	return time.Unix(secs.Int64(), fracToNs(frac)).UTC()
+}
+
+// FromDuration returns d as a @dr atom. It panics if d is negative.
+func FromDuration(d time.Duration) Atom {
+	if d < 0 {
+		panic("negative duration")
+	}
+	secs := big.NewInt(int64(d / time.Second))
+	return Atom{
+		i:    secs.Lsh(secs, 64).Add(secs, nsToFrac(int64(d%time.Second))),
+		aura: AuraDR,
+	}
+}
+
+// Duration returns a, interpreted as a @dr, as a time.Duration. Sub-second
+// precision is rounded to the nearest nanosecond. Durations longer than the
+// maximum time.Duration are clamped to it.
+func (a Atom) Duration() time.Duration {
+	secs, frac := new(big.Int).QuoRem(a.i, oneSec, new(big.Int))
+	if secs.Cmp(big.NewInt(int64(math.MaxInt64/time.Second))) > 0 {
+		return math.MaxInt64
+	}
+	d := time.Duration(secs.Int64())*time.Second + time.Duration(fracToNs(frac))
+	if d < 0 {
+		return math.MaxInt64
+	}
+	return d
+}
+
+// Add returns a+b, with the aura of a. Adding a @dr to a @da thus yields a
+// @da.
+func (a Atom) Add(b Atom) Atom {
+	return Atom{
+		i:    new(big.Int).Add(a.i, b.i),
+		aura: a.aura,
+	}
+}
+
+// Sub returns a-b, with the aura of a, or @dr if both a and b are @da. Like
+// Hoon's ++sub, it panics if b > a.
+func (a Atom) Sub(b Atom) Atom {
+	if a.i.Cmp(b.i) < 0 {
+		panic("subtract underflow")
+	}
+	aura := a.aura
+	if a.aura == AuraDA && b.aura == AuraDA {
+		aura = AuraDR
+	}
+	return Atom{
+		i:    new(big.Int).Sub(a.i, b.i),
+		aura: aura,
+	}
+}
+
+// Cmp compares a and b, returning -1, 0, or +1.
+func (a Atom) Cmp(b Atom) int {
+	return a.i.Cmp(b.i)
+}
+
+func nsToFrac(ns int64) *big.Int {
+	frac := new(big.Int).Lsh(big.NewInt(ns), 64)
+	return frac.Quo(frac, nsPerSec)
+}
+
+func fracToNs(frac *big.Int) int64 {
+	ns := new(big.Int).Mul(frac, nsPerSec)
+	ns.Add(ns, new(big.Int).Rsh(oneSec, 1))
+	return ns.Rsh(ns, 64).Int64()
+}
+
+// formatDate renders a @da. Years before 1 AD are suffixed with '-'.
+func formatDate(a *big.Int) string {
+	secs, frac := new(big.Int).QuoRem(a, oneSec, new(big.Int))
+	days, tod := new(big.Int).DivMod(secs.Sub(secs, unixEpoch), big.NewInt(86400), new(big.Int))
+
+	// convert days since the Unix epoch to a proleptic Gregorian date; see
+	// http://howardhinnant.github.io/date_algorithms.html#civil_from_days
+	era, doeBig := new(big.Int).DivMod(days.Add(days, big.NewInt(719468)), big.NewInt(146097), new(big.Int))
+	doe := doeBig.Int64()
+	yoe := (doe - doe/1460 + doe/36524 - doe/146096) / 365
+	doy := doe - (365*yoe + yoe/4 - yoe/100)
+	mp := (5*doy + 2) / 153
+	day := doy - (153*mp+2)/5 + 1
+	month := mp + 3
+	if month > 12 {
+		month -= 12
+	}
+	year := era.Mul(era, big.NewInt(400)).Add(era, big.NewInt(yoe))
+	if month <= 2 {
+		year.Add(year, big.NewInt(1))
+	}
+	yearStr := year.String()
+	if year.Sign() <= 0 {
+		yearStr = year.Sub(big.NewInt(1), year).String() + "-"
+	}
+
+	s := fmt.Sprintf("%v.%v.%v", yearStr, month, day)
+	if t := tod.Int64(); t != 0 || frac.BitLen() != 0 {
+		s += fmt.Sprintf("..%02d.%02d.%02d", t/3600, t/60%60, t%60)
+	}
+	if frac.BitLen() != 0 {
+		s += ".." + formatFrac(frac)
+	}
+	return s
+}
+
+// formatFrac renders the fractional part of a @da or @dr as dot-separated
+// groups of four hex digits, omitting trailing zero groups.
+func formatFrac(frac *big.Int) string {
+	s := fmt.Sprintf("%016x", frac)
+	groups := []string{s[0:4], s[4:8], s[8:12], s[12:16]}
+	for groups[len(groups)-1] == "0000" {
+		groups = groups[:len(groups)-1]
+	}
+	return strings.Join(groups, ".")
+}
+
+func formatDuration(a *big.Int) string {
+	if a.BitLen() == 0 {
+		return "s0"
+	}
+	secs, rem := new(big.Int).QuoRem(a, oneSec, new(big.Int))
+	mins, secRem := new(big.Int).QuoRem(secs, big.NewInt(60), new(big.Int))
+	hrs, minRem := new(big.Int).QuoRem(mins, big.NewInt(60), new(big.Int))
+	days, hrRem := new(big.Int).QuoRem(hrs, big.NewInt(24), new(big.Int))
+	var sb strings.Builder
+	if days.BitLen() > 0 {
+		sb.WriteString(".d" + days.String())
+	}
+	if hrRem.BitLen() > 0 {
+		sb.WriteString(".h" + hrRem.String())
+	}
+	if minRem.BitLen() > 0 {
+		sb.WriteString(".m" + minRem.String())
+	}
+	if secRem.BitLen() > 0 {
+		sb.WriteString(".s" + secRem.String())
+	}
+	if rem.BitLen() > 0 {
+		if sb.Len() == 0 {
+			sb.WriteString("s0")
+		}
+		sb.WriteString(".." + formatFrac(rem))
+	}
+	return strings.TrimPrefix(sb.String(), ".")
+}
+
+// parseDate parses the body of a @da literal (without the leading ~), such as
+// 2020.1.1, 2020.1.1..12.00.00, or 2020.1.1..12.00.00..8000.
+func parseDate(s string) (*big.Int, error) {
+	bad := fmt.Errorf("invalid @da literal %q", "~"+s)
+	parts := strings.SplitN(s, "..", 3)
+	ymd := strings.Split(parts[0], ".")
+	if len(ymd) != 3 {
+		return nil, bad
+	}
+	bc := strings.HasSuffix(ymd[0], "-")
+	year, ok := new(big.Int).SetString(strings.TrimSuffix(ymd[0], "-"), 10)
+	if !ok || year.Sign() <= 0 || ymd[0][0] == '0' {
+		return nil, bad
+	} else if bc {
+		year.Sub(big.NewInt(1), year)
+	}
+	month, err1 := parseDecimal(ymd[1], 1, 12)
+	day, err2 := parseDecimal(ymd[2], 1, 31)
+	if err1 != nil || err2 != nil || day > daysIn(year, month) {
+		return nil, bad
+	}
+
+	// convert a proleptic Gregorian date to days since the Unix epoch; see
+	// http://howardhinnant.github.io/date_algorithms.html#days_from_civil
+	if month <= 2 {
+		year.Sub(year, big.NewInt(1))
+	}
+	era, yoeBig := new(big.Int).DivMod(year, big.NewInt(400), new(big.Int))
+	yoe := yoeBig.Int64()
+	mp := (month + 9) % 12
+	doy := (153*mp+2)/5 + day - 1
+	doe := yoe*365 + yoe/4 - yoe/100 + doy
+	days := era.Mul(era, big.NewInt(146097)).Add(era, big.NewInt(doe-719468))
+
+	secs := days.Mul(days, big.NewInt(86400)).Add(days, unixEpoch)
+	if len(parts) > 1 {
+		hms := strings.Split(parts[1], ".")
+		if len(hms) != 3 {
+			return nil, bad
+		}
+		h, err1 := parseDecimal(hms[0], 0, 23)
+		m, err2 := parseDecimal(hms[1], 0, 59)
+		sec, err3 := parseDecimal(hms[2], 0, 59)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, bad
+		}
+		secs.Add(secs, big.NewInt(h*3600+m*60+sec))
+	}
+	if secs.Sign() < 0 {
+		return nil, bad
+	}
+	a := secs.Lsh(secs, 64)
+	if len(parts) > 2 {
+		frac, err := parseFrac(parts[2])
+		if err != nil {
+			return nil, bad
+		}
+		a.Add(a, frac)
+	}
+	return a, nil
+}
+
+// parseDuration parses the body of a @dr literal (without the leading ~), such
+// as s0, d1.h2.m3.s4, or s0..8000.
+func parseDuration(s string) (*big.Int, error) {
+	bad := fmt.Errorf("invalid @dr literal %q", "~"+s)
+	parts := strings.SplitN(s, "..", 2)
+	units := []struct {
+		prefix byte
+		secs   int64
+	}{{'d', 86400}, {'h', 3600}, {'m', 60}, {'s', 1}}
+	secs := new(big.Int)
+	for _, f := range strings.Split(parts[0], ".") {
+		for len(units) > 0 && (len(f) == 0 || f[0] != units[0].prefix) {
+			units = units[1:]
+		}
+		if len(units) == 0 {
+			return nil, bad
+		}
+		n, ok := new(big.Int).SetString(f[1:], 10)
+		if !ok || n.Sign() < 0 || (f[1] == '0' && len(f) > 2) {
+			return nil, bad
+		}
+		secs.Add(secs, n.Mul(n, big.NewInt(units[0].secs)))
+		units = units[1:]
+	}
+	a := secs.Lsh(secs, 64)
+	if len(parts) > 1 {
+		frac, err := parseFrac(parts[1])
+		if err != nil {
+			return nil, bad
+		}
+		a.Add(a, frac)
+	}
+	return a, nil
+}
+
+// parseFrac parses the fractional part of a @da or @dr, the inverse of
+// formatFrac.
+func parseFrac(s string) (*big.Int, error) {
+	groups := strings.Split(s, ".")
+	if len(groups) > 4 {
+		return nil, fmt.Errorf("invalid fractional seconds %q", s)
+	}
+	frac := new(big.Int)
+	for i := 0; i < 4; i++ {
+		frac.Lsh(frac, 16)
+		if i < len(groups) {
+			g := groups[i]
+			u, err := strconv.ParseUint(g, 16, 16)
+			if err != nil || len(g) != 4 || strings.ToLower(g) != g {
+				return nil, fmt.Errorf("invalid fractional seconds %q", s)
+			}
+			frac.Or(frac, big.NewInt(int64(u)))
+		}
+	}
+	return frac, nil
+}
+
+// parseDecimal parses a small decimal number in the range [min, max].
+func parseDecimal(s string, min, max int64) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || s[0] == '+' || s[0] == '-' || n < min || n > max {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+func daysIn(year *big.Int, month int64) int64 {
+	switch month {
+	case 2:
+		y := new(big.Int)
+		if y.Mod(year, big.NewInt(4)).Sign() == 0 && (y.Mod(year, big.NewInt(100)).Sign() != 0 || y.Mod(year, big.NewInt(400)).Sign() == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
+}
@@ -0,0 +1,143 @@
+package azimuth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	"lukechampine.com/urbit/ob"
+)
+
+func TestTopics(t *testing.T) {
+	// topics from azimuth.hoon
+	tests := map[Hash]string{
+		TopicOwnerChanged: "0x16d0f539d49c6cad822b767a9445bfb1cf7ea6f2a6c2b120a7ea4cc7660d8fda",
+		TopicActivated:    "0xe74c03809d0769e1b1f706cc8414258cd1f3b6fe020cd15d0165c210ba503a0f",
+	}
+	for topic, exp := range tests {
+		if topic.String() != exp {
+			t.Errorf("wrong topic: expected %v, got %v", exp, topic)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	f, err := os.Open("testdata/logs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	logs, err := ReadLogsJSON(f)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 22 {
+		t.Fatal("wrong number of logs:", len(logs))
+	} else if logs[3].BlockNumber != 6784801 || logs[3].LogIndex != 0 {
+		t.Fatal("wrong log metadata:", logs[3].BlockNumber, logs[3].LogIndex)
+	}
+
+	s := NewState()
+	if err := s.ApplyLogs(logs); err != nil {
+		t.Fatal(err)
+	}
+	addr := func(b byte) (a Address) {
+		copy(a[:], bytes.Repeat([]byte{b}, len(a)))
+		return
+	}
+	zod, marzod := ob.AzimuthPoint(0), ob.AzimuthPoint(256)
+	planet := marzod.ChildPlanet(1)
+	if ps := s.SortedPoints(); len(ps) != 3 || ps[0] != zod || ps[1] != marzod || ps[2] != planet {
+		t.Fatal("wrong points:", ps)
+	}
+
+	z := s.Points[zod]
+	if z.Owner != addr(0xaa) || !z.Active || z.VotingProxy != addr(0xab) || z.SpawnProxy != addr(0xac) {
+		t.Errorf("wrong state for ~zod: %+v", z)
+	} else if len(z.Spawned) != 1 || z.Spawned[0] != marzod {
+		t.Errorf("wrong spawned list for ~zod: %v", z.Spawned)
+	}
+
+	m := s.Points[marzod]
+	if m.Owner != addr(0xbb) || !m.Active || m.ManagementProxy != addr(0xbc) {
+		t.Errorf("wrong state for ~marzod: %+v", m)
+	} else if m.HasSponsor || m.Sponsor != zod {
+		t.Errorf("~marzod should have lost its sponsor: %+v", m)
+	} else if m.CryptoSuite != 1 || m.KeyRevision != 1 || m.EncryptionKey[0] != 0x11 || m.AuthenticationKey[0] != 0x22 {
+		t.Errorf("wrong keys for ~marzod: %+v", m)
+	} else if p := m.Pass(); p.Crypt()[0] != 0x11 || p.Auth()[0] != 0x22 {
+		t.Errorf("wrong pass for ~marzod: %x", p)
+	}
+
+	p := s.Points[planet]
+	if p.Owner != addr(0xcc) || p.TransferProxy != addr(0xcd) || !p.Active {
+		t.Errorf("wrong state for %v: %+v", planet, p)
+	} else if !p.HasSponsor || p.Sponsor != 768 || p.EscapeRequested {
+		t.Errorf("%v should have escaped to ~wicdev: %+v", planet, p)
+	} else if p.KeyRevision != 2 || p.ContinuityNumber != 1 {
+		t.Errorf("wrong networking state for %v: %+v", planet, p)
+	}
+
+	if s.DNS != [3]string{"urbit.org", "urbit.org", "tlon.io"} {
+		t.Error("wrong DNS:", s.DNS)
+	}
+}
+
+func TestReadLogsJSONRPC(t *testing.T) {
+	js := `{"jsonrpc":"2.0","id":1,"result":[{"address":"0x223c067f8cf28ae173ee5cafea60ca44c335fecb","topics":["0xe74c03809d0769e1b1f706cc8414258cd1f3b6fe020cd15d0165c210ba503a0f","0x0000000000000000000000000000000000000000000000000000000000000100"],"data":"0x","blockNumber":"0x10","transactionHash":"0x0000000000000000000000000000000000000000000000000000000000000000","logIndex":"0x2"}]}`
+	logs, err := ReadLogsJSON(strings.NewReader(js))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 1 || logs[0].BlockNumber != 16 || logs[0].LogIndex != 2 {
+		t.Fatalf("wrong logs: %+v", logs)
+	}
+	if e, err := DecodeEvent(logs[0]); err != nil {
+		t.Fatal(err)
+	} else if e != (Activated{Point: 256}) {
+		t.Fatalf("wrong event: %+v", e)
+	}
+}
+
+func TestDecodeEventErrors(t *testing.T) {
+	var bigPoint Hash
+	bigPoint[0] = 1
+	tests := []Log{
+		{},
+		{Topics: []Hash{{1, 2, 3}}},
+		{Topics: []Hash{TopicOwnerChanged, {}}},
+		{Topics: []Hash{TopicActivated, bigPoint}},
+		{Topics: []Hash{TopicChangedKeys, {}}, Data: make([]byte, 64)},
+		{Topics: []Hash{TopicChangedDNS}, Data: make([]byte, 64)},
+	}
+	for i, l := range tests {
+		if _, err := DecodeEvent(l); err == nil {
+			t.Errorf("%v: expected error", i)
+		}
+	}
+	if _, err := DecodeEvent(tests[1]); err != ErrUnknownEvent {
+		t.Error("expected ErrUnknownEvent, got", err)
+	}
+}
+
+func TestDecodeLogRLP(t *testing.T) {
+	// [address, [Activated, 256], ""]
+	b, _ := hex.DecodeString("f85a94223c067f8cf28ae173ee5cafea60ca44c335fecbf842a0e74c03809d0769e1b1f706cc8414258cd1f3b6fe020cd15d0165c210ba503a0fa0000000000000000000000000000000000000000000000000000000000000010080")
+	l, err := DecodeLogRLP(b)
+	if err != nil {
+		t.Fatal(err)
+	} else if l.Address.String() != "0x223c067f8cf28ae173ee5cafea60ca44c335fecb" || len(l.Topics) != 2 || len(l.Data) != 0 {
+		t.Fatalf("wrong log: %+v", l)
+	} else if e, err := DecodeEvent(l); err != nil || e != (Activated{Point: 256}) {
+		t.Fatalf("wrong event: %+v %v", e, err)
+	}
+
+	for _, bad := range []string{"", "c0", "c3808080", "f85a94", b2h(b[:len(b)-1]), b2h(append(b, 0))} {
+		b, _ := hex.DecodeString(bad)
+		if _, err := DecodeLogRLP(b); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func b2h(b []byte) string { return hex.EncodeToString(b) }
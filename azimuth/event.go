@@ -0,0 +1,280 @@
+// Package azimuth decodes the events emitted by the Azimuth PKI contract and
+// folds them into a table of point states.
+package azimuth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/urbit/ob"
+)
+
+// An Event is a decoded Azimuth event.
+type Event interface {
+	isEvent()
+}
+
+func (OwnerChanged) isEvent()           {}
+func (Activated) isEvent()              {}
+func (Spawned) isEvent()                {}
+func (EscapeRequested) isEvent()        {}
+func (EscapeCanceled) isEvent()         {}
+func (EscapeAccepted) isEvent()         {}
+func (LostSponsor) isEvent()            {}
+func (ChangedKeys) isEvent()            {}
+func (BrokeContinuity) isEvent()        {}
+func (ChangedSpawnProxy) isEvent()      {}
+func (ChangedTransferProxy) isEvent()   {}
+func (ChangedManagementProxy) isEvent() {}
+func (ChangedVotingProxy) isEvent()     {}
+func (ChangedDNS) isEvent()             {}
+
+// OwnerChanged is emitted when a point is transferred.
+type OwnerChanged struct {
+	Point ob.AzimuthPoint
+	Owner Address
+}
+
+// Activated is emitted when a point is spawned, making it usable.
+type Activated struct {
+	Point ob.AzimuthPoint
+}
+
+// Spawned is emitted when a galaxy or star spawns a child.
+type Spawned struct {
+	Prefix ob.AzimuthPoint
+	Child  ob.AzimuthPoint
+}
+
+// EscapeRequested is emitted when a point requests a new sponsor.
+type EscapeRequested struct {
+	Point   ob.AzimuthPoint
+	Sponsor ob.AzimuthPoint
+}
+
+// EscapeCanceled is emitted when an escape request is canceled or rejected.
+type EscapeCanceled struct {
+	Point   ob.AzimuthPoint
+	Sponsor ob.AzimuthPoint
+}
+
+// EscapeAccepted is emitted when a point's sponsor changes as the result of an
+// accepted escape.
+type EscapeAccepted struct {
+	Point   ob.AzimuthPoint
+	Sponsor ob.AzimuthPoint
+}
+
+// LostSponsor is emitted when a point's sponsor detaches from it.
+type LostSponsor struct {
+	Point   ob.AzimuthPoint
+	Sponsor ob.AzimuthPoint
+}
+
+// ChangedKeys is emitted when a point configures new networking keys.
+type ChangedKeys struct {
+	Point             ob.AzimuthPoint
+	EncryptionKey     [32]byte
+	AuthenticationKey [32]byte
+	CryptoSuite       uint32
+	KeyRevision       uint32
+}
+
+// BrokeContinuity is emitted when a point breaches, resetting its network
+// state.
+type BrokeContinuity struct {
+	Point  ob.AzimuthPoint
+	Number uint32
+}
+
+// ChangedSpawnProxy is emitted when a point's spawn proxy changes.
+type ChangedSpawnProxy struct {
+	Point ob.AzimuthPoint
+	Proxy Address
+}
+
+// ChangedTransferProxy is emitted when a point's transfer proxy changes.
+type ChangedTransferProxy struct {
+	Point ob.AzimuthPoint
+	Proxy Address
+}
+
+// ChangedManagementProxy is emitted when a point's management proxy changes.
+type ChangedManagementProxy struct {
+	Point ob.AzimuthPoint
+	Proxy Address
+}
+
+// ChangedVotingProxy is emitted when a galaxy's voting proxy changes.
+type ChangedVotingProxy struct {
+	Point ob.AzimuthPoint
+	Proxy Address
+}
+
+// ChangedDNS is emitted when the DNS domains used for bootstrapping change.
+type ChangedDNS struct {
+	Primary   string
+	Secondary string
+	Tertiary  string
+}
+
+// Topic returns the topic hash of an event signature, e.g.
+// Topic("Activated(uint32)").
+func Topic(sig string) Hash {
+	var h Hash
+	k := sha3.NewLegacyKeccak256()
+	k.Write([]byte(sig))
+	k.Sum(h[:0])
+	return h
+}
+
+// Topics of each Azimuth event.
+var (
+	TopicOwnerChanged           = Topic("OwnerChanged(uint32,address)")
+	TopicActivated              = Topic("Activated(uint32)")
+	TopicSpawned                = Topic("Spawned(uint32,uint32)")
+	TopicEscapeRequested        = Topic("EscapeRequested(uint32,uint32)")
+	TopicEscapeCanceled         = Topic("EscapeCanceled(uint32,uint32)")
+	TopicEscapeAccepted         = Topic("EscapeAccepted(uint32,uint32)")
+	TopicLostSponsor            = Topic("LostSponsor(uint32,uint32)")
+	TopicChangedKeys            = Topic("ChangedKeys(uint32,bytes32,bytes32,uint32,uint32)")
+	TopicBrokeContinuity        = Topic("BrokeContinuity(uint32,uint32)")
+	TopicChangedSpawnProxy      = Topic("ChangedSpawnProxy(uint32,address)")
+	TopicChangedTransferProxy   = Topic("ChangedTransferProxy(uint32,address)")
+	TopicChangedManagementProxy = Topic("ChangedManagementProxy(uint32,address)")
+	TopicChangedVotingProxy     = Topic("ChangedVotingProxy(uint32,address)")
+	TopicChangedDNS             = Topic("ChangedDns(string,string,string)")
+)
+
+// ErrUnknownEvent is returned by DecodeEvent when a log's topic does not match
+// any Azimuth event.
+var ErrUnknownEvent = errors.New("unknown event")
+
+// DecodeEvent decodes the Azimuth event contained in l.
+func DecodeEvent(l Log) (Event, error) {
+	if len(l.Topics) == 0 {
+		return nil, errors.New("log has no topics")
+	}
+	d := eventDecoder{l: l}
+	var e Event
+	switch l.Topics[0] {
+	case TopicOwnerChanged:
+		e = OwnerChanged{d.point(1), d.address(2)}
+	case TopicActivated:
+		e = Activated{d.point(1)}
+	case TopicSpawned:
+		e = Spawned{d.point(1), d.point(2)}
+	case TopicEscapeRequested:
+		e = EscapeRequested{d.point(1), d.point(2)}
+	case TopicEscapeCanceled:
+		e = EscapeCanceled{d.point(1), d.point(2)}
+	case TopicEscapeAccepted:
+		e = EscapeAccepted{d.point(1), d.point(2)}
+	case TopicLostSponsor:
+		e = LostSponsor{d.point(1), d.point(2)}
+	case TopicChangedKeys:
+		e = ChangedKeys{d.point(1), d.word(0), d.word(1), d.uint32(2), d.uint32(3)}
+	case TopicBrokeContinuity:
+		e = BrokeContinuity{d.point(1), d.uint32(0)}
+	case TopicChangedSpawnProxy:
+		e = ChangedSpawnProxy{d.point(1), d.address(2)}
+	case TopicChangedTransferProxy:
+		e = ChangedTransferProxy{d.point(1), d.address(2)}
+	case TopicChangedManagementProxy:
+		e = ChangedManagementProxy{d.point(1), d.address(2)}
+	case TopicChangedVotingProxy:
+		e = ChangedVotingProxy{d.point(1), d.address(2)}
+	case TopicChangedDNS:
+		e = ChangedDNS{d.string(0), d.string(1), d.string(2)}
+	default:
+		return nil, ErrUnknownEvent
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return e, nil
+}
+
+// eventDecoder decodes ABI-encoded values from a log's topics and data. The
+// first error encountered is stored in err.
+type eventDecoder struct {
+	l   Log
+	err error
+}
+
+func (d *eventDecoder) setErr(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *eventDecoder) topic(i int) (h Hash) {
+	if i >= len(d.l.Topics) {
+		d.setErr(fmt.Errorf("missing topic %v", i))
+		return
+	}
+	return d.l.Topics[i]
+}
+
+func (d *eventDecoder) word(i int) (w [32]byte) {
+	if len(d.l.Data) < (i+1)*32 {
+		d.setErr(fmt.Errorf("missing data word %v", i))
+		return
+	}
+	copy(w[:], d.l.Data[i*32:])
+	return
+}
+
+func toUint32(w [32]byte) (uint32, bool) {
+	for _, b := range w[:28] {
+		if b != 0 {
+			return 0, false
+		}
+	}
+	return binary.BigEndian.Uint32(w[28:]), true
+}
+
+func (d *eventDecoder) point(topic int) ob.AzimuthPoint {
+	p, ok := toUint32(d.topic(topic))
+	if !ok {
+		d.setErr(fmt.Errorf("topic %v is not a valid point", topic))
+	}
+	return ob.AzimuthPoint(p)
+}
+
+func (d *eventDecoder) address(topic int) (a Address) {
+	t := d.topic(topic)
+	for _, b := range t[:12] {
+		if b != 0 {
+			d.setErr(fmt.Errorf("topic %v is not a valid address", topic))
+		}
+	}
+	copy(a[:], t[12:])
+	return
+}
+
+func (d *eventDecoder) uint32(word int) uint32 {
+	u, ok := toUint32(d.word(word))
+	if !ok {
+		d.setErr(fmt.Errorf("data word %v is not a valid uint32", word))
+	}
+	return u
+}
+
+func (d *eventDecoder) string(word int) string {
+	off, ok := toUint32(d.word(word))
+	if !ok || int(off)+32 > len(d.l.Data) {
+		d.setErr(fmt.Errorf("invalid string offset in data word %v", word))
+		return ""
+	}
+	var lw [32]byte
+	copy(lw[:], d.l.Data[off:])
+	n, ok := toUint32(lw)
+	if !ok || int(off)+32+int(n) > len(d.l.Data) {
+		d.setErr(fmt.Errorf("invalid string length in data word %v", word))
+		return ""
+	}
+	return string(d.l.Data[off+32:][:n])
+}
@@ -0,0 +1,229 @@
+package azimuth
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// An Address is an Ethereum address.
+type Address [20]byte
+
+// String implements fmt.Stringer.
+func (a Address) String() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Address) UnmarshalText(b []byte) error {
+	return decodeHex(a[:], string(b))
+}
+
+// A Hash is a 32-byte Ethereum hash, such as a log topic.
+type Hash [32]byte
+
+// String implements fmt.Stringer.
+func (h Hash) String() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Hash) UnmarshalText(b []byte) error {
+	return decodeHex(h[:], string(b))
+}
+
+// A Log is an Ethereum event log.
+type Log struct {
+	Address     Address
+	Topics      []Hash
+	Data        []byte
+	BlockNumber uint64
+	TxHash      Hash
+	LogIndex    uint64
+}
+
+// jsonLog is the JSON representation of a Log, as returned by eth_getLogs.
+type jsonLog struct {
+	Address     Address `json:"address"`
+	Topics      []Hash  `json:"topics"`
+	Data        string  `json:"data"`
+	BlockNumber string  `json:"blockNumber"`
+	TxHash      Hash    `json:"transactionHash"`
+	LogIndex    string  `json:"logIndex"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l Log) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonLog{
+		Address:     l.Address,
+		Topics:      l.Topics,
+		Data:        "0x" + hex.EncodeToString(l.Data),
+		BlockNumber: fmt.Sprintf("0x%x", l.BlockNumber),
+		TxHash:      l.TxHash,
+		LogIndex:    fmt.Sprintf("0x%x", l.LogIndex),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *Log) UnmarshalJSON(b []byte) error {
+	var jl jsonLog
+	if err := json.Unmarshal(b, &jl); err != nil {
+		return err
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(jl.Data, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid log data: %w", err)
+	}
+	*l = Log{
+		Address: jl.Address,
+		Topics:  jl.Topics,
+		Data:    data,
+		TxHash:  jl.TxHash,
+	}
+	if l.BlockNumber, err = decodeQuantity(jl.BlockNumber); err != nil {
+		return fmt.Errorf("invalid block number: %w", err)
+	} else if l.LogIndex, err = decodeQuantity(jl.LogIndex); err != nil {
+		return fmt.Errorf("invalid log index: %w", err)
+	}
+	return nil
+}
+
+// ReadLogsJSON reads a JSON array of logs, in the format returned by
+// eth_getLogs, from r. The array may also be wrapped in a JSON-RPC response.
+func ReadLogsJSON(r io.Reader) ([]Log, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Result *[]Log `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &resp); err == nil && resp.Result != nil {
+		return *resp.Result, nil
+	}
+	var logs []Log
+	if err := json.Unmarshal(raw, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// DecodeLogRLP decodes a log from its RLP encoding, as it appears in a
+// transaction receipt: [address, [topics...], data].
+func DecodeLogRLP(b []byte) (Log, error) {
+	item, rest, err := decodeRLP(b)
+	if err != nil {
+		return Log{}, err
+	} else if len(rest) != 0 {
+		return Log{}, errors.New("trailing bytes after log")
+	} else if len(item.list) != 3 || item.list[1].list == nil {
+		return Log{}, errors.New("log must be a list of [address, topics, data]")
+	}
+	var l Log
+	if len(item.list[0].str) != len(l.Address) {
+		return Log{}, errors.New("invalid log address")
+	}
+	copy(l.Address[:], item.list[0].str)
+	for _, t := range item.list[1].list {
+		if len(t.str) != len(Hash{}) {
+			return Log{}, errors.New("invalid log topic")
+		}
+		var h Hash
+		copy(h[:], t.str)
+		l.Topics = append(l.Topics, h)
+	}
+	l.Data = item.list[2].str
+	return l, nil
+}
+
+// An rlpItem is either a byte string or a list of items.
+type rlpItem struct {
+	str  []byte
+	list []rlpItem
+}
+
+func decodeRLP(b []byte) (item rlpItem, rest []byte, err error) {
+	if len(b) == 0 {
+		return rlpItem{}, nil, io.ErrUnexpectedEOF
+	}
+	readLen := func(n int) (int, error) {
+		if len(b) < 1+n || n > 8 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		l := new(big.Int).SetBytes(b[1 : 1+n])
+		if !l.IsInt64() || l.Int64() > int64(len(b)-1-n) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return int(l.Int64()), nil
+	}
+	var off, size int
+	isList := false
+	switch p := int(b[0]); {
+	case p < 0x80:
+		return rlpItem{str: b[:1]}, b[1:], nil
+	case p < 0xb8:
+		off, size = 1, p-0x80
+	case p < 0xc0:
+		if size, err = readLen(p - 0xb7); err != nil {
+			return rlpItem{}, nil, err
+		}
+		off = 1 + p - 0xb7
+	case p < 0xf8:
+		off, size, isList = 1, p-0xc0, true
+	default:
+		if size, err = readLen(p - 0xf7); err != nil {
+			return rlpItem{}, nil, err
+		}
+		off, isList = 1+p-0xf7, true
+	}
+	if len(b) < off+size {
+		return rlpItem{}, nil, io.ErrUnexpectedEOF
+	}
+	payload, rest := b[off:off+size], b[off+size:]
+	if !isList {
+		return rlpItem{str: payload}, rest, nil
+	}
+	item.list = []rlpItem{}
+	for len(payload) > 0 {
+		var elem rlpItem
+		if elem, payload, err = decodeRLP(payload); err != nil {
+			return rlpItem{}, nil, err
+		}
+		item.list = append(item.list, elem)
+	}
+	return item, rest, nil
+}
+
+func decodeHex(dst []byte, s string) error {
+	s = strings.TrimPrefix(s, "0x")
+	if hex.DecodedLen(len(s)) != len(dst) {
+		return fmt.Errorf("expected %v hex-encoded bytes, got %q", len(dst), s)
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+func decodeQuantity(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil // pending logs omit some fields
+	}
+	i, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok || !i.IsUint64() {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return i.Uint64(), nil
+}
@@ -0,0 +1,149 @@
+package azimuth
+
+import (
+	"sort"
+
+	"lukechampine.com/urbit/ob"
+)
+
+// A PointState is the Azimuth state of a single point.
+type PointState struct {
+	Owner  Address
+	Active bool
+
+	// networking keys
+	EncryptionKey     [32]byte
+	AuthenticationKey [32]byte
+	CryptoSuite       uint32
+	KeyRevision       uint32
+	ContinuityNumber  uint32
+
+	// sponsorship
+	Sponsor           ob.AzimuthPoint
+	HasSponsor        bool
+	EscapeRequested   bool
+	EscapeRequestedTo ob.AzimuthPoint
+
+	// proxies
+	ManagementProxy Address
+	SpawnProxy      Address
+	TransferProxy   Address
+	VotingProxy     Address
+
+	Spawned []ob.AzimuthPoint
+}
+
+// Pass returns the networking keys of the point as an ob.Pass. Azimuth stores
+// keys as big-endian atoms, so their bytes are reversed.
+func (ps *PointState) Pass() ob.Pass {
+	rev := func(k [32]byte) []byte {
+		for i := 0; i < len(k)/2; i++ {
+			k[i], k[len(k)-i-1] = k[len(k)-i-1], k[i]
+		}
+		return k[:]
+	}
+	return ob.PassFromKeys(rev(ps.EncryptionKey), rev(ps.AuthenticationKey))
+}
+
+// A State is a table of point states, built by applying Azimuth events in
+// order.
+type State struct {
+	Points map[ob.AzimuthPoint]*PointState
+	DNS    [3]string
+}
+
+// NewState returns an empty State.
+func NewState() *State {
+	return &State{
+		Points: make(map[ob.AzimuthPoint]*PointState),
+	}
+}
+
+// Point returns the state of p, creating it if necessary.
+func (s *State) Point(p ob.AzimuthPoint) *PointState {
+	ps, ok := s.Points[p]
+	if !ok {
+		ps = new(PointState)
+		s.Points[p] = ps
+	}
+	return ps
+}
+
+// SortedPoints returns the points in s, in ascending order.
+func (s *State) SortedPoints() []ob.AzimuthPoint {
+	ps := make([]ob.AzimuthPoint, 0, len(s.Points))
+	for p := range s.Points {
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i] < ps[j] })
+	return ps
+}
+
+// Apply applies e to s, mirroring the state transitions of the Azimuth
+// contract.
+func (s *State) Apply(e Event) {
+	switch e := e.(type) {
+	case OwnerChanged:
+		s.Point(e.Point).Owner = e.Owner
+	case Activated:
+		ps := s.Point(e.Point)
+		ps.Active = true
+		ps.HasSponsor = true
+		ps.Sponsor = e.Point
+		if !e.Point.IsGalaxy() {
+			ps.Sponsor = e.Point.Parent()
+		}
+	case Spawned:
+		ps := s.Point(e.Prefix)
+		ps.Spawned = append(ps.Spawned, e.Child)
+	case EscapeRequested:
+		ps := s.Point(e.Point)
+		ps.EscapeRequested = true
+		ps.EscapeRequestedTo = e.Sponsor
+	case EscapeCanceled:
+		ps := s.Point(e.Point)
+		ps.EscapeRequested = false
+		ps.EscapeRequestedTo = 0
+	case EscapeAccepted:
+		ps := s.Point(e.Point)
+		ps.HasSponsor = true
+		ps.Sponsor = e.Sponsor
+		ps.EscapeRequested = false
+		ps.EscapeRequestedTo = 0
+	case LostSponsor:
+		s.Point(e.Point).HasSponsor = false
+	case ChangedKeys:
+		ps := s.Point(e.Point)
+		ps.EncryptionKey = e.EncryptionKey
+		ps.AuthenticationKey = e.AuthenticationKey
+		ps.CryptoSuite = e.CryptoSuite
+		ps.KeyRevision = e.KeyRevision
+	case BrokeContinuity:
+		s.Point(e.Point).ContinuityNumber = e.Number
+	case ChangedSpawnProxy:
+		s.Point(e.Point).SpawnProxy = e.Proxy
+	case ChangedTransferProxy:
+		s.Point(e.Point).TransferProxy = e.Proxy
+	case ChangedManagementProxy:
+		s.Point(e.Point).ManagementProxy = e.Proxy
+	case ChangedVotingProxy:
+		s.Point(e.Point).VotingProxy = e.Proxy
+	case ChangedDNS:
+		s.DNS = [3]string{e.Primary, e.Secondary, e.Tertiary}
+	}
+}
+
+// ApplyLogs decodes each log and applies the resulting event to s. Logs that
+// do not contain Azimuth events are skipped.
+func (s *State) ApplyLogs(logs []Log) error {
+	for _, l := range logs {
+		e, err := DecodeEvent(l)
+		if err == ErrUnknownEvent {
+			continue
+		} else if err != nil {
+			return err
+		}
+		s.Apply(e)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Command hoonfmt formats Hoon source files.
+//
+// Usage:
+//
+//	hoonfmt [flags] [path ...]
+//
+// Without paths, hoonfmt formats standard input. Given a directory, it
+// formats every .hoon file within it, recursively. By default, the formatted
+// source is written to standard output.
+//
+// The flags are:
+//
+//	-d  display diffs instead of rewriting files
+//	-l  list files whose formatting differs from hoonfmt's
+//	-w  write the result to the source file instead of standard output
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"lukechampine.com/urbit/hoon/format"
+)
+
+var (
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+	list  = flag.Bool("l", false, "list files whose formatting differs from hoonfmt's")
+	write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hoonfmt [flags] [path ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			fatalf("cannot use -w with standard input")
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		if err := processFile("<standard input>", src); err != nil {
+			fatalf("%v", err)
+		}
+		return
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			} else if info.IsDir() || filepath.Ext(path) != ".hoon" {
+				return nil
+			}
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := processFile(path, src); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
+}
+
+func processFile(filename string, src []byte) error {
+	out, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+	if !bytes.Equal(src, out) {
+		if *list {
+			fmt.Println(filename)
+		}
+		if *write {
+			info, err := os.Stat(filename)
+			if err != nil {
+				return err
+			} else if err := ioutil.WriteFile(filename, out, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+		if *diff {
+			d, err := diffSource(filename, src, out)
+			if err != nil {
+				return fmt.Errorf("computing diff: %v", err)
+			}
+			os.Stdout.Write(d)
+		}
+	}
+	if !*list && !*write && !*diff {
+		_, err = os.Stdout.Write(out)
+	}
+	return err
+}
+
+// diffSource returns a unified diff of a and b, using the system diff
+// command.
+func diffSource(filename string, a, b []byte) ([]byte, error) {
+	fa, err := writeTemp(a)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fa)
+	fb, err := writeTemp(b)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fb)
+
+	out, err := exec.Command("diff", "-u",
+		"--label", filename+".orig", "--label", filename,
+		fa, fb).Output()
+	if len(out) > 0 {
+		// diff exits with status 1 when the files differ
+		return out, nil
+	}
+	return out, err
+}
+
+func writeTemp(data []byte) (string, error) {
+	f, err := ioutil.TempFile("", "hoonfmt")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "hoonfmt: "+format+"\n", args...)
+	os.Exit(2)
+}
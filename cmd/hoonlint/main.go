@@ -0,0 +1,115 @@
+// Command hoonlint reports style and correctness problems in Hoon source
+// files.
+//
+// Usage:
+//
+//	hoonlint [flags] [path ...]
+//
+// Without paths, hoonlint checks standard input. Given a directory, it checks
+// every .hoon file within it, recursively. Each problem is printed as
+// file:line:col: message (check). hoonlint exits with status 1 if any
+// problems were found.
+//
+// The flags are:
+//
+//	-checks  comma-separated list of checks to run (default: all)
+//
+// The available checks are listed by hoonlint -h. Additional checks can be
+// written as lint.Analyzers.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lukechampine.com/urbit/hoon/lint"
+)
+
+var checks = flag.String("checks", "", "comma-separated list of checks to run (default: all)")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hoonlint [flags] [path ...]")
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "\nchecks:")
+		for _, a := range lint.Analyzers {
+			fmt.Fprintf(os.Stderr, "  %-10v %v\n", a.Name, a.Doc)
+		}
+	}
+	flag.Parse()
+
+	analyzers := lint.Analyzers
+	if *checks != "" {
+		analyzers = nil
+		for _, name := range strings.Split(*checks, ",") {
+			a := lookup(name)
+			if a == nil {
+				fatalf("unknown check %q", name)
+			}
+			analyzers = append(analyzers, a)
+		}
+	}
+
+	problems, failed := false, false
+	check := func(filename string, src []byte) {
+		diags, err := lint.Run(src, analyzers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", filename, err)
+			failed = true
+		}
+		for _, d := range diags {
+			fmt.Printf("%v:%v\n", filename, d)
+			problems = true
+		}
+	}
+
+	if flag.NArg() == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		check("<standard input>", src)
+	}
+	for _, path := range flag.Args() {
+		err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			} else if info.IsDir() || filepath.Ext(path) != ".hoon" {
+				return nil
+			}
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			check(path, src)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(2)
+	} else if problems {
+		os.Exit(1)
+	}
+}
+
+func lookup(name string) *lint.Analyzer {
+	for _, a := range lint.Analyzers {
+		if a.Name == strings.TrimSpace(name) {
+			return a
+		}
+	}
+	return nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "hoonlint: "+format+"\n", args...)
+	os.Exit(2)
+}
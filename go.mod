@@ -3,6 +3,10 @@ module lukechampine.com/urbit
 go 1.14
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/llir/llvm v0.3.1
 	github.com/spaolacci/murmur3 v1.1.0
+	golang.org/x/crypto v0.9.0
+	golang.org/x/sys v0.9.0 // indirect
+	lukechampine.com/frand v1.4.2
 )
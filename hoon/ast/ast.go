@@ -3,6 +3,7 @@ package ast
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"lukechampine.com/urbit/hoon/token"
 )
@@ -11,293 +12,411 @@ type Node interface {
 	isNode()
 }
 
-func (Buc) isNode()  {}
-func (Pat) isNode()  {}
-func (Dot) isNode()  {}
-func (Face) isNode() {}
-func (Slot) isNode() {}
-func (Tis) isNode()  {}
-func (Num) isNode()  {}
-func (Rune) isNode() {}
-func (Cell) isNode() {}
+func (Buc) isNode()     {}
+func (Pat) isNode()     {}
+func (Tar) isNode()     {}
+func (Wut) isNode()     {}
+func (Ket) isNode()     {}
+func (Face) isNode()    {}
+func (Wing) isNode()    {}
+func (Tis) isNode()     {}
+func (Num) isNode()     {}
+func (Literal) isNode() {}
+func (Path) isNode()    {}
+func (Sail) isNode()    {}
+func (File) isNode()    {}
+func (Rune) isNode()    {}
+func (Cell) isNode()    {}
 
 type Buc struct {
-	Tok token.Token
+	Tok    token.Token
+	Syntax *Syntax
 }
 
+// A Pat is the atom mold, @, optionally with an aura, e.g. @ux.
 type Pat struct {
-	Tok token.Token
+	Tok    token.Token
+	Aura   string
+	Syntax *Syntax
 }
 
-// TODO: replace with wing?
-type Dot struct {
-	Tok token.Token
+// A Tar is the noun mold, *.
+type Tar struct {
+	Tok    token.Token
+	Syntax *Syntax
+}
+
+// A Ket is the cell mold, ^.
+type Ket struct {
+	Tok    token.Token
+	Syntax *Syntax
+}
+
+// A Wut is the loobean mold, ?.
+type Wut struct {
+	Tok    token.Token
+	Syntax *Syntax
 }
 
 type Face struct {
-	Tok  token.Token
-	Name string
+	Tok    token.Token
+	Name   string
+	Syntax *Syntax
+}
+
+// A Wing is a path into the subject, such as a.b.c, +<, or ^face. Its limbs
+// are searched for right-to-left: a.b is a within b. A Wing with no limbs is
+// the subject itself, written as a single dot. Wings consisting of a single
+// plain face or $ are represented as a Face or Buc instead.
+type Wing struct {
+	Tok    token.Token
+	Limbs  []Limb
+	Syntax *Syntax
+}
+
+// A Limb is one component of a Wing: either a name (a face or arm) or an
+// axis.
+type Limb struct {
+	Name string // face or arm name, or "$"; empty for axis limbs
+	Skip int    // number of ^ prefixes, i.e. matches of Name to skip
+	Core bool   // ..name: the core containing the arm Name
+	Axis string // for axis limbs, the axis in decimal
+	Lit  string // for axis limbs, the original syntax, e.g. +<, +6, &2, or |1
+}
+
+// AxisWing returns a Wing containing a single axis limb.
+func AxisWing(axis string) Wing {
+	return Wing{Limbs: []Limb{{Axis: axis}}}
 }
 
-type Slot struct {
-	Tok     token.Token
-	Address string
+func (l Limb) String() string {
+	switch {
+	case l.Lit != "":
+		return l.Lit
+	case l.Axis != "":
+		return "+" + l.Axis
+	case l.Core:
+		return ".." + l.Name
+	}
+	return strings.Repeat("^", l.Skip) + l.Name
+}
+
+func (w Wing) String() string {
+	if len(w.Limbs) == 0 {
+		return "."
+	}
+	parts := make([]string, len(w.Limbs))
+	for i, l := range w.Limbs {
+		parts[i] = l.String()
+	}
+	return strings.Join(parts, ".")
 }
 
 type Tis struct {
-	Tok   token.Token
-	Left  Node
-	Right Node
+	Tok    token.Token
+	Left   Node
+	Right  Node
+	Syntax *Syntax
 }
 
 type Num struct {
-	Tok token.Token
-	Int string
+	Tok    token.Token
+	Int    string
+	Syntax *Syntax
 }
 
-type Rune struct {
+// A Literal is a constant other than a decimal number: an atom with an
+// explicit aura, such as 0x10, ~zod, or --5; a term, such as %foo or %.y; a
+// cord or tape, written with its quotes; or one of ~, &, and |.
+type Literal struct {
+	Tok    token.Token
+	Lit    string
+	Syntax *Syntax
+}
+
+// A Path is a path literal, such as /foo/(scot %ud 1). Each segment is a
+// Literal (a term or knot) or an arbitrary expression. The path / has no
+// segments.
+type Path struct {
+	Tok    token.Token
+	Segs   []Node
+	Syntax *Syntax
+}
+
+// A Sail is an XML element written in Sail, Hoon's templating syntax, such
+// as ;div.foo: text. If Tag is empty, it is a text node, such as ; text.
+type Sail struct {
+	Tok    token.Token
+	Tag    string
+	Attrs  []SailAttr
+	Text   string // inline text, e.g. after ;p:
+	Void   bool   // written with a trailing ;, e.g. ;br;
+	Kids   []Node
+	Syntax *Syntax
+}
+
+// A SailAttr is an attribute of a Sail element. Classes (.foo) and ids (#foo)
+// are represented as class and id attributes.
+type SailAttr struct {
+	Key   string
+	Value Node
+}
+
+// A Ford is a build system directive at the top of a file, such as
+// /+  default-agent or /=  foo  /lib/foo. Its arguments are kept verbatim.
+type Ford struct {
 	Tok  token.Token
 	Lit  string
-	Args []Node
+	Args []string
+}
+
+// A File is a parsed source file: a list of Ford directives followed by a
+// body. If the file contains multiple top-level expressions, they are
+// composed with =~.
+type File struct {
+	Ford   []Ford
+	Body   Node
+	Syntax *Syntax
+}
+
+type Rune struct {
+	Tok    token.Token
+	Lit    string
+	Args   []Node
+	Syntax *Syntax
 }
 
 type Cell struct {
-	Tok  token.Token
-	Head Node
-	Tail Node
+	Tok    token.Token
+	Head   Node
+	Tail   Node
+	Syntax *Syntax
 }
 
-func (r Rune) Reduce() Rune {
-	switch r.Lit {
-	// these are the only "real" runes; every other rune reduces to one of these
-	case "=~", "%=", "?:":
-		return r
-
-	// these runes are just flipped versions of other runes
-	case "=<", "%.":
-		return Rune{
-			Lit: map[string]string{
-				"=<": "=>",
-				"=-": "=+",
-				"%.": "%-",
-				"?.": "?:",
-			}[r.Lit],
-			Args: []Node{
-				r.Args[1],
-				r.Args[0],
-			},
-		}.Reduce()
-
-	// tis
-	case "=>":
-		return Rune{
-			Lit:  "=~",
-			Args: r.Args,
-		}.Reduce()
-	case "=+":
-		return Rune{
-			Lit: "=>",
-			Args: []Node{
-				Cell{
-					Head: r.Args[0],
-					Tail: Dot{},
-				},
-				r.Args[1],
-			},
-		}.Reduce()
-	case "=.", "=:":
-		return Rune{
-			Lit: "=>",
-			Args: []Node{
-				Rune{
-					Lit: "%_",
-					Args: append([]Node{
-						Dot{},
-					}, r.Args[:len(r.Args)-1]...),
-				},
-				r.Args[len(r.Args)-1],
-			},
-		}.Reduce()
-	case "=|":
-		return Rune{
-			Lit: "=+",
-			Args: []Node{
-				Rune{
-					Lit:  "^*",
-					Args: []Node{r.Args[0]},
-				},
-				r.Args[1],
-			},
-		}.Reduce()
-	case "=/":
-		return Rune{
-			Lit: "=+",
-			Args: []Node{
-				Tis{
-					Left:  r.Args[0],
-					Right: r.Args[1],
-				},
-				r.Args[2],
-			},
-		}.Reduce()
-	case "=;":
-		return Rune{
-			Lit: "=/",
-			Args: []Node{
-				r.Args[0],
-				r.Args[2],
-				r.Args[1],
-			},
-		}.Reduce()
-	case "=?":
-		return Rune{
-			Lit: "=.",
-			Args: []Node{
-				r.Args[0],
-				Rune{
-					Lit: "?:",
-					Args: []Node{
-						r.Args[1],
-						r.Args[2],
-						r.Args[0],
-					},
-				},
-				r.Args[3],
-			},
-		}.Reduce()
-
-	// bar
-	case "|.":
-		return Rune{
-			Lit: "|%",
-			Args: []Node{
-				Buc{},
-				r.Args[0],
-			},
-		}.Reduce()
-	case "|_":
-		return Rune{
-			Lit: "=|",
-			Args: []Node{
-				r.Args[0],
-				Rune{
-					Lit:  "|%",
-					Args: r.Args[1:],
-				},
-			},
-		}.Reduce()
-	case "|=":
-		return Rune{
-			Lit: "=|",
-			Args: []Node{
-				r.Args[0],
-				Rune{
-					Lit:  "|.",
-					Args: []Node{r.Args[1]},
-				},
-			},
-		}.Reduce()
-	case "|^", "|-":
-		return Rune{
-			Lit: "=>",
-			Args: []Node{
-				Rune{
-					Lit: "|%",
-					Args: append([]Node{
-						Buc{},
-						r.Args[0],
-					}, r.Args[1:]...),
-				},
-				Buc{},
-			},
-		}.Reduce()
-
-	// cen
-	case "%_":
-		return Rune{
-			Lit: "^+",
-			Args: []Node{
-				r.Args[0],
-				Rune{
-					Lit:  "%=",
-					Args: r.Args[1:],
-				},
-			},
-		}.Reduce()
-	case "%~":
-		return Rune{
-			Lit: "=+",
-			Args: []Node{
-				r.Args[1],
-				Rune{
-					Lit: "=>",
-					Args: []Node{
-						Rune{
-							Lit: "%=",
-							Args: []Node{
-								Slot{Address: "2"},
-								Slot{Address: "6"},
-								r.Args[2],
-							},
-						},
-						r.Args[0],
-					},
-				},
-			},
-		}.Reduce()
-	case "%-":
-		return Rune{
-			Lit:  "%~",
-			Args: []Node{Buc{}, r.Args[0], r.Args[1]},
-		}.Reduce()
+// pairRunes maps each rune whose variable arguments come in pairs, such as
+// the wing-value pairs of %= or the case-body pairs of ?-, to the number of
+// fixed arguments preceding the pairs. The pairs of =: are followed by one
+// more argument.
+var pairRunes = map[string]int{
+	"%=": 1,
+	"%_": 1,
+	"%*": 2,
+	"?-": 1,
+	"?+": 2,
+	"=:": 0,
+	"+*": 0,
+}
 
-	default:
-		panic("unhandled reduction " + r.Lit)
-	}
+// joggingRunes maps each rune whose variable arguments are terminated by ==
+// in tall form to the number of fixed arguments following the ==.
+var joggingRunes = map[string]int{
+	"=:": 1,
+	"=~": 0,
+	"?|": 0,
+	"?&": 0,
+	"?-": 0,
+	"?+": 0,
+	":*": 0,
+	":~": 0,
+	"%:": 0,
+	"%=": 0,
+	"%_": 0,
+	"%*": 0,
+	"$%": 0,
+	"$:": 0,
+	"$?": 0,
+	";:": 0,
+	";=": 0,
+	";~": 0,
+}
+
+// coreRunes lists the runes whose arguments end with arms, terminated by --
+// in tall form. Cores have no wide form.
+var coreRunes = map[string]struct{}{
+	"|%": {},
+	"|@": {},
+	"|_": {},
+	"|^": {},
+}
+
+// armRunes lists the arms of a core.
+var armRunes = map[string]struct{}{
+	"++": {},
+	"+$": {},
+	"+|": {},
+	"+*": {},
+}
+
+// tall reports whether n has no wide form: whether it is, or contains, a core,
+// an arm, or =:.
+func tall(n Node) (t bool) {
+	Inspect(n, func(n Node) bool {
+		switch n := n.(type) {
+		case Rune:
+			_, core := coreRunes[n.Lit]
+			_, arm := armRunes[n.Lit]
+			t = t || core || arm || n.Lit == "=:"
+			return !t
+		case Cell, Tis:
+			return !t
+		}
+		return false
+	})
+	return t
+}
+
+func Print(w io.Writer, n Node) error {
+	return fprint(w, n, false)
 }
 
-func Print(w io.Writer, n Node) (err error) {
+// fprint prints n in canonical form. If concrete is set, its children are
+// printed with PrintConcrete.
+func fprint(w io.Writer, n Node, concrete bool) (err error) {
 	writeString := func(str string) {
 		if err == nil {
 			_, err = io.WriteString(w, str)
 		}
 	}
 	writeNode := func(p Node) {
-		if err == nil {
-			err = Print(w, p)
+		if err == nil && concrete {
+			err = PrintConcrete(w, p)
+		} else if err == nil {
+			err = fprint(w, p, false)
 		}
 	}
 	switch n := n.(type) {
 	case Buc:
 		writeString("$")
 	case Pat:
-		writeString("@")
-	case Dot:
-		writeString(".")
+		writeString("@" + n.Aura)
+	case Tar:
+		writeString("*")
+	case Wut:
+		writeString("?")
+	case Ket:
+		writeString("^")
+	case Literal:
+		writeString(n.Lit)
+	case Path:
+		if len(n.Segs) == 0 {
+			writeString("/")
+		}
+		for _, seg := range n.Segs {
+			writeString("/")
+			writeNode(seg)
+		}
+	case Sail:
+		if n.Tag == "" {
+			writeString("; " + n.Text)
+			break
+		}
+		writeString(";" + n.Tag)
+		if len(n.Attrs) > 0 {
+			writeString("(")
+			for i, a := range n.Attrs {
+				if i > 0 {
+					writeString(", ")
+				}
+				writeString(a.Key + " ")
+				writeNode(a.Value)
+			}
+			writeString(")")
+		}
+		switch {
+		case n.Void:
+			writeString(";")
+		case n.Text != "":
+			writeString(": " + n.Text)
+		case len(n.Kids) > 0:
+			for _, k := range n.Kids {
+				writeString(" ")
+				writeNode(k)
+			}
+			writeString(" ==")
+		}
+	case File:
+		for _, f := range n.Ford {
+			writeString(f.Lit)
+			sep := "  "
+			for _, a := range f.Args {
+				writeString(sep + a)
+				if f.Lit == "/-" || f.Lit == "/+" {
+					sep = ", "
+				}
+			}
+			writeString("\n")
+		}
+		writeNode(n.Body)
+	case Wing:
+		writeString(n.String())
 	case Face:
 		writeString(n.Name)
 	case Tis:
+		if tall(n) {
+			writeString("^=  ")
+			writeNode(n.Left)
+			writeString("  ")
+			writeNode(n.Right)
+			break
+		}
 		writeNode(n.Left)
 		writeString("=")
 		writeNode(n.Right)
 	case Num:
 		writeString(n.Int)
 	case Rune:
-		switch n.Lit {
-		case "%=":
+		if tall(n) {
+			// runes without a wide form, and runes containing them, are
+			// printed in tall form on a single line, e.g. |%  ++  a  1  --
+			_, jogging := joggingRunes[n.Lit]
+			post := joggingRunes[n.Lit]
+			writeString(n.Lit)
+			for i, arg := range n.Args {
+				if jogging && i == len(n.Args)-post {
+					writeString("  ==")
+				}
+				writeString("  ")
+				writeNode(arg)
+			}
+			if _, ok := coreRunes[n.Lit]; ok {
+				writeString("  --")
+			} else if jogging && post == 0 {
+				writeString("  ==")
+			}
+			break
+		}
+		if fixed, ok := pairRunes[n.Lit]; ok {
+			post := 0
+			if n.Lit == "=:" {
+				post = 1
+			}
 			writeString(n.Lit)
 			writeString("(")
-			writeNode(n.Args[0])
-			for i := 1; i < len(n.Args); i += 2 {
-				if i > 1 {
+			for i, arg := range n.Args[:fixed] {
+				if i > 0 {
+					writeString(" ")
+				}
+				writeNode(arg)
+			}
+			for i := fixed; i+1 < len(n.Args)-post; i += 2 {
+				if i > fixed {
 					writeString(",")
 				}
-				writeString(" ")
+				if i > 0 {
+					writeString(" ")
+				}
 				writeNode(n.Args[i])
 				writeString(" ")
 				writeNode(n.Args[i+1])
 			}
+			for _, arg := range n.Args[len(n.Args)-post:] {
+				writeString(" ")
+				writeNode(arg)
+			}
 			writeString(")")
+			break
+		}
+		switch n.Lit {
 		case "%-":
 			writeString("(")
 			for i, arg := range n.Args {
@@ -309,6 +428,9 @@ func Print(w io.Writer, n Node) (err error) {
 			writeString(")")
 		default:
 			writeString(n.Lit)
+			if len(n.Args) == 0 {
+				break // e.g. !!
+			}
 			writeString("(")
 			for i, arg := range n.Args {
 				if i > 0 {
@@ -319,6 +441,13 @@ func Print(w io.Writer, n Node) (err error) {
 			writeString(")")
 		}
 	case Cell:
+		if tall(n) {
+			writeString(":-  ")
+			writeNode(n.Head)
+			writeString("  ")
+			writeNode(n.Tail)
+			break
+		}
 		writeString("[")
 		writeNode(n.Head)
 		writeString(" ")
@@ -0,0 +1,404 @@
+package ast
+
+import (
+	"fmt"
+
+	"lukechampine.com/urbit/hoon/token"
+)
+
+// primitiveRunes lists the runes that Reduce leaves as is. As in ++open:ap,
+// every other rune is shorthand for some combination of these (and of the
+// primitive irregular forms: cells, faces, wings, and literals).
+var primitiveRunes = map[string]struct{}{
+	// nock
+	".^": {}, ".+": {}, ".*": {}, ".=": {}, ".?": {},
+	// compiler directives
+	"!>": {}, "!<": {}, "!:": {}, "!.": {}, "!=": {}, "!?": {}, "!!": {}, "!,": {}, "!@": {},
+	// subject
+	"=>": {}, "=,": {}, "=*": {},
+	// branches
+	"?:": {}, "?=": {},
+	// cores and arms
+	"|%": {}, "|@": {}, "|$": {}, "++": {}, "+$": {}, "+|": {}, "+*": {},
+	// wing resolution
+	"%=": {},
+	// types
+	"^+": {}, "^*": {}, "^|": {}, "^&": {}, "^?": {}, "^~": {}, "^:": {},
+	// hints
+	"~>": {}, "~!": {},
+	// structures
+	"$_": {}, "$%": {}, "$:": {}, "$?": {}, "$<": {}, "$>": {}, "$-": {}, "$@": {},
+	"$^": {}, "$~": {}, "$=": {}, "$+": {}, "$;": {}, "$&": {}, "$|": {},
+	// sail
+	";+": {}, ";/": {}, ";*": {}, ";=": {},
+}
+
+// reduceArity maps each non-primitive rune to its minimum number of
+// arguments and, for runes with a fixed number of arguments, its maximum;
+// variable arguments are indicated by -1. Runes whose variable arguments come
+// in pairs are listed in pairRunes.
+var reduceArity = map[string][2]int{
+	"=<": {2, 2}, "=-": {2, 2}, "=~": {1, -1}, "=+": {2, 2}, "=.": {3, 3},
+	"=:": {1, -1}, "=|": {2, 2}, "=/": {3, 3}, "=;": {3, 3}, "=?": {4, 4},
+	"=^": {4, 4},
+	"?.": {3, 3}, "?>": {2, 2}, "?<": {2, 2}, "?!": {1, 1}, "?|": {0, -1},
+	"?&": {0, -1}, "?@": {3, 3}, "?^": {3, 3}, "?~": {3, 3}, "?-": {1, -1},
+	"?+": {2, -1},
+	"|.": {1, 1}, "|-": {1, 1}, "|^": {1, -1}, "|_": {1, -1}, "|=": {2, 2},
+	"|:": {2, 2}, "|?": {1, 1}, "|~": {2, 2}, "|*": {2, 2},
+	":-": {2, 2}, ":_": {2, 2}, ":+": {3, 3}, ":^": {4, 4}, ":*": {1, -1},
+	":~": {0, -1},
+	"%.": {2, 2}, "%~": {3, 3}, "%-": {1, -1}, "%+": {3, 3}, "%^": {4, 4},
+	"%:": {1, -1}, "%_": {1, -1}, "%*": {2, -1},
+	"^-": {2, 2}, "^.": {2, 2}, "^=": {2, 2},
+	";:": {2, -1}, ";~": {2, -1}, ";<": {4, 4}, ";;": {2, 2},
+	"~|": {2, 2}, "~_": {2, 2}, "~$": {2, 2}, "~%": {4, 4}, "~<": {2, 2},
+	"~+": {1, 1}, "~/": {2, 2}, "~&": {2, 2}, "~?": {3, 3}, "~=": {2, 2},
+}
+
+// Reduce desugars r into a primitive form, mirroring ++open:ap. The result is
+// either a primitive rune or a primitive irregular form, such as a cell; the
+// arguments of the result are not themselves reduced. To reduce every rune in
+// a tree, use Desugar.
+func (r Rune) Reduce() (Node, error) {
+	var n Node = r
+	for {
+		r, ok := n.(Rune)
+		if !ok {
+			return n, nil
+		} else if _, ok := primitiveRunes[r.Lit]; ok {
+			return r, nil
+		}
+		var err error
+		if n, err = r.open(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Desugar reduces every rune in n, as by Rune.Reduce.
+func Desugar(n Node) (Node, error) {
+	var err error
+	n = Rewrite(n, func(n Node) Node {
+		if err != nil {
+			return n
+		}
+		if r, ok := n.(Rune); ok {
+			var reduced Node
+			if reduced, err = r.Reduce(); err != nil {
+				return n
+			}
+			n = reduced
+		}
+		if p, ok := n.(Path); ok {
+			n = openPath(p)
+		}
+		return n
+	})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// checkArgs returns an error if r has the wrong number of arguments for
+// reduction.
+func (r Rune) checkArgs() error {
+	arity, ok := reduceArity[r.Lit]
+	if !ok {
+		return fmt.Errorf("unknown rune %v", r.Lit)
+	}
+	min, max := arity[0], arity[1]
+	switch {
+	case len(r.Args) < min:
+		return fmt.Errorf("%v requires at least %v arguments, got %v", r.Lit, min, len(r.Args))
+	case max >= 0 && len(r.Args) > max:
+		return fmt.Errorf("%v requires %v arguments, got %v", r.Lit, max, len(r.Args))
+	}
+	if fixed, ok := pairRunes[r.Lit]; ok {
+		post := joggingRunes[r.Lit]
+		if (len(r.Args)-fixed-post)%2 != 0 {
+			return fmt.Errorf("%v requires pairs of arguments", r.Lit)
+		}
+	}
+	return nil
+}
+
+// open performs a single step of reduction on the non-primitive rune r.
+func (r Rune) open() (Node, error) {
+	if err := r.checkArgs(); err != nil {
+		return nil, err
+	}
+	a := r.Args
+	switch r.Lit {
+	// flipped versions of other runes
+	case "=<":
+		return newRune("=>", a[1], a[0]), nil
+	case "=-":
+		return newRune("=+", a[1], a[0]), nil
+	case "%.":
+		return newRune("%-", a[1], a[0]), nil
+	case "?.":
+		return newRune("?:", a[0], a[2], a[1]), nil
+
+	// tis
+	case "=~":
+		if len(a) == 1 {
+			return a[0], nil
+		}
+		return newRune("=>", a[0], newRune("=~", a[1:]...)), nil
+	case "=+":
+		return newRune("=>", Cell{Head: a[0], Tail: Wing{}}, a[1]), nil
+	case "=.", "=:":
+		last := len(a) - 1
+		return newRune("=>", newRune("%_", append([]Node{Wing{}}, a[:last]...)...), a[last]), nil
+	case "=|":
+		return newRune("=+", newRune("^*", a[0]), a[1]), nil
+	case "=/":
+		if t, ok := a[0].(Tis); ok {
+			// =/  a=@  1  b is =+  a=^-(@ 1)  b
+			return newRune("=+", Tis{Left: t.Left, Right: newRune("^-", t.Right, a[1])}, a[2]), nil
+		}
+		return newRune("=+", Tis{Left: a[0], Right: a[1]}, a[2]), nil
+	case "=;":
+		return newRune("=/", a[0], a[2], a[1]), nil
+	case "=?":
+		return newRune("=.", a[0], newRune("?:", a[1], a[2], a[0]), a[3]), nil
+	case "=^":
+		// push the product, then take its head and tail from +4 and +13
+		return newRune("=+", a[2],
+			newRune("=/", a[0], AxisWing("4"),
+				newRune("=.", a[1], AxisWing("13"), a[3]))), nil
+
+	// wut
+	case "?>":
+		return newRune("?:", a[0], a[1], newRune("!!")), nil
+	case "?<":
+		return newRune("?:", a[0], newRune("!!"), a[1]), nil
+	case "?!":
+		return newRune("?:", a[0], Literal{Lit: "|"}, Literal{Lit: "&"}), nil
+	case "?|":
+		var n Node = Literal{Lit: "|"}
+		for i := len(a) - 1; i >= 0; i-- {
+			n = newRune("?:", a[i], Literal{Lit: "&"}, n)
+		}
+		return n, nil
+	case "?&":
+		var n Node = Literal{Lit: "&"}
+		for i := len(a) - 1; i >= 0; i-- {
+			n = newRune("?:", a[i], n, Literal{Lit: "|"})
+		}
+		return n, nil
+	case "?@":
+		return newRune("?:", newRune("?=", Pat{}, a[0]), a[1], a[2]), nil
+	case "?^":
+		return newRune("?:", newRune("?=", Ket{}, a[0]), a[1], a[2]), nil
+	case "?~":
+		return newRune("?:", newRune("?=", Literal{Lit: "~"}, a[0]), a[1], a[2]), nil
+	case "?-", "?+":
+		var n Node = newRune("!!")
+		cases := a[1:]
+		if r.Lit == "?+" {
+			n, cases = a[1], a[2:]
+		}
+		for i := len(cases) - 2; i >= 0; i -= 2 {
+			n = newRune("?:", newRune("?=", cases[i], a[0]), cases[i+1], n)
+		}
+		return n, nil
+
+	// bar
+	case "|.":
+		return newRune("|%", newRune("++", Buc{}, a[0])), nil
+	case "|-":
+		return newRune("=<", Buc{}, newRune("|.", a[0])), nil
+	case "|^":
+		arms := append([]Node{newRune("++", Buc{}, a[0])}, a[1:]...)
+		return newRune("=<", Buc{}, newRune("|%", arms...)), nil
+	case "|_":
+		return newRune("=|", a[0], newRune("|%", a[1:]...)), nil
+	case "|=":
+		return newRune("=|", a[0], newRune("|.", a[1])), nil
+	case "|:":
+		return newRune("=+", a[0], newRune("|.", a[1])), nil
+	case "|?":
+		return newRune("^?", newRune("|.", a[0])), nil
+	case "|~":
+		return newRune("^|", newRune("|=", a[0], a[1])), nil
+	case "|*":
+		return newRune("=|", a[0], newRune("|@", newRune("++", Buc{}, a[1]))), nil
+
+	// col
+	case ":-":
+		return Cell{Head: a[0], Tail: a[1]}, nil
+	case ":_":
+		return Cell{Head: a[1], Tail: a[0]}, nil
+	case ":+", ":^", ":*":
+		return tuple(a), nil
+	case ":~":
+		var n Node = Literal{Lit: "~"}
+		for i := len(a) - 1; i >= 0; i-- {
+			n = Cell{Head: a[i], Tail: n}
+		}
+		return n, nil
+
+	// cen
+	case "%~":
+		// push the door, replace its sample, and pull the arm
+		return newRune("=+", a[1],
+			newRune("=>",
+				newRune("%=", AxisWing("2"), AxisWing("6"), newRune("=>", AxisWing("3"), a[2])),
+				a[0])), nil
+	case "%-":
+		switch len(a) {
+		case 1:
+			return newRune("=<", Buc{}, a[0]), nil
+		case 2:
+			return newRune("%~", Buc{}, a[0], a[1]), nil
+		default:
+			return newRune("%-", a[0], tuple(a[1:])), nil
+		}
+	case "%+", "%^", "%:":
+		if len(a) == 1 {
+			return newRune("%-", a[0]), nil
+		}
+		return newRune("%-", a[0], tuple(a[1:])), nil
+	case "%_":
+		return newRune("^+", a[0], newRune("%=", a...)), nil
+	case "%*":
+		// resolve the wing within the new subject, and the values within the
+		// old one
+		w, err := toWing(a[0])
+		if err != nil {
+			return nil, err
+		}
+		w.Limbs = append(append([]Limb(nil), w.Limbs...), Limb{Axis: "2"})
+		args := []Node{w}
+		for i := 2; i < len(a); i += 2 {
+			args = append(args, a[i], newRune("=>", AxisWing("3"), a[i+1]))
+		}
+		return newRune("=+", a[1], newRune("%=", args...)), nil
+
+	// ket
+	case "^-":
+		return newRune("^+", newRune("^*", a[0]), a[1]), nil
+	case "^.":
+		return newRune("^+", newRune("%-", a[0], a[1]), a[1]), nil
+	case "^=":
+		return Tis{Left: a[0], Right: a[1]}, nil
+
+	// mic
+	case ";:":
+		if len(a) == 2 {
+			return a[1], nil
+		}
+		return newRune("%-", a[0], Cell{Head: a[1], Tail: newRune(";:", append([]Node{a[0]}, a[2:]...)...)}), nil
+	case ";~":
+		return openMcsg(a[0], a[1:]), nil
+	case ";<":
+		bind := newRune("%-", a[1], newRune("^:", a[0]))
+		return newRune("%+", bind, a[2], newRune("|=", a[0], a[3])), nil
+	case ";;":
+		return newRune("%-", a[0], a[1]), nil
+
+	// sig
+	case "~|":
+		// as with ~_, the clue is a trap; its subject is the original one
+		// beneath the trap's battery
+		msg := newRune("%-", Face{Name: "cain"}, newRune("!>", newRune("=>", AxisWing("3"), a[0])))
+		return newRune("~>", hint("%mean", newRune("|.", msg)), a[1]), nil
+	case "~_":
+		return newRune("~>", hint("%mean", newRune("|.", a[0])), a[1]), nil
+	case "~$":
+		return newRune("~>", hint("%live", a[0]), a[1]), nil
+	case "~%":
+		return newRune("~>", hint("%fast", tuple([]Node{a[0], newRune("!=", a[1]), a[2]})), a[3]), nil
+	case "~<":
+		return newRune("=>", a[1], newRune("~>", a[0], Wing{})), nil
+	case "~+":
+		return newRune("~>", Literal{Lit: "%memo"}, a[0]), nil
+	case "~/":
+		return newRune("~%", a[0], AxisWing("7"), Literal{Lit: "~"}, a[1]), nil
+	case "~&":
+		msg := newRune("%-", Face{Name: "cain"}, newRune("!>", a[0]))
+		return newRune("~>", hint("%slog", Cell{Head: Num{Int: "0"}, Tail: msg}), a[1]), nil
+	case "~?":
+		return newRune("?:", a[0], newRune("~&", a[1], a[2]), a[2]), nil
+	case "~=":
+		return newRune("~>", hint("%same", a[0]), a[1]), nil
+
+	default:
+		return nil, fmt.Errorf("unhandled reduction %v", r.Lit)
+	}
+}
+
+// openMcsg reduces ;~(p a b ...), which composes the gates a, b, ... using
+// the gate p.
+func openMcsg(p Node, gates []Node) Node {
+	// =>  v=.  ...
+	v := Face{Name: "v"}
+	var compose func(gates []Node) Node
+	compose = func(gates []Node) Node {
+		if len(gates) == 1 {
+			return newRune("=>", v, gates[0])
+		}
+		// =+  a=$(gates t.gates)
+		// =+  b==>(v i.gates)
+		// =+  c=,.+6:b
+		// |.  %+  =>(v p)  (b c)  a(,.+6 c)
+		sample := Wing{Limbs: []Limb{{Lit: ","}, {Axis: "6"}}}
+		return newRune("=+", Tis{Left: Face{Name: "a"}, Right: compose(gates[1:])},
+			newRune("=+", Tis{Left: Face{Name: "b"}, Right: newRune("=>", v, gates[0])},
+				newRune("=+", Tis{Left: Face{Name: "c"}, Right: newRune("=<", sample, Face{Name: "b"})},
+					newRune("|.",
+						newRune("%+",
+							newRune("=>", v, p),
+							newRune("%-", Face{Name: "b"}, Face{Name: "c"}),
+							newRune("%=", Face{Name: "a"}, sample, Face{Name: "c"}))))))
+	}
+	return newRune("=>", Tis{Left: v, Right: Wing{}}, compose(gates))
+}
+
+// openPath reduces a path to a list of its segments, e.g. /a/(b c) is
+// [%a [(b c) ~]].
+func openPath(p Path) Node {
+	var n Node = Literal{Lit: "~"}
+	for i := len(p.Segs) - 1; i >= 0; i-- {
+		seg := p.Segs[i]
+		if l, ok := seg.(Literal); ok && l.Tok == token.Face {
+			seg = Literal{Lit: "%" + l.Lit}
+		}
+		n = Cell{Head: seg, Tail: n}
+	}
+	return n
+}
+
+func newRune(lit string, args ...Node) Rune {
+	return Rune{Lit: lit, Args: args}
+}
+
+// tuple returns the right-nested cell of ns, e.g. [a [b c]].
+func tuple(ns []Node) Node {
+	if len(ns) == 1 {
+		return ns[0]
+	}
+	return Cell{Head: ns[0], Tail: tuple(ns[1:])}
+}
+
+// hint returns the dynamic hint [term value], e.g. %mean.value.
+func hint(term string, value Node) Node {
+	return Cell{Head: Literal{Lit: term}, Tail: value}
+}
+
+// toWing returns n as a Wing, if it is one.
+func toWing(n Node) (Wing, error) {
+	switch n := n.(type) {
+	case Wing:
+		return n, nil
+	case Face:
+		return Wing{Limbs: []Limb{{Name: n.Name}}}, nil
+	case Buc:
+		return Wing{Limbs: []Limb{{Name: "$"}}}, nil
+	default:
+		return Wing{}, fmt.Errorf("expected wing, got %T", n)
+	}
+}
@@ -0,0 +1,123 @@
+package ast_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+)
+
+func TestReduce(t *testing.T) {
+	var tests = []struct {
+		prog string
+		exp  string
+	}{
+		{"=<  a  b", "=>(b a)"},
+		{"=-  a  b", "=>([b .] a)"},
+		{"?.  a  b  c", "?:(a c b)"},
+		{"%.  a  b", "=>([b .] =>(%=(+2 +6 =>(+3 a)) $))"},
+		{"=/  a  1  a", "=>([a=1 .] a)"},
+		{"=/  a=@  1  a", "=>([a=^+(^*(@) 1) .] a)"},
+		{"=~  a\n  b\n  c\n==", "=>(a =>(b c))"},
+		{"?>  a  b", "?:(a b !!)"},
+		{"?|  a\n  b\n==", "?:(a & ?:(b & |))"},
+		{"?&  a\n  b\n==", "?:(a ?:(b & |) |)"},
+		{"?~  a  b  c", "?:(?=(~ a) b c)"},
+		{"?-  a\n  %b  1\n  %c  2\n==", "?:(?=(%b a) 1 ?:(?=(%c a) 2 !!))"},
+		{"?+  a  0\n  %b  1\n==", "?:(?=(%b a) 1 0)"},
+		{":~  a\n  b\n==", "[a [b ~]]"},
+		{":*  a\n  b\n  c\n==", "[a [b c]]"},
+		{":_  a  b", "[b a]"},
+		{"(a)", "=>(a $)"},
+		{"%*  a  b\n  c  1\n==", "=>([b .] %=(a.+2 c =>(+3 1)))"},
+		{"|=  a=@  a", "=>  [^*(a=@) .]  |%  ++  $  a  --"},
+		{"|-  a", "=>  |%  ++  $  a  --  $"},
+		{"|^  a\n++  b  1\n--", "=>  |%  ++  $  a  ++  b  1  --  $"},
+		{"=:  a  1\n  b  2\n==\n[a b]", "=>(^+(. %=(. a 1, b 2)) [a b])"},
+		{"[/a/b a.+6 a==>(b c)]", "[[%a [%b ~]] [a.+6 a==>(b c)]]"},
+		{"~+  a", "~>(%memo a)"},
+		{"~_  a  b", "~>  :-  %mean  |%  ++  $  a  --  b"},
+		{"~|  a  b", "~>  :-  %mean  |%  ++  $  =>([cain .] =>(%=(+2 +6 =>(+3 !>(=>(+3 a)))) $))  --  b"},
+		{"~&  a  b", "~>([%slog [0 =>([cain .] =>(%=(+2 +6 =>(+3 !>(a))) $))]] b)"},
+		{"!!", "!!"},
+	}
+	for _, test := range tests {
+		n, err := ast.Desugar(parser.New(scanner.New([]byte(test.prog))).Parse())
+		if err != nil {
+			t.Errorf("%q: %v", test.prog, err)
+			continue
+		}
+		var sb strings.Builder
+		ast.Print(&sb, n)
+		if got := sb.String(); got != test.exp {
+			t.Errorf("%q: expected %v, got %v", test.prog, test.exp, got)
+		}
+	}
+
+	// the reduced form of every file should print, and parse to a tree with
+	// the same reduced form; sail, which Print does not render as valid
+	// source, is skipped
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*", "*.hoon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.New(scanner.New(src)).ParseFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sail := false
+		ast.Inspect(f, func(n ast.Node) bool {
+			_, ok := n.(ast.Sail)
+			sail = sail || ok
+			return !sail
+		})
+		if sail {
+			continue
+		}
+		body, err := ast.Desugar(f.Body)
+		if err != nil {
+			t.Errorf("%v: %v", file, err)
+			continue
+		}
+		var sb strings.Builder
+		ast.Print(&sb, body)
+		reduced := sb.String()
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%v: reduced form does not parse: %v\n%v", file, r, reduced)
+				}
+			}()
+			again, err := ast.Desugar(parser.New(scanner.New([]byte(reduced))).Parse())
+			if err != nil {
+				t.Errorf("%v: %v", file, err)
+				return
+			}
+			sb.Reset()
+			ast.Print(&sb, again)
+			if sb.String() != reduced {
+				t.Errorf("%v: reduced form does not round-trip:\n%v\n%v", file, reduced, sb.String())
+			}
+		}()
+	}
+
+	for _, r := range []ast.Rune{
+		{Lit: "?.", Args: []ast.Node{ast.Buc{}}},
+		{Lit: "?-", Args: []ast.Node{ast.Buc{}, ast.Buc{}}},
+		{Lit: "%*", Args: []ast.Node{ast.Num{Int: "1"}, ast.Buc{}}},
+		{Lit: "&&"},
+	} {
+		if _, err := ast.Desugar(ast.Rune{Lit: "=>", Args: []ast.Node{ast.Buc{}, r}}); err == nil {
+			t.Errorf("%v: expected error", r.Lit)
+		}
+	}
+}
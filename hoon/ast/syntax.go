@@ -0,0 +1,189 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+)
+
+// Syntax is the concrete syntax of a node: its location in the source, and the
+// exact source text surrounding each of its children, including whitespace and
+// comments. Text[i] precedes the ith child, and the final element follows the
+// last child, so a node with n children has n+1 elements; a leaf node's text is
+// simply its source. Nodes produced by the parser in concrete mode have
+// Syntax; nodes created by other means do not.
+type Syntax struct {
+	Pos  int // byte offset of the start of the node
+	End  int // byte offset immediately after the node
+	Text []string
+}
+
+// Children returns the child nodes of n, in source order.
+func Children(n Node) []Node {
+	switch n := n.(type) {
+	case Tis:
+		return []Node{n.Left, n.Right}
+	case Rune:
+		return n.Args
+	case Cell:
+		return []Node{n.Head, n.Tail}
+	case Path:
+		return n.Segs
+	case Sail:
+		kids := make([]Node, 0, len(n.Attrs)+len(n.Kids))
+		for _, a := range n.Attrs {
+			kids = append(kids, a.Value)
+		}
+		return append(kids, n.Kids...)
+	case File:
+		return []Node{n.Body}
+	case Buc, Pat, Tar, Ket, Wut, Face, Wing, Num, Literal:
+		return nil
+	default:
+		panic(fmt.Sprintf("unknown node type %T", n))
+	}
+}
+
+// withChildren returns a copy of n with its children, in the order returned
+// by Children, replaced by kids.
+func withChildren(n Node, kids []Node) Node {
+	switch n := n.(type) {
+	case Tis:
+		n.Left, n.Right = kids[0], kids[1]
+		return n
+	case Rune:
+		n.Args = kids
+		return n
+	case Cell:
+		n.Head, n.Tail = kids[0], kids[1]
+		return n
+	case Path:
+		n.Segs = kids
+		return n
+	case Sail:
+		attrs := make([]SailAttr, len(n.Attrs))
+		for i, a := range n.Attrs {
+			attrs[i] = SailAttr{Key: a.Key, Value: kids[i]}
+		}
+		n.Attrs, n.Kids = attrs, kids[len(attrs):]
+		return n
+	case File:
+		n.Body = kids[0]
+		return n
+	default:
+		return n
+	}
+}
+
+// SyntaxOf returns the concrete syntax of n, or nil if it has none.
+func SyntaxOf(n Node) *Syntax {
+	switch n := n.(type) {
+	case Buc:
+		return n.Syntax
+	case Pat:
+		return n.Syntax
+	case Tar:
+		return n.Syntax
+	case Ket:
+		return n.Syntax
+	case Wut:
+		return n.Syntax
+	case Face:
+		return n.Syntax
+	case Wing:
+		return n.Syntax
+	case Tis:
+		return n.Syntax
+	case Num:
+		return n.Syntax
+	case Literal:
+		return n.Syntax
+	case Path:
+		return n.Syntax
+	case Sail:
+		return n.Syntax
+	case File:
+		return n.Syntax
+	case Rune:
+		return n.Syntax
+	case Cell:
+		return n.Syntax
+	default:
+		panic(fmt.Sprintf("unknown node type %T", n))
+	}
+}
+
+// WithSyntax returns a copy of n with its concrete syntax set to s.
+func WithSyntax(n Node, s *Syntax) Node {
+	switch n := n.(type) {
+	case Buc:
+		n.Syntax = s
+		return n
+	case Pat:
+		n.Syntax = s
+		return n
+	case Tar:
+		n.Syntax = s
+		return n
+	case Ket:
+		n.Syntax = s
+		return n
+	case Wut:
+		n.Syntax = s
+		return n
+	case Face:
+		n.Syntax = s
+		return n
+	case Wing:
+		n.Syntax = s
+		return n
+	case Tis:
+		n.Syntax = s
+		return n
+	case Num:
+		n.Syntax = s
+		return n
+	case Literal:
+		n.Syntax = s
+		return n
+	case Path:
+		n.Syntax = s
+		return n
+	case Sail:
+		n.Syntax = s
+		return n
+	case File:
+		n.Syntax = s
+		return n
+	case Rune:
+		n.Syntax = s
+		return n
+	case Cell:
+		n.Syntax = s
+		return n
+	default:
+		panic(fmt.Sprintf("unknown node type %T", n))
+	}
+}
+
+// PrintConcrete writes n to w using its concrete syntax, reproducing the
+// original source exactly. Nodes without concrete syntax, or whose children
+// no longer match it (e.g. after a rewrite added an argument), are printed
+// in canonical form, as by Print; their children are still printed
+// concretely, so a partially rewritten tree keeps the formatting and comments
+// of its untouched parts.
+func PrintConcrete(w io.Writer, n Node) (err error) {
+	s := SyntaxOf(n)
+	kids := Children(n)
+	if s == nil || len(s.Text) != len(kids)+1 {
+		return fprint(w, n, true)
+	}
+	for i, k := range kids {
+		if _, err = io.WriteString(w, s.Text[i]); err != nil {
+			return err
+		} else if err = PrintConcrete(w, k); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, s.Text[len(kids)])
+	return err
+}
@@ -0,0 +1,180 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the children of node, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, k := range Children(node) {
+		Walk(v, k)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the children of node, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite returns a copy of n in which each node has been replaced by the
+// result of calling f on it. Nodes are rewritten top-down: f is called on n,
+// and then Rewrite recurses into the children of the node f returned, so a
+// replacement is itself rewritten. f must not return nil. The input tree is
+// not modified.
+func Rewrite(n Node, f func(Node) Node) Node {
+	n = f(n)
+	kids := Children(n)
+	if len(kids) == 0 {
+		return n
+	}
+	rewritten := make([]Node, len(kids))
+	for i, k := range kids {
+		rewritten[i] = Rewrite(k, f)
+	}
+	return withChildren(n, rewritten)
+}
+
+// Equal reports whether a and b are structurally identical. Token kinds and
+// concrete syntax are ignored, so a parsed tree is equal to its canonical
+// reprinting, reparsed.
+func Equal(a, b Node) bool {
+	switch a := a.(type) {
+	case Buc, Tar, Ket, Wut:
+		return reflect.TypeOf(a) == reflect.TypeOf(b)
+	case Pat:
+		b, ok := b.(Pat)
+		return ok && a.Aura == b.Aura
+	case Face:
+		b, ok := b.(Face)
+		return ok && a.Name == b.Name
+	case Wing:
+		b, ok := b.(Wing)
+		if !ok || len(a.Limbs) != len(b.Limbs) {
+			return false
+		}
+		for i := range a.Limbs {
+			// ignore the original syntax of axis limbs, e.g. +< vs. +6
+			x, y := a.Limbs[i], b.Limbs[i]
+			x.Lit, y.Lit = "", ""
+			if x != y {
+				return false
+			}
+		}
+		return true
+	case Num:
+		b, ok := b.(Num)
+		return ok && a.Int == b.Int
+	case Literal:
+		b, ok := b.(Literal)
+		return ok && a.Lit == b.Lit
+	case Sail:
+		b, ok := b.(Sail)
+		if !ok || a.Tag != b.Tag || a.Text != b.Text || a.Void != b.Void || len(a.Attrs) != len(b.Attrs) {
+			return false
+		}
+		for i := range a.Attrs {
+			if a.Attrs[i].Key != b.Attrs[i].Key {
+				return false
+			}
+		}
+	case File:
+		b, ok := b.(File)
+		if !ok || len(a.Ford) != len(b.Ford) {
+			return false
+		}
+		for i := range a.Ford {
+			if !equalFord(a.Ford[i], b.Ford[i]) {
+				return false
+			}
+		}
+	case Rune:
+		if b, ok := b.(Rune); !ok || a.Lit != b.Lit {
+			return false
+		}
+	case Tis, Cell, Path:
+		if reflect.TypeOf(a) != reflect.TypeOf(b) {
+			return false
+		}
+	default:
+		panic(fmt.Sprintf("unknown node type %T", a))
+	}
+	ak, bk := Children(a), Children(b)
+	if len(ak) != len(bk) {
+		return false
+	}
+	for i := range ak {
+		if !Equal(ak[i], bk[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalFord(a, b Ford) bool {
+	if a.Lit != b.Lit || len(a.Args) != len(b.Args) {
+		return false
+	}
+	for i := range a.Args {
+		if a.Args[i] != b.Args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy returns a deep copy of n, sharing no slices or concrete syntax with
+// the original.
+func Copy(n Node) Node {
+	return Rewrite(n, func(n Node) Node {
+		switch c := n.(type) {
+		case Wing:
+			c.Limbs = append([]Limb(nil), c.Limbs...)
+			n = c
+		case File:
+			fords := make([]Ford, len(c.Ford))
+			for i, f := range c.Ford {
+				f.Args = append([]string(nil), f.Args...)
+				fords[i] = f
+			}
+			c.Ford = fords
+			n = c
+		}
+		return WithSyntax(n, copySyntax(SyntaxOf(n)))
+	})
+}
+
+func copySyntax(s *Syntax) *Syntax {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Text = append([]string(nil), s.Text...)
+	return &c
+}
@@ -0,0 +1,80 @@
+package ast_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+)
+
+func TestWalk(t *testing.T) {
+	parse := func(s string) ast.Node {
+		return parser.New(scanner.New([]byte(s))).Parse()
+	}
+	n := parse("=/  a  [1 b.c %d]  (add a +<)")
+
+	var faces []string
+	ast.Inspect(n, func(n ast.Node) bool {
+		if f, ok := n.(ast.Face); ok {
+			faces = append(faces, f.Name)
+		}
+		return true
+	})
+	if strings.Join(faces, " ") != "a add a" {
+		t.Errorf("wrong faces: %v", faces)
+	}
+
+	// only the outermost cell should be visited
+	var cells int
+	ast.Inspect(n, func(n ast.Node) bool {
+		_, ok := n.(ast.Cell)
+		if ok {
+			cells++
+		}
+		return !ok
+	})
+	if cells != 1 {
+		t.Errorf("expected 1 cell, got %v", cells)
+	}
+
+	r := ast.Rewrite(n, func(n ast.Node) ast.Node {
+		if f, ok := n.(ast.Face); ok && f.Name == "a" {
+			return ast.Face{Name: "x"}
+		}
+		return n
+	})
+	if !ast.Equal(r, parse("=/(x [1 b.c %d] (add x +6))")) {
+		t.Error("rewrite produced wrong tree")
+	} else if ast.Equal(r, n) {
+		t.Error("rewrite modified original")
+	} else if !ast.Equal(n, parse("=/(a [1 b.c %d] (add a +<))")) {
+		t.Error("wide and tall forms should be equal")
+	}
+
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*", "*.hoon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.NewConcrete(scanner.New(src)).ParseFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := ast.Copy(f)
+		if !ast.Equal(c, f) {
+			t.Errorf("%v: copy should equal original", file)
+		}
+		ast.SyntaxOf(c).Text[0] = "changed"
+		if ast.SyntaxOf(f).Text[0] == "changed" {
+			t.Errorf("%v: copy shares syntax with original", file)
+		}
+	}
+}
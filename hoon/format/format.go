@@ -0,0 +1,534 @@
+// Package format implements canonical formatting of Hoon source.
+//
+// Formatted source follows the Hoon style guide: tall runes are separated
+// from their arguments by two-space gaps, arguments are indented by
+// backstepping, so that the last argument of a rune is aligned with the rune
+// itself, and the == and -- terminators of jogs and cores are aligned with
+// the runes that open them. The pairs of a jog, such as the cases of a ?-,
+// are printed one per line if they fit; otherwise, the head of each pair is
+// indented four columns, and its value is backstepped to two. Expressions
+// that are not the last argument of a rune are collapsed to wide form when
+// they fit within 80 columns. Comments are preserved, as are single blank
+// lines.
+package format
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+	"lukechampine.com/urbit/hoon/token"
+)
+
+// maxWidth is the column limit for collapsing expressions to wide form.
+const maxWidth = 80
+
+// Source formats the Hoon source file src. Formatting is idempotent:
+// formatting the output of Source again returns it unchanged.
+func Source(src []byte) ([]byte, error) {
+	f, err := parser.NewConcrete(scanner.New(src)).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	p := &printer{src: src}
+	p.collectComments(f)
+	p.file(f)
+	return p.buf.Bytes(), nil
+}
+
+type comment struct {
+	pos, end int
+	text     string
+}
+
+type printer struct {
+	src      []byte
+	buf      bytes.Buffer
+	col      int       // current output column
+	last     int       // source offset after the last node or comment printed
+	comments []comment // comments not yet printed, in source order
+}
+
+// collectComments records the comments in the concrete syntax of n.
+func (p *printer) collectComments(n ast.Node) {
+	s := ast.SyntaxOf(n)
+	kids := ast.Children(n)
+	if sail, ok := n.(ast.Sail); (ok && sail.Text != "") || len(kids) == 0 {
+		return // raw text
+	}
+	for i, text := range s.Text {
+		off := s.Pos
+		if i > 0 {
+			off = ast.SyntaxOf(kids[i-1]).End
+		}
+		if _, ok := n.(ast.File); ok && i == 0 {
+			off += len(text) // the header is printed verbatim
+		} else {
+			for sc := scanner.New([]byte(text)); ; {
+				t, lit := sc.Scan()
+				if t == token.EOF || t == token.ILLEGAL {
+					break
+				} else if t == token.Comment {
+					p.comments = append(p.comments, comment{off, off + len(lit), strings.TrimRight(lit, " \t\r")})
+				}
+				off += len(lit)
+			}
+		}
+		if i < len(kids) {
+			p.collectComments(kids[i])
+		}
+	}
+}
+
+func (p *printer) write(s string) {
+	p.buf.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.col = utf8.RuneCountInString(s[i+1:])
+	} else {
+		p.col += utf8.RuneCountInString(s)
+	}
+}
+
+// printed records that the source up to end has been printed.
+func (p *printer) printed(end int) {
+	if end > p.last {
+		p.last = end
+	}
+}
+
+// hasComments reports whether any unprinted comment lies within [start, end).
+func (p *printer) hasComments(start, end int) bool {
+	for _, c := range p.comments {
+		if start <= c.pos && c.pos < end {
+			return true
+		}
+	}
+	return false
+}
+
+// dropComments discards the unprinted comments before end, which have been
+// printed verbatim.
+func (p *printer) dropComments(end int) {
+	for len(p.comments) > 0 && p.comments[0].pos < end {
+		p.comments = p.comments[1:]
+	}
+}
+
+var blankLine = regexp.MustCompile(`\n[ \t]*\n`)
+
+// blankBetween reports whether the source contains a blank line between
+// offsets start and end.
+func (p *printer) blankBetween(start, end int) bool {
+	return start < end && end <= len(p.src) && blankLine.Match(p.src[start:end])
+}
+
+// newline begins a new line at column ind, on which the source at offset
+// next will be printed. A comment on the same source line as the code before
+// it stays at the end of the line; other comments before next are printed on
+// lines of their own.
+func (p *printer) newline(ind, next int) {
+	p.newlineComments(ind, ind, next)
+}
+
+// newlineComments is like newline, but prints comments on lines of their own
+// at column cind.
+func (p *printer) newlineComments(cind, ind, next int) {
+	if len(p.comments) > 0 {
+		c := p.comments[0]
+		if c.pos < next && (c.pos < p.last || !bytes.ContainsRune(p.src[p.last:c.pos], '\n')) {
+			p.write("  " + c.text)
+			p.printed(c.end)
+			p.comments = p.comments[1:]
+		}
+	}
+	p.write("\n")
+	for len(p.comments) > 0 && p.comments[0].pos < next {
+		c := p.comments[0]
+		if p.blankBetween(p.last, c.pos) {
+			p.write("\n")
+		}
+		p.write(strings.Repeat(" ", cind) + c.text + "\n")
+		p.printed(c.end)
+		p.comments = p.comments[1:]
+	}
+	if next < len(p.src) && p.blankBetween(p.last, next) {
+		p.write("\n")
+	}
+	p.write(strings.Repeat(" ", ind))
+}
+
+func (p *printer) file(f ast.File) {
+	s := ast.SyntaxOf(f.Body)
+	p.header(string(p.src[:s.Pos]))
+	p.dropComments(s.Pos)
+	p.last = s.Pos
+	if r, ok := f.Body.(ast.Rune); ok && !p.startsWith(r, r.Lit) {
+		// multiple top-level expressions
+		for i, arg := range r.Args {
+			if i > 0 {
+				p.newline(0, pos(arg))
+			}
+			p.node(arg, true)
+		}
+	} else {
+		p.node(f.Body, true)
+	}
+	p.newline(0, len(p.src))
+}
+
+// header prints the Ford directives and comments preceding the body of a
+// file, removing trailing whitespace and redundant blank lines.
+func (p *printer) header(text string) {
+	blank := false
+	lines := strings.Split(text, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = p.buf.Len() > 0
+			continue
+		} else if blank {
+			p.write("\n")
+		}
+		p.write(line + "\n")
+		blank = false
+	}
+	if blank {
+		p.write("\n")
+	}
+}
+
+// node prints n at the current column. If keepTall is set, n is printed in
+// tall form if it was written in tall form, even if it would fit in wide
+// form.
+func (p *printer) node(n ast.Node, keepTall bool) {
+	if w, ok := p.wide(n); ok && (!keepTall || wideSource(n)) && p.fits(w) {
+		p.write(w)
+		p.printed(end(n))
+		return
+	}
+	if r, ok := n.(ast.Rune); ok && p.tall(r) {
+		return
+	}
+	p.verbatim(n)
+}
+
+// verbatim prints n exactly as it was written.
+func (p *printer) verbatim(n ast.Node) {
+	s := ast.SyntaxOf(n)
+	p.write(string(p.src[s.Pos:s.End]))
+	p.dropComments(s.End)
+	p.printed(s.End)
+}
+
+func (p *printer) fits(w string) bool {
+	return !strings.Contains(w, "\n") && p.col+utf8.RuneCountInString(w) <= maxWidth
+}
+
+// wide returns the wide form of n, if it has one. Parts of n that were
+// written in wide form are printed as written, preserving irregular forms.
+func (p *printer) wide(n ast.Node) (string, bool) {
+	if !wideable(n) || p.hasComments(pos(n), end(n)) {
+		return "", false
+	}
+	var sb strings.Builder
+	ast.PrintConcrete(&sb, flatten(n))
+	return sb.String(), true
+}
+
+// wideable reports whether n can be written in wide form.
+func wideable(n ast.Node) bool {
+	switch n := n.(type) {
+	case ast.Literal:
+		return !strings.Contains(n.Lit, "\n") // e.g. a block cord
+	case ast.Sail, ast.File:
+		return false
+	case ast.Rune:
+		if e, ok := parser.LookupRune(n.Lit); ok && (e.Arms || e.Arm || e.Post > 0) {
+			return false
+		}
+	}
+	for _, k := range ast.Children(n) {
+		if !wideable(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// wideSource reports whether n was written in wide form.
+func wideSource(n ast.Node) bool {
+	s := ast.SyntaxOf(n)
+	if s == nil {
+		return false
+	}
+	kids := ast.Children(n)
+	if len(kids) == 0 {
+		return !strings.Contains(s.Text[0], "\n")
+	}
+	for _, text := range s.Text {
+		if strings.Contains(text, "\n") || strings.Contains(text, "  ") || strings.Contains(text, "::") {
+			return false
+		}
+	}
+	for _, k := range kids {
+		if !wideSource(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// flatten returns n with the concrete syntax of every node not written in
+// wide form removed, so that it is printed in canonical wide form.
+func flatten(n ast.Node) ast.Node {
+	if wideSource(n) {
+		return n
+	}
+	switch m := n.(type) {
+	case ast.Tis:
+		m.Left, m.Right = flatten(m.Left), flatten(m.Right)
+		n = m
+	case ast.Cell:
+		m.Head, m.Tail = flatten(m.Head), flatten(m.Tail)
+		n = m
+	case ast.Rune:
+		args := make([]ast.Node, len(m.Args))
+		for i := range args {
+			args[i] = flatten(m.Args[i])
+		}
+		m.Args = args
+		n = m
+	case ast.Path:
+		segs := make([]ast.Node, len(m.Segs))
+		for i := range segs {
+			segs[i] = flatten(m.Segs[i])
+		}
+		m.Segs = segs
+		n = m
+	}
+	return ast.WithSyntax(n, nil)
+}
+
+// startsWith reports whether the source of n begins with prefix.
+func (p *printer) startsWith(n ast.Node, prefix string) bool {
+	s := ast.SyntaxOf(n)
+	return s != nil && bytes.HasPrefix(p.src[s.Pos:s.End], []byte(prefix))
+}
+
+// tall prints r in tall form, reporting whether it was able to.
+func (p *printer) tall(r ast.Rune) bool {
+	e, ok := parser.LookupRune(r.Lit)
+	if !ok || e.Arm || !p.startsWith(r, r.Lit) {
+		return false // an irregular form, e.g. (a b)
+	}
+	jog := len(r.Args) - e.Args - e.Post
+	switch {
+	case jog < 0,
+		!e.Jogging && !e.Arms && jog != 0,
+		e.Pairs && jog%2 != 0:
+		return false
+	case e.Arms:
+		for _, arm := range r.Args[e.Args:] {
+			if a, ok := arm.(ast.Rune); !ok || !p.startsWith(a, a.Lit) {
+				return false
+			}
+		}
+	}
+
+	c := p.col
+	p.write(r.Lit)
+	switch {
+	case e.Arms:
+		p.fixed(r.Args[:e.Args], c)
+		for _, arm := range r.Args[e.Args:] {
+			p.newline(c, pos(arm))
+			p.arm(arm.(ast.Rune), c)
+		}
+		p.terminate(c, end(r)-2, "--")
+	case e.Jogging:
+		args := r.Args[e.Args : e.Args+jog]
+		if e.Pairs && !p.shortPairs(args, c+2) {
+			// ?-    a
+			//     %b
+			//   1
+			// ==
+			p.write("  ")
+			p.fixed(r.Args[:e.Args], c)
+			for i := 0; i < len(args); i += 2 {
+				p.newlineComments(c, c+4, pos(args[i]))
+				p.node(args[i], false)
+				p.lastArg(args[i+1], c+2, true)
+			}
+		} else if p.fixed(r.Args[:e.Args], c); e.Pairs {
+			// ?-  a
+			//   %b  1
+			// ==
+			for i := 0; i < len(args); i += 2 {
+				p.newline(c+2, pos(args[i]))
+				p.node(args[i], false)
+				p.lastArg(args[i+1], c+4, true)
+			}
+		} else if e.Args == 0 {
+			// :~  a
+			//     b
+			// ==
+			for i, arg := range args {
+				if i == 0 {
+					p.write("  ")
+				} else {
+					p.newline(c+4, pos(arg))
+				}
+				p.node(arg, false)
+			}
+		} else {
+			for _, arg := range args {
+				p.newline(c+2, pos(arg))
+				p.node(arg, false)
+			}
+		}
+		if e.Post == 0 {
+			p.terminate(c, end(r)-2, "==")
+			break
+		}
+		post := r.Args[len(r.Args)-e.Post:]
+		sep := ast.SyntaxOf(r).Text[len(r.Args)-e.Post]
+		p.terminate(c, pos(post[0])-len(sep)+strings.LastIndex(sep, "=="), "==")
+		for _, arg := range post {
+			p.newline(c, pos(arg))
+			p.node(arg, true)
+		}
+	default:
+		p.backstep(r.Args, c, c, len(r.Args) == 1)
+	}
+	p.printed(end(r))
+	return true
+}
+
+// arm prints an arm of a core at column c.
+func (p *printer) arm(a ast.Rune, c int) {
+	p.write(a.Lit)
+	if e, _ := parser.LookupRune(a.Lit); e.Pairs {
+		// +*  this  .
+		//     def   ~(. (default-agent this %|) bowl)
+		for i := 0; i+1 < len(a.Args); i += 2 {
+			if i == 0 {
+				p.write("  ")
+			} else {
+				p.newline(c+4, pos(a.Args[i]))
+			}
+			p.node(a.Args[i], false)
+			p.lastArg(a.Args[i+1], c+6, true)
+		}
+	} else {
+		p.backstep(a.Args, c, c+2, true)
+	}
+	p.printed(end(a))
+}
+
+// terminate prints the terminator of a jog or core, located at offset at in
+// the source, on a new line at column c.
+func (p *printer) terminate(c, at int, term string) {
+	p.newline(c, at)
+	p.write(term)
+	p.printed(at + len(term))
+}
+
+// fixed prints the fixed arguments of a jogging rune or core, which follow
+// the rune at column c.
+func (p *printer) fixed(args []ast.Node, c int) {
+	if ws, ok := p.packed(args); ok {
+		for i, w := range ws {
+			p.write("  " + w)
+			p.printed(end(args[i]))
+		}
+		return
+	}
+	for i, arg := range args {
+		if i == 0 {
+			p.write("  ")
+		} else {
+			p.newline(c+4, pos(arg))
+		}
+		p.node(arg, false)
+	}
+}
+
+// shortPairs reports whether each pair of a jog can be printed on a single
+// line at column c, in which case the jog is printed with its pairs indented
+// two columns past the rune. Otherwise, it is printed as in the style guide,
+// with the rune followed by a four-space gap, each pair's head indented four
+// columns, and its value backstepped to two.
+func (p *printer) shortPairs(args []ast.Node, c int) bool {
+	for i := 0; i+1 < len(args); i += 2 {
+		w0, ok0 := p.wide(args[i])
+		w1, ok1 := p.wide(args[i+1])
+		if !ok0 || !ok1 || !wideSource(args[i+1]) || p.hasComments(end(args[i]), pos(args[i+1])) ||
+			c+utf8.RuneCountInString(w0)+2+utf8.RuneCountInString(w1) > maxWidth {
+			return false
+		}
+	}
+	return true
+}
+
+// backstep prints the arguments of a rune at column c. Each argument is
+// indented two columns less than the one before it; the last is printed at
+// column ind. If possible, all but the last argument are printed on the same
+// line as the rune, as is the last if sameLine is set.
+func (p *printer) backstep(args []ast.Node, c, ind int, sameLine bool) {
+	n := len(args)
+	if n == 0 {
+		return // e.g. !!
+	} else if ws, ok := p.packed(args[:n-1]); ok {
+		for i, w := range ws {
+			p.write("  " + w)
+			p.printed(end(args[i]))
+		}
+		p.lastArg(args[n-1], ind, sameLine)
+		return
+	}
+	p.write("  ")
+	p.node(args[0], false)
+	for i := 1; i < n-1; i++ {
+		p.newline(c+2*(n-1-i), pos(args[i]))
+		p.node(args[i], false)
+	}
+	p.newline(ind, pos(args[n-1]))
+	p.node(args[n-1], true)
+}
+
+// lastArg prints the last argument of a rune, either on the current line, if
+// sameLine is set and it was written in wide form and fits, or on a new line
+// at column ind.
+func (p *printer) lastArg(n ast.Node, ind int, sameLine bool) {
+	if w, ok := p.wide(n); ok && sameLine && wideSource(n) && !p.hasComments(p.last, pos(n)) {
+		if p.col+2+utf8.RuneCountInString(w) <= maxWidth {
+			p.write("  " + w)
+			p.printed(end(n))
+			return
+		}
+	}
+	p.newline(ind, pos(n))
+	p.node(n, true)
+}
+
+// packed returns the wide forms of args, if they all fit on the current line.
+func (p *printer) packed(args []ast.Node) ([]string, bool) {
+	ws := make([]string, len(args))
+	col := p.col
+	for i, arg := range args {
+		w, ok := p.wide(arg)
+		if !ok || (i > 0 && p.hasComments(end(args[i-1]), pos(arg))) {
+			return nil, false
+		}
+		col += 2 + utf8.RuneCountInString(w)
+		ws[i] = w
+	}
+	return ws, col <= maxWidth
+}
+
+func pos(n ast.Node) int { return ast.SyntaxOf(n).Pos }
+func end(n ast.Node) int { return ast.SyntaxOf(n).End }
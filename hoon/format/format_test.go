@@ -0,0 +1,119 @@
+package format
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		src string
+		exp string
+	}{
+		{
+			src: "=/    n   1\n      n",
+			exp: "=/  n  1\nn\n",
+		},
+		{
+			src: "=/  x\n  %+  add\n    1\n  2\nx",
+			exp: "=/  x  %+(add 1 2)\nx\n",
+		},
+		{
+			src: "?:(=(a b) %loooooooooooooooooooooooooong-term %another-loooooooooooooooooooooong-term)",
+			exp: "?:  =(a b)  %loooooooooooooooooooooooooong-term\n%another-loooooooooooooooooooooong-term\n",
+		},
+		{
+			src: "?:  ?|  a\n  b  ==\n  1\n2",
+			exp: "?:  ?|(a b)  1\n2\n",
+		},
+		{
+			src: "=/  n  1  ::  one\n\n\n::  two\nn  :: end\n:: trailing\n",
+			exp: "=/  n  1  ::  one\n\n::  two\nn  :: end\n:: trailing\n",
+		},
+		{
+			src: "%=    $\n  a  ::  c\n    1\n  b   ~[1 2]\n    ==",
+			exp: "%=    $\n    a  ::  c\n  1\n    b  ~[1 2]\n==\n",
+		},
+		{
+			src: "?-  a\n    %b    1\n  %c  2\n==",
+			exp: "?-  a\n  %b  1\n  %c  2\n==\n",
+		},
+		{
+			src: ":~  1\n  2  3\n==",
+			exp: ":~  1\n    2\n    3\n==\n",
+		},
+		{
+			src: "/+  default-agent  \n\n\n|%\n++  foo  1\n  ++  bar\n  |=  a=@\n  a\n--",
+			exp: "/+  default-agent\n\n|%\n++  foo  1\n++  bar\n  |=  a=@\n  a\n--\n",
+		},
+	}
+	for _, test := range tests {
+		out, err := Source([]byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(out) != test.exp {
+			t.Errorf("bad format:\nexp:\n%s\ngot:\n%s", test.exp, out)
+		}
+	}
+
+	// jogs in the style guide's layout are left unchanged
+	for _, src := range []string{
+		"?-    a\n    %b\n  =/  c  1\n  c\n::\n    %d\n  ?+    e  ~\n      %f\n    =/  g  2\n    g\n  ==\n==\n",
+		"?+    mark  (on-poke:def mark vase)\n    %noun\n  ?-  -.act\n    %inc  `this\n    %reset  `this\n  ==\n==\n",
+		"?+  a  ~\n  %b  1\n  %c  2\n==\n",
+	} {
+		if out, err := Source([]byte(src)); err != nil {
+			t.Fatal(err)
+		} else if string(out) != src {
+			t.Errorf("bad format:\nexp:\n%s\ngot:\n%s", src, out)
+		}
+	}
+
+	for _, src := range []string{"=/  n", "=/  n\n", ")", "{a b}"} {
+		if _, err := Source([]byte(src)); err == nil {
+			t.Errorf("%q: expected parse error", src)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*", "*.hoon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical := func(src []byte) string {
+		f, err := parser.New(scanner.New(src)).ParseFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sb strings.Builder
+		ast.Print(&sb, f)
+		return sb.String()
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Source(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := Source(out)
+		if err != nil {
+			t.Fatalf("%v: formatted source does not parse: %v", file, err)
+		} else if string(again) != string(out) {
+			t.Errorf("%v: formatting is not idempotent:\n%s\n\n%s", file, out, again)
+		} else if canonical(src) != canonical(out) {
+			t.Errorf("%v: formatting changed meaning", file)
+		} else if strings.Count(string(src), "::") != strings.Count(string(out), "::") {
+			t.Errorf("%v: formatting lost comments", file)
+		}
+	}
+}
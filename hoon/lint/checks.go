@@ -0,0 +1,257 @@
+package lint
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+)
+
+// maxWidth is the column limit enforced by LineLength.
+const maxWidth = 80
+
+// Unused reports faces pinned with =/ that are never referred to.
+var Unused = &Analyzer{
+	Name: "unused",
+	Doc:  "report =/ faces that are never used",
+	Run: func(p *Pass) {
+		ast.Inspect(p.File, func(n ast.Node) bool {
+			if r, ok := n.(ast.Rune); ok && r.Lit == "=/" && len(r.Args) == 3 {
+				for _, f := range boundFaces(r.Args[0]) {
+					if !uses(r.Args[2], f.Name) {
+						p.Reportf(f.Syntax.Pos, "%v is never used", f.Name)
+					}
+				}
+			}
+			return true
+		})
+	},
+}
+
+// Shadow reports faces that are bound while a face of the same name is
+// already in scope.
+var Shadow = &Analyzer{
+	Name: "shadow",
+	Doc:  "report faces that shadow an enclosing face",
+	Run: func(p *Pass) {
+		var walk func(n ast.Node, scope map[string]int)
+		walk = func(n ast.Node, scope map[string]int) {
+			r, ok := n.(ast.Rune)
+			if !ok {
+				for _, k := range ast.Children(n) {
+					walk(k, scope)
+				}
+				return
+			}
+			// the faces of the binding, the arguments evaluated outside
+			// their scope, and the body they are in scope for
+			var bound []ast.Face
+			var outside []ast.Node
+			var body ast.Node
+			switch {
+			case r.Lit == "=/" && len(r.Args) == 3:
+				bound, outside, body = boundFaces(r.Args[0]), r.Args[1:2], r.Args[2]
+			case (r.Lit == "=|" || r.Lit == "|=") && len(r.Args) == 2:
+				bound, body = boundFaces(r.Args[0]), r.Args[1]
+			default:
+				for _, k := range r.Args {
+					walk(k, scope)
+				}
+				return
+			}
+			for _, k := range outside {
+				walk(k, scope)
+			}
+			inner := make(map[string]int, len(scope)+len(bound))
+			for name, pos := range scope {
+				inner[name] = pos
+			}
+			for _, f := range bound {
+				if pos, ok := scope[f.Name]; ok {
+					line, col := p.Position(pos)
+					p.Reportf(f.Syntax.Pos, "%v shadows face declared at %v:%v", f.Name, line, col)
+				}
+				inner[f.Name] = f.Syntax.Pos
+			}
+			walk(body, inner)
+		}
+		walk(p.File, nil)
+	},
+}
+
+// Terminator reports == and -- terminators that are not aligned with the
+// runes they close.
+var Terminator = &Analyzer{
+	Name: "terminator",
+	Doc:  "report == and -- terminators not aligned with their runes",
+	Run: func(p *Pass) {
+		ast.Inspect(p.File, func(n ast.Node) bool {
+			r, ok := n.(ast.Rune)
+			if !ok || !isTall(r) {
+				return true
+			}
+			e, ok := parser.LookupRune(r.Lit)
+			if !ok {
+				return true
+			}
+			var i int // index of the Text containing the terminator
+			var term string
+			switch {
+			case e.Arms:
+				i, term = len(r.Args), "--"
+			case e.Jogging:
+				i, term = len(r.Args)-e.Post, "=="
+			default:
+				return true
+			}
+			text := r.Syntax.Text[i]
+			j := strings.LastIndex(text, term)
+			if j < 0 || !strings.Contains(text[:j], "\n") {
+				return true // terminator on the same line as the rune's last argument
+			}
+			pos := textPos(r, i) + j
+			_, runeCol := p.Position(r.Syntax.Pos)
+			if _, col := p.Position(pos); col != runeCol {
+				p.Reportf(pos, "%v should be aligned with %v at column %v", term, r.Lit, runeCol)
+			}
+			return true
+		})
+	},
+}
+
+// Gap reports gaps between the arguments of tall runes that are neither two
+// spaces nor a newline.
+var Gap = &Analyzer{
+	Name: "gap",
+	Doc:  "report tall-form gaps that are not two spaces or a newline",
+	Run: func(p *Pass) {
+		ast.Inspect(p.File, func(n ast.Node) bool {
+			r, ok := n.(ast.Rune)
+			if !ok || !isTall(r) || r.Lit == "+*" {
+				return true // the aliases of +* are conventionally aligned
+			}
+			e, _ := parser.LookupRune(r.Lit)
+			for i, text := range r.Syntax.Text {
+				pos := textPos(r, i)
+				if i == 0 {
+					text, pos = text[len(r.Lit):], pos+len(r.Lit)
+				}
+				for j := 0; j < len(text); {
+					if strings.HasPrefix(text[j:], "::") {
+						nl := strings.IndexByte(text[j:], '\n')
+						if nl < 0 {
+							break
+						}
+						j += nl
+						continue
+					} else if text[j] != ' ' {
+						j++
+						continue
+					}
+					k := j
+					for k < len(text) && text[k] == ' ' {
+						k++
+					}
+					// indentation, trailing space, and space before comments
+					// are not gaps
+					indent := j > 0 && text[j-1] == '\n'
+					trailing := k < len(text) && text[k] == '\n'
+					comment := strings.HasPrefix(text[k:], "::")
+					// jogging runes are conventionally followed by four
+					// spaces, and the values of pairs and arms may be aligned
+					head := i == 0 && j == 0 && e.Jogging && k-j == 4
+					align := j == 0 && k-j > 2 && aligned(r, e, i)
+					if k-j != 2 && !indent && !trailing && !comment && !head && !align {
+						p.Reportf(pos+j, "gap should be two spaces, not %v", k-j)
+					}
+					j = k
+				}
+			}
+			return true
+		})
+	},
+}
+
+// aligned reports whether the ith child of r may be aligned with its
+// neighbours: whether it is the value of a pair or the body of an arm.
+func aligned(r ast.Rune, e parser.RuneInfo, i int) bool {
+	if e.Arm {
+		return i == 1
+	}
+	return e.Pairs && i > e.Args && i < len(r.Args)-e.Post && (i-e.Args)%2 == 1
+}
+
+// DebugPrint reports ~& debug printfs.
+var DebugPrint = &Analyzer{
+	Name: "debug",
+	Doc:  "report ~& debug prints",
+	Run: func(p *Pass) {
+		ast.Inspect(p.File, func(n ast.Node) bool {
+			if r, ok := n.(ast.Rune); ok && r.Lit == "~&" && r.Syntax != nil {
+				p.Reportf(r.Syntax.Pos, "debug print left in code")
+			}
+			return true
+		})
+	},
+}
+
+// TrailingSpace reports lines ending in whitespace.
+var TrailingSpace = &Analyzer{
+	Name: "trailing",
+	Doc:  "report trailing whitespace",
+	Run: func(p *Pass) {
+		lines, offsets := p.Lines()
+		for i, line := range lines {
+			if trimmed := strings.TrimRight(line, " \t\r"); len(trimmed) != len(line) {
+				p.Reportf(offsets[i]+len(trimmed), "trailing whitespace")
+			}
+		}
+	},
+}
+
+// LineLength reports lines longer than 80 columns.
+var LineLength = &Analyzer{
+	Name: "length",
+	Doc:  "report lines longer than 80 columns",
+	Run: func(p *Pass) {
+		lines, offsets := p.Lines()
+		for i, line := range lines {
+			if n := utf8.RuneCountInString(line); n > maxWidth {
+				p.Reportf(offsets[i], "line is %v columns long", n)
+			}
+		}
+	},
+}
+
+// boundFaces returns the faces bound by a =/ or =| name, or a gate sample,
+// such as a, a=@, or [a=@ b=@].
+func boundFaces(n ast.Node) []ast.Face {
+	switch n := n.(type) {
+	case ast.Face:
+		if n.Syntax != nil {
+			return []ast.Face{n}
+		}
+	case ast.Tis:
+		return boundFaces(n.Left)
+	case ast.Cell:
+		return append(boundFaces(n.Head), boundFaces(n.Tail)...)
+	}
+	return nil
+}
+
+// uses reports whether n refers to the face name.
+func uses(n ast.Node, name string) (used bool) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case ast.Face:
+			used = used || n.Name == name
+		case ast.Wing:
+			for _, l := range n.Limbs {
+				used = used || l.Name == name
+			}
+		}
+		return !used
+	})
+	return used
+}
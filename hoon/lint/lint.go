@@ -0,0 +1,128 @@
+// Package lint reports style and correctness problems in Hoon source.
+//
+// Each check is an Analyzer, which inspects a parsed file and reports
+// Diagnostics through a Pass. The checks provided by this package are listed
+// in Analyzers; programs may run their own Analyzers alongside them.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+)
+
+// An Analyzer is a single check.
+type Analyzer struct {
+	Name string // short identifier, used to select the check
+	Doc  string // one-line description
+	Run  func(*Pass)
+}
+
+// A Pass provides an Analyzer with the file being checked, and collects the
+// Diagnostics it reports.
+type Pass struct {
+	Analyzer *Analyzer
+	Src      []byte
+	File     ast.File // parsed with concrete syntax
+
+	lines []int // offset of the start of each line
+	diags []Diagnostic
+}
+
+// Reportf reports a problem at byte offset pos.
+func (p *Pass) Reportf(pos int, format string, args ...interface{}) {
+	line, col := p.Position(pos)
+	p.diags = append(p.diags, Diagnostic{
+		Pos:      pos,
+		Line:     line,
+		Col:      col,
+		Analyzer: p.Analyzer.Name,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Position returns the 1-based line and column of byte offset pos. Columns
+// count characters, not bytes.
+func (p *Pass) Position(pos int) (line, col int) {
+	i := sort.Search(len(p.lines), func(i int) bool { return p.lines[i] > pos }) - 1
+	return i + 1, utf8.RuneCount(p.Src[p.lines[i]:pos]) + 1
+}
+
+// Lines returns the lines of the file, without their newlines, along with
+// the offset of each.
+func (p *Pass) Lines() ([]string, []int) {
+	lines := strings.Split(string(p.Src), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, p.lines[:len(lines)]
+}
+
+// A Diagnostic is a problem reported by an Analyzer.
+type Diagnostic struct {
+	Pos       int // byte offset
+	Line, Col int
+	Analyzer  string
+	Message   string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v:%v: %v (%v)", d.Line, d.Col, d.Message, d.Analyzer)
+}
+
+// Analyzers lists the checks provided by this package.
+var Analyzers = []*Analyzer{
+	Unused,
+	Shadow,
+	Terminator,
+	Gap,
+	DebugPrint,
+	TrailingSpace,
+	LineLength,
+}
+
+// Run parses src and runs each of the analyzers on it, returning their
+// Diagnostics sorted by position.
+func Run(src []byte, analyzers []*Analyzer) ([]Diagnostic, error) {
+	f, err := parser.NewConcrete(scanner.New(src)).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	lines := []int{0}
+	for i, c := range src {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	var diags []Diagnostic
+	for _, a := range analyzers {
+		p := &Pass{Analyzer: a, Src: src, File: f, lines: lines}
+		a.Run(p)
+		diags = append(diags, p.diags...)
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Pos < diags[j].Pos
+	})
+	return diags, nil
+}
+
+// textPos returns the byte offset of s.Text[i] within the source of n.
+func textPos(n ast.Node, i int) int {
+	if i == 0 {
+		return ast.SyntaxOf(n).Pos
+	}
+	return ast.SyntaxOf(ast.Children(n)[i-1]).End
+}
+
+// isTall reports whether r was written in tall form.
+func isTall(r ast.Rune) bool {
+	if r.Syntax == nil || len(r.Args) == 0 || !strings.HasPrefix(r.Syntax.Text[0], r.Lit) {
+		return false
+	}
+	return !strings.HasPrefix(r.Syntax.Text[0][len(r.Lit):], "(")
+}
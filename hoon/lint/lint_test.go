@@ -0,0 +1,72 @@
+package lint
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzers(t *testing.T) {
+	tests := []struct {
+		a   *Analyzer
+		src string
+		exp []string
+	}{
+		{Unused, "=/  a  1\n=/  b=@  2\na", []string{"2:5: b is never used (unused)"}},
+		{Unused, "=/  a  1\n(add a.b 2)", nil},
+		{Unused, "=/  [a=@ b=@]  [1 2]\n|=(c=@ (add a c))", []string{"1:10: b is never used (unused)"}},
+		{Shadow, "=/  a  1\n|=  a=@\na", []string{"2:5: a shadows face declared at 1:5 (shadow)"}},
+		{Shadow, "=/  a  =/(a 1 a)\na", nil},
+		{Shadow, "|=  [a=@ b=@]\n=|  b=@\n(add a b)", []string{"2:5: b shadows face declared at 1:10 (shadow)"}},
+		{Terminator, ":~  1\n    2\n==", nil},
+		{Terminator, ":~  1  2  ==", nil},
+		{Terminator, "  :~  1\n      2\n==", []string{"3:1: == should be aligned with :~ at column 3 (terminator)"}},
+		{Terminator, "|%\n++  a  1\n  --", []string{"3:3: -- should be aligned with |% at column 1 (terminator)"}},
+		{Gap, "=/  a   1\na", []string{"1:6: gap should be two spaces, not 3 (gap)"}},
+		{Gap, "?-    a\n  %b    1\n  %c  2\n==", nil},
+		{Gap, "?-  a\n  %b  1  ::  comment   here\n==", nil},
+		{Gap, "|%\n++  a    1\n++  bcd  2\n--", nil},
+		{Gap, "=/  a  1   ::  comment\na", nil},
+		{DebugPrint, "~&  %hi\n~&(%there 1)", []string{"1:1: debug print left in code (debug)", "2:1: debug print left in code (debug)"}},
+		{TrailingSpace, "=/  a  1 \na  \n", []string{"1:9: trailing whitespace (trailing)", "2:2: trailing whitespace (trailing)"}},
+		{LineLength, "'" + strings.Repeat("é", 80) + "'\n", []string{"1:1: line is 82 columns long (length)"}},
+	}
+	for _, test := range tests {
+		diags, err := Run([]byte(test.src), []*Analyzer{test.a})
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+			continue
+		}
+		var got []string
+		for _, d := range diags {
+			got = append(got, d.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(test.exp, "\n") {
+			t.Errorf("%v %q:\nexpected %q\ngot      %q", test.a.Name, test.src, test.exp, got)
+		}
+	}
+
+	for _, src := range []string{"=/  a", "=/  a\n", ")", "{a b}"} {
+		if _, err := Run([]byte(src), Analyzers); err == nil {
+			t.Errorf("%q: expected parse error", src)
+		}
+	}
+}
+
+// TestFiles checks that the analyzers run cleanly on the parser's testdata.
+func TestFiles(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*", "*.hoon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Run(src, Analyzers); err != nil {
+			t.Errorf("%v: %v", file, err)
+		}
+	}
+}
@@ -1,56 +1,75 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
+	"unicode/utf8"
 
+	"lukechampine.com/urbit/atom"
 	"lukechampine.com/urbit/hoon/ast"
 	"lukechampine.com/urbit/hoon/scanner"
 	"lukechampine.com/urbit/hoon/token"
 )
 
-// TODO: determine appropriate values for these
+// precedences of the binary irregular forms; a=b:c is a=(b:c), and a=b^c:d
+// is a=([b c]:d). ^ is right-associative: a^b^c is [a [b c]].
 var precedences = map[token.Token]int{
+	token.Ket: 3,
 	token.Col: 2,
-	token.Tis: 2,
+	token.Tis: 1,
 }
 
+// A runeEntry describes the arguments taken by a rune. In tall form, the
+// fixed arguments are followed by the jogging arguments, if any, terminated by
+// ==, and then by the post arguments. Core runes instead end with a list of
+// arms, terminated by --. In wide form, arguments are separated by single
+// spaces, with pairs separated by commas, e.g. ?-(a %b 1, %c 2).
 type runeEntry struct {
 	args    int
 	jogging bool
-	post    int // some runes have fixed args *after* the jog
+	pairs   bool // jogging args come in pairs, e.g. the cases of ?-
+	post    int  // some runes have fixed args *after* the jog
+	arms    bool // core runes are followed by arms
 }
 
-// TODO: probably numerous errors here
 var runeTab = map[string]runeEntry{
+	// dot
 	".^": {args: 2},
 	".+": {args: 1},
 	".*": {args: 2},
 	".=": {args: 2},
 	".?": {args: 1},
+	// zap
 	"!>": {args: 1},
 	"!<": {args: 2},
 	"!:": {args: 1},
 	"!.": {args: 1},
 	"!=": {args: 1},
 	"!?": {args: 2},
+	"!!": {args: 0},
+	"!,": {args: 2},
+	"!@": {args: 3},
+	// tis
 	"=+": {args: 2},
 	"=-": {args: 2},
 	"=|": {args: 2},
 	"=/": {args: 3},
-	"=;": {args: 2},
+	"=;": {args: 3},
 	"=.": {args: 3},
-	"=:": {jogging: true, post: 2},
+	"=:": {jogging: true, pairs: true, post: 1},
 	"=?": {args: 4},
 	"=*": {args: 3},
 	"=>": {args: 2},
 	"=<": {args: 2},
-	"=~": {args: 2},
+	"=~": {jogging: true},
 	"=,": {args: 2},
 	"=^": {args: 4},
+	// wut
 	"?>": {args: 2},
 	"?<": {args: 2},
-	"?|": {args: 1, jogging: true},
-	"?&": {args: 1, jogging: true},
+	"?|": {jogging: true},
+	"?&": {jogging: true},
 	"?!": {args: 1},
 	"?=": {args: 2},
 	"?:": {args: 3},
@@ -58,39 +77,39 @@ var runeTab = map[string]runeEntry{
 	"?@": {args: 3},
 	"?^": {args: 3},
 	"?~": {args: 3},
-	"?-": {args: 1, jogging: true},
-	"?+": {args: 1, jogging: true},
-	"|_": {args: 1, jogging: true},
-	"|%": {args: 0, jogging: true}, // cores can be empty
+	"?-": {args: 1, jogging: true, pairs: true},
+	"?+": {args: 2, jogging: true, pairs: true},
+	// bar
+	"|_": {args: 1, arms: true},
+	"|%": {arms: true},
 	"|:": {args: 2},
 	"|.": {args: 1},
 	"|-": {args: 1},
 	"|?": {args: 1},
-	"|^": {args: 2, jogging: true},
+	"|^": {args: 1, arms: true},
 	"|~": {args: 2},
 	"|=": {args: 2},
 	"|*": {args: 2},
-	"|@": {args: 2},
-	"++": {args: 2},
-	"+$": {args: 2},
-	"+*": {args: 2},
-	"+|": {args: 1},
+	"|@": {arms: true},
+	"|$": {args: 2},
+	// col
 	":-": {args: 2},
 	":_": {args: 2},
 	":+": {args: 3},
 	":^": {args: 4},
-	":*": {args: 1, jogging: true},
-	":~": {args: 1, jogging: true},
-	"::": {args: 1},
-	"%~": {args: 3, jogging: true},
+	":*": {jogging: true},
+	":~": {jogging: true},
+	// cen
+	"%~": {args: 3},
 	"%-": {args: 2},
 	"%.": {args: 2},
 	"%+": {args: 3},
 	"%^": {args: 4},
-	"%:": {args: 2, jogging: true},
-	"%=": {args: 3, jogging: true},
-	"%_": {args: 2, jogging: true},
-	"%*": {args: 3, jogging: true},
+	"%:": {args: 1, jogging: true},
+	"%=": {args: 1, jogging: true, pairs: true},
+	"%_": {args: 1, jogging: true, pairs: true},
+	"%*": {args: 2, jogging: true, pairs: true},
+	// ket
 	"^|": {args: 1},
 	"^&": {args: 1},
 	"^?": {args: 1},
@@ -101,10 +120,11 @@ var runeTab = map[string]runeEntry{
 	"^~": {args: 1},
 	"^*": {args: 1},
 	"^=": {args: 2},
+	// buc
 	"$_": {args: 1},
-	"$%": {args: 1, jogging: true},
-	"$:": {args: 1, jogging: true},
-	"$?": {args: 1, jogging: true},
+	"$%": {jogging: true},
+	"$:": {jogging: true},
+	"$?": {jogging: true},
 	"$<": {args: 2},
 	"$>": {args: 2},
 	"$-": {args: 2},
@@ -112,43 +132,182 @@ var runeTab = map[string]runeEntry{
 	"$^": {args: 2},
 	"$~": {args: 2},
 	"$=": {args: 2},
-	";:": {args: 2, jogging: true},
+	"$+": {args: 2},
+	"$;": {args: 1},
+	"$&": {args: 2},
+	"$|": {args: 2},
+	// mic
+	";:": {args: 1, jogging: true},
 	";+": {args: 1},
 	";/": {args: 1},
 	";*": {args: 1},
-	";=": {args: 1, jogging: true},
+	";=": {jogging: true},
 	";;": {args: 2},
-	";~": {args: 2},
+	";~": {args: 1, jogging: true},
+	";<": {args: 4},
+	// sig
 	"~>": {args: 2},
 	"~|": {args: 2},
 	"~_": {args: 2},
 	"~$": {args: 2},
-	"~%": {args: 3, jogging: true},
+	"~%": {args: 4},
 	"~<": {args: 2},
 	"~+": {args: 1},
 	"~/": {args: 2},
 	"~&": {args: 2},
 	"~?": {args: 3},
 	"~!": {args: 2},
+	"~=": {args: 2},
 }
 
-func hasArms(r string) bool {
-	switch r {
-	case "|%", "|^": // TODO
-		return true
-	default:
-		return false
+// armTab describes the arms that may appear in a core. The name-alias pairs
+// of +* are terminated by the next arm, rather than by ==.
+var armTab = map[string]runeEntry{
+	"++": {args: 2},
+	"+$": {args: 2},
+	"+|": {args: 1},
+	"+*": {jogging: true, pairs: true},
+}
+
+func isArm(t token.Token, lit string) bool {
+	_, ok := armTab[lit]
+	return t == token.Rune && ok
+}
+
+// A RuneInfo describes the tall form of a rune.
+type RuneInfo struct {
+	Args    int  // fixed arguments
+	Jogging bool // followed by a jog of arguments terminated by ==
+	Pairs   bool // jogging arguments come in pairs
+	Post    int  // fixed arguments after the jog
+	Arms    bool // followed by arms terminated by --
+	Arm     bool // the rune is itself an arm, such as ++
+}
+
+// LookupRune returns a description of the rune lit, if it is known.
+func LookupRune(lit string) (RuneInfo, bool) {
+	e, ok := runeTab[lit]
+	_, arm := armTab[lit]
+	if arm {
+		e, ok = armTab[lit], true
 	}
+	return RuneInfo{
+		Args:    e.args,
+		Jogging: e.jogging,
+		Pairs:   e.pairs,
+		Post:    e.post,
+		Arms:    e.arms,
+		Arm:     arm,
+	}, ok
 }
 
 type Parser struct {
 	s   *scanner.Scanner
 	tok token.Token
 	lit string
+	pos int // offset of tok
+
+	// a token that has already been scanned; see splitRune and next
+	pend    bool
+	pendTok token.Token
+	pendLit string
+	pendPos int
+
+	concrete bool
 }
 
-func (p *Parser) next() {
+// An Error is a syntax error.
+type Error struct {
+	Pos       int // byte offset
+	Line, Col int
+	Msg       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("parse: %v:%v: %v", e.Line, e.Col, e.Msg)
+}
+
+// errorf reports a syntax error at the current token.
+func (p *Parser) errorf(format string, args ...interface{}) {
+	p.errorAt(p.pos, format, args...)
+}
+
+// errorAt reports a syntax error at offset pos.
+func (p *Parser) errorAt(pos int, format string, args ...interface{}) {
+	src := p.s.Source()[:pos]
+	panic(&Error{
+		Pos:  pos,
+		Line: 1 + bytes.Count(src, []byte("\n")),
+		Col:  1 + utf8.RuneCount(src[bytes.LastIndexByte(src, '\n')+1:]),
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *Parser) scan() {
+	if p.pend {
+		p.tok, p.lit, p.pos = p.pendTok, p.pendLit, p.pendPos
+		p.pend = false
+		return
+	}
 	p.tok, p.lit = p.s.Scan()
+	p.pos = p.s.Offset() - len(p.lit)
+}
+
+// next advances to the next token. Comments are treated as whitespace, and
+// merged with any adjacent gaps into a single gap.
+func (p *Parser) next() {
+	p.scan()
+	if p.tok != token.Gap && p.tok != token.Comment {
+		return
+	}
+	lit, pos := p.lit, p.pos
+	for {
+		p.scan()
+		if p.tok != token.Gap && p.tok != token.Comment {
+			break
+		}
+		lit += p.lit
+	}
+	p.pend, p.pendTok, p.pendLit, p.pendPos = true, p.tok, p.lit, p.pos
+	p.tok, p.lit, p.pos = token.Gap, lit, pos
+}
+
+// splitRune splits the current rune token into two single-character tokens.
+// This is necessary when a rune appears directly after an expression, e.g. in
+// a=* or a=~, where = is a binary operator.
+func (p *Parser) splitRune() {
+	p.tok, _ = scanner.New([]byte(p.lit[:1])).Scan()
+	p.pendTok, p.pendLit = scanner.New([]byte(p.lit[1:])).Scan()
+	p.pend, p.pendPos = true, p.pos+1
+	p.lit = p.lit[:1]
+}
+
+// finish attaches concrete syntax to n, which spans [start, end) in the
+// source, if the parser is in concrete mode. The children of n must already
+// have concrete syntax.
+func (p *Parser) finish(n ast.Node, start, end int) ast.Node {
+	if !p.concrete {
+		return n
+	}
+	src := p.s.Source()
+	kids := ast.Children(n)
+	s := &ast.Syntax{Pos: start, End: end, Text: make([]string, len(kids)+1)}
+	cur := start
+	for i, k := range kids {
+		ks := ast.SyntaxOf(k)
+		if ks == nil || ks.Pos < cur || ks.End > end {
+			p.errorf("internal error: bad concrete syntax for %T", k)
+		}
+		s.Text[i] = string(src[cur:ks.Pos])
+		cur = ks.End
+	}
+	s.Text[len(kids)] = string(src[cur:end])
+	return ast.WithSyntax(n, s)
+}
+
+// finishHere calls finish with the end of n at the current token.
+func (p *Parser) finishHere(n ast.Node, start int) ast.Node {
+	return p.finish(n, start, p.pos)
 }
 
 func (p *Parser) consumeWhitespace() {
@@ -159,7 +318,7 @@ func (p *Parser) consumeWhitespace() {
 
 func (p *Parser) expect(t token.Token) {
 	if p.tok != t {
-		panic(fmt.Sprintf("parse: expected %q, got %q", t, p.tok))
+		p.errorf("expected %q, got %q", t, p.tok)
 	}
 	p.next()
 }
@@ -203,6 +362,8 @@ func (p *Parser) consumeTall(stop token.Token) []ast.Node {
 	}
 }
 
+// Parse parses a single expression. It panics with an *Error if the
+// expression is malformed; use ParseFile to parse untrusted source.
 func (p *Parser) Parse() ast.Node {
 	p.consumeWhitespace()
 	n := p.parseExpr()
@@ -215,16 +376,29 @@ func (p *Parser) parseExpr() ast.Node {
 }
 
 func (p *Parser) parseBinaryExpr(prec int) ast.Node {
+	start := p.pos
 	n := p.parseUnaryExpr()
 	for {
+		if p.tok == token.Rune && (p.lit[0] == '=' || p.lit[0] == ':') {
+			p.splitRune()
+		}
 		op := p.tok
 		qPrec, ok := precedences[op]
 		if !ok || qPrec < prec {
 			break
 		}
 		p.next()
+		if op == token.Ket {
+			qPrec-- // right-associative
+		}
 		next := p.parseBinaryExpr(qPrec + 1)
 		switch op {
+		case token.Ket:
+			n = ast.Cell{
+				Tok:  op,
+				Head: n,
+				Tail: next,
+			}
 		case token.Col:
 			n = ast.Rune{
 				Tok:  op,
@@ -240,48 +414,158 @@ func (p *Parser) parseBinaryExpr(prec int) ast.Node {
 		default:
 			panic("unhandled binop")
 		}
+		n = p.finishHere(n, start)
 	}
 	return n
 }
 
 func (p *Parser) parseUnaryExpr() ast.Node {
+	start := p.pos
+	return p.finishHere(p.parseUnary(start), start)
+}
+
+// parseUnary parses a unary expression beginning at offset start. Nodes
+// other than the returned node must be finished here; the returned node is
+// finished by the caller.
+func (p *Parser) parseUnary(start int) ast.Node {
 	t, lit := p.tok, p.lit
 	p.next()
 	switch t {
-	case token.Face:
-		if p.tok == token.Pal {
+	case token.Face, token.Buc:
+		return p.parseWingExpr(t, lit, start)
+	case token.Ket:
+		if p.tok != token.Face && p.tok != token.Buc && p.tok != token.Ket {
+			return ast.Ket{Tok: t}
+		}
+		return p.parseWingExpr(t, lit, start)
+	case token.Com:
+		if p.tok != token.Dot && startsExpr(p.tok) {
+			// ,type
+			return ast.Rune{Tok: t, Lit: "^:", Args: []ast.Node{p.parseUnaryExpr()}}
+		}
+		return p.parseWingExpr(t, lit, start)
+	case token.Cab:
+		// _value
+		return ast.Rune{Tok: t, Lit: "$_", Args: []ast.Node{p.parseUnaryExpr()}}
+	case token.Fas:
+		return p.parsePath(t)
+	case token.Mic:
+		return p.parseSail(t)
+	case token.Dot:
+		if p.tok == token.Num || p.tok == token.Sig || p.tok == token.Hep {
+			return p.parseLiteral(t, lit) // e.g. .1.5, .~zod, .127.0.0.1
+		}
+		return p.parseWingExpr(t, lit, start)
+	case token.Hep, token.HepHep:
+		if p.tok == token.Num {
+			return p.parseLiteral(t, lit) // e.g. -5, --5
+		} else if t == token.HepHep {
+			p.errorf("unexpected --")
+		}
+		return p.parseWingExpr(t, lit, start)
+	case token.Pam, token.Bar:
+		switch p.tok {
+		case token.Num:
+			return p.parseWingExpr(t, lit, start)
+		case token.Pal:
+			// &(a b) and |(a b)
 			p.next()
 			return ast.Rune{
-				Tok: t,
-				Lit: "%=",
-				Args: append([]ast.Node{ast.Face{
-					Tok:  t,
-					Name: lit,
-				}}, p.consumeWideComma()...),
+				Tok:  t,
+				Lit:  map[token.Token]string{token.Pam: "?&", token.Bar: "?|"}[t],
+				Args: p.consumeWide(),
 			}
 		}
-		return ast.Face{Tok: t, Name: lit}
+		return ast.Literal{Tok: t, Lit: lit}
 	case token.Num:
 		return ast.Num{Tok: t, Int: lit}
+	case token.Cord, token.Tape:
+		return ast.Literal{Tok: t, Lit: lit}
+	case token.Cen:
+		switch p.tok {
+		case token.Face, token.Buc, token.Pam, token.Bar, token.Num:
+			lit += p.lit
+			p.next()
+			if p.tok == token.Dot {
+				// a dynamic hint, e.g. %mean.'need'
+				head := p.finishHere(ast.Literal{Tok: t, Lit: lit}, start)
+				p.next()
+				return ast.Cell{Tok: t, Head: head, Tail: p.parseUnaryExpr()}
+			}
+			return ast.Literal{Tok: t, Lit: lit}
+		}
+		p.errorf("expected term after %%, got %q", p.lit)
+	case token.Sig:
+		switch p.tok {
+		case token.Sel:
+			// ~[a b c]
+			p.next()
+			return ast.Rune{Tok: t, Lit: ":~", Args: p.consumeSeq()}
+		case token.Pal:
+			// ~(arm core arg)
+			p.next()
+			args := p.consumeWide()
+			switch {
+			case len(args) < 2:
+				p.errorf("~( requires at least two arguments")
+			case len(args) == 2:
+				return ast.Rune{Tok: t, Lit: "=<", Args: args}
+			case len(args) > 3:
+				args = []ast.Node{args[0], args[1], p.consNodes(args[2:])}
+			}
+			return ast.Rune{Tok: t, Lit: "%~", Args: args}
+		case token.Face, token.Num, token.Dot, token.Hep, token.Sig:
+			return p.parseLiteral(t, lit) // e.g. ~zod, ~2020.1.1, ~.knot
+		}
+		return ast.Literal{Tok: t, Lit: lit}
 	case token.Pat:
+		if p.tok == token.Face {
+			lit = p.lit
+			p.next()
+			return ast.Pat{Tok: t, Aura: lit}
+		}
 		return ast.Pat{Tok: t}
-	case token.Dot:
-		return ast.Dot{Tok: t}
-	case token.Buc:
+	case token.Tar:
+		if startsExpr(p.tok) {
+			// *type
+			return ast.Rune{Tok: t, Lit: "^*", Args: []ast.Node{p.parseUnaryExpr()}}
+		}
+		return ast.Tar{Tok: t}
+	case token.Wut:
 		if p.tok == token.Pal {
+			// ?(a b)
 			p.next()
-			return ast.Rune{
-				Tok: t,
-				Lit: "%=",
-				Args: append([]ast.Node{ast.Buc{
-					Tok: t,
-				}}, p.consumeWideComma()...),
-			}
+			return ast.Rune{Tok: t, Lit: "$?", Args: p.consumeWide()}
 		}
-		return ast.Buc{Tok: t}
+		return ast.Wut{Tok: t}
+	case token.Zap:
+		// !a
+		return ast.Rune{Tok: t, Lit: "?!", Args: []ast.Node{p.parseUnaryExpr()}}
+	case token.Col:
+		// :(f a b)
+		p.expect(token.Pal)
+		return ast.Rune{Tok: t, Lit: ";:", Args: p.consumeWide()}
+	case token.Tic:
+		q := p.parseExpr()
+		if p.tok == token.Tic {
+			// `type`value
+			p.next()
+			return ast.Rune{Tok: t, Lit: "^-", Args: []ast.Node{q, p.parseExpr()}}
+		}
+		// `a is [~ a]
+		sig := p.finish(ast.Literal{Tok: t, Lit: "~"}, start+1, start+1)
+		return ast.Cell{Tok: t, Head: sig, Tail: q}
 	case token.Rune:
+		if lit == "%." && p.tok == token.Face && (p.lit == "y" || p.lit == "n") {
+			lit += p.lit
+			p.next()
+			return ast.Literal{Tok: t, Lit: lit}
+		}
 		return p.parseRune(t, lit)
 	case token.Lus:
+		if p.tok != token.Pal {
+			return p.parseWingExpr(t, lit, start)
+		}
 		p.expect(token.Pal)
 		q := p.parseExpr()
 		p.expect(token.Par)
@@ -291,6 +575,19 @@ func (p *Parser) parseUnaryExpr() ast.Node {
 			Args: []ast.Node{q},
 		}
 	case token.Tis:
+		if p.tok == token.Face {
+			// =face is face=face, e.g. =mark or =bowl:gall
+			r := p.parseBinaryExpr(precedences[token.Col])
+			l, ok := r.(ast.Face)
+			if rn, isRune := r.(ast.Rune); isRune && rn.Lit == "=<" {
+				l, ok = rn.Args[0].(ast.Face)
+			}
+			if !ok {
+				p.errorf("expected face after =")
+			}
+			left := p.finish(ast.Face{Tok: l.Tok, Name: l.Name}, start+1, start+1)
+			return ast.Tis{Tok: t, Left: left, Right: r}
+		}
 		p.expect(token.Pal)
 		q := p.parseExpr()
 		p.expect(token.Ace)
@@ -308,51 +605,500 @@ func (p *Parser) parseUnaryExpr() ast.Node {
 			Args: p.consumeWide(),
 		}
 	case token.Sel:
-		n := p.parseCons()
-		p.expect(token.Ser)
+		n := p.finishHere(p.consNodes(p.consumeSeq()), start)
+		if p.tok == token.Sig {
+			// [a b]~ is [[a b] ~]
+			sig := p.finish(ast.Literal{Tok: token.Sig, Lit: "~"}, p.pos, p.pos+1)
+			p.next()
+			n = ast.Cell{Tok: t, Head: n, Tail: sig}
+		}
 		return n
 	default:
-		panic(fmt.Sprintf("unhandled token %v (%v)", lit, t))
+		p.errorAt(start, "unexpected %q", t)
+	}
+	return nil
+}
+
+// parsePath parses a path, such as /foo/(scot %ud 1), whose leading / has
+// already been consumed.
+func (p *Parser) parsePath(t token.Token) ast.Node {
+	path := ast.Path{Tok: t}
+	for {
+		switch p.tok {
+		case token.Face:
+			path.Segs = append(path.Segs, p.parseFaceLiteral(token.Face, p.lit))
+		case token.Num, token.Sig, token.Cen, token.Pal:
+			path.Segs = append(path.Segs, p.parseUnaryExpr())
+		default:
+			if len(path.Segs) > 0 {
+				p.errorf("invalid path segment %q", p.lit)
+			}
+			return path
+		}
+		if p.tok != token.Fas {
+			return path
+		}
+		p.next()
+	}
+}
+
+// parseSail parses a Sail element or text node, whose leading ; has already
+// been consumed.
+func (p *Parser) parseSail(t token.Token) ast.Node {
+	if p.tok == token.Ace {
+		// ; text
+		text := p.s.ScanLine()
+		p.next()
+		return ast.Sail{Tok: t, Text: text}
+	} else if p.tok != token.Face {
+		p.errorf("expected Sail tag, got %q", p.lit)
+	}
+	n := ast.Sail{Tok: t, Tag: p.lit}
+	p.next()
+	for {
+		switch p.tok {
+		case token.Dot, token.Hax:
+			key := map[token.Token]string{token.Dot: "class", token.Hax: "id"}[p.tok]
+			p.next()
+			if p.tok != token.Face {
+				p.errorf("expected %v name, got %q", key, p.lit)
+			}
+			n.Attrs = append(n.Attrs, ast.SailAttr{Key: key, Value: p.parseFaceLiteral(token.Tape, `"`+p.lit+`"`)})
+			continue
+		case token.Pal:
+			p.next()
+			kvs := p.consumeWideComma()
+			for i := 0; i < len(kvs); i += 2 {
+				key, ok := kvs[i].(ast.Face)
+				if !ok {
+					p.errorf("expected Sail attribute name")
+				}
+				n.Attrs = append(n.Attrs, ast.SailAttr{Key: key.Name, Value: kvs[i+1]})
+			}
+			continue
+		}
+		break
+	}
+	switch p.tok {
+	case token.Mic:
+		// ;br;
+		n.Void = true
+		p.next()
+	case token.Col:
+		// ;p: text
+		text := p.s.ScanLine()
+		if len(text) == 0 || text[0] != ' ' {
+			p.errorf("expected space after Sail :")
+		}
+		n.Text = text[1:]
+		p.next()
+	case token.Gap:
+		n.Kids = p.consumeTall(token.TisTis)
+	}
+	return n
+}
+
+// ParseFile parses a source file: any number of Ford directives, followed by
+// one or more expressions separated by gaps. If the file is malformed, the
+// error is an *Error.
+func (p *Parser) ParseFile() (f ast.File, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	return p.parseFile(), nil
+}
+
+func (p *Parser) parseFile() ast.File {
+	var f ast.File
+	p.consumeWhitespace()
+	for p.tok == token.Fas {
+		f.Ford = append(f.Ford, p.parseFord())
+		p.consumeWhitespace()
+	}
+	var body []ast.Node
+	for p.tok != token.EOF {
+		body = append(body, p.parseExpr())
+		if p.tok != token.EOF {
+			p.expect(token.Gap)
+		}
+	}
+	switch len(body) {
+	case 0:
+		p.errorf("empty file")
+	case 1:
+		f.Body = body[0]
+	default:
+		f.Body = p.finishSpan(ast.Rune{Tok: token.Rune, Lit: "=~", Args: body}, body[0], body[len(body)-1])
 	}
+	return p.finish(f, 0, len(p.s.Source())).(ast.File)
+}
+
+// fordTab lists the Ford runes and their number of arguments. The imports of
+// /- and /+ are instead a comma-separated list.
+var fordTab = map[string]int{
+	"/-": -1,
+	"/+": -1,
+	"/=": 2,
+	"/*": 3,
+	"/?": 1,
+}
+
+func (p *Parser) parseFord() ast.Ford {
+	t := p.tok
+	p.next()
+	f := ast.Ford{Tok: t, Lit: "/" + p.lit}
+	n, ok := fordTab[f.Lit]
+	if !ok {
+		p.errorf("unknown Ford rune %q", f.Lit)
+	}
+	p.next()
+	p.expect(token.Gap)
+	if n < 0 {
+		for {
+			f.Args = append(f.Args, p.fordArg())
+			if p.tok != token.Com {
+				return f
+			}
+			p.next()
+			p.consumeWhitespace()
+		}
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			p.expect(token.Gap)
+		}
+		f.Args = append(f.Args, p.fordArg())
+	}
+	return f
+}
+
+// fordArg returns the verbatim text of a Ford argument, such as *foo=bar or
+// /lib/foo.
+func (p *Parser) fordArg() string {
+	var arg string
+	for p.tok != token.Com && p.tok != token.Ace && p.tok != token.Gap && p.tok != token.EOF {
+		arg += p.lit
+		p.next()
+	}
+	if arg == "" {
+		p.errorf("expected Ford argument, got %q", p.lit)
+	}
+	return arg
+}
+
+// parseFaceLiteral parses a face as a Literal with the given value, e.g. the
+// path segment foo in /foo, or the class foo in ;div.foo.
+func (p *Parser) parseFaceLiteral(t token.Token, lit string) ast.Node {
+	start := p.pos
+	p.next()
+	return p.finishHere(ast.Literal{Tok: t, Lit: lit}, start)
+}
+
+// startsExpr reports whether t can begin an expression.
+func startsExpr(t token.Token) bool {
+	switch t {
+	case token.Ace, token.Gap, token.Par, token.Ser, token.Col, token.Tis, token.Com, token.Comment, token.EOF, token.HepHep, token.TisTis:
+		return false
+	}
+	return true
+}
+
+// consNodes returns the right-nested cell of ns, e.g. [a [b c]].
+func (p *Parser) consNodes(ns []ast.Node) ast.Node {
+	if len(ns) == 1 {
+		return ns[0]
+	}
+	return p.finishSpan(ast.Cell{Head: ns[0], Tail: p.consNodes(ns[1:])}, ns[0], ns[len(ns)-1])
+}
+
+// finishSpan calls finish with n spanning from the start of first to the end
+// of last.
+func (p *Parser) finishSpan(n, first, last ast.Node) ast.Node {
+	if !p.concrete {
+		return n
+	}
+	return p.finish(n, ast.SyntaxOf(first).Pos, ast.SyntaxOf(last).End)
+}
+
+// parseWingExpr parses a wing, which may be followed by a parenthesized list
+// of changes, e.g. a.b(c 1, d 2).
+func (p *Parser) parseWingExpr(t token.Token, lit string, start int) ast.Node {
+	w := p.finishHere(p.parseWing(t, lit), start)
+	if f, ok := w.(ast.Face); ok && p.tok == token.Lus {
+		// term+value is [%term value], e.g. leaf+"text"
+		term := p.finishHere(ast.Literal{Tok: token.Cen, Lit: "%" + f.Name}, start)
+		p.next()
+		return ast.Cell{Tok: t, Head: term, Tail: p.parseUnaryExpr()}
+	}
+	if p.tok == token.Pal {
+		p.next()
+		return ast.Rune{
+			Tok:  t,
+			Lit:  "%=",
+			Args: append([]ast.Node{w}, p.consumeWideComma()...),
+		}
+	}
+	return w
+}
+
+// parseWing parses a wing whose first token, t, has already been consumed.
+func (p *Parser) parseWing(t token.Token, lit string) ast.Node {
+	w := ast.Wing{Tok: t}
+	core := false
+	if t == token.Dot {
+		if p.tok != token.Dot {
+			return w // the subject
+		}
+		p.next()
+		t, lit = p.tok, p.lit
+		p.next()
+		core = true
+	}
+	for {
+		l := p.parseLimb(t, lit)
+		if core {
+			if l.Name == "" || l.Skip != 0 {
+				p.errorf("expected arm name after .., got %q", l)
+			}
+			l.Core, core = true, false
+		}
+		w.Limbs = append(w.Limbs, l)
+		if p.tok != token.Dot {
+			break
+		}
+		p.next()
+		t, lit = p.tok, p.lit
+		p.next()
+	}
+	if len(w.Limbs) == 1 {
+		switch l := w.Limbs[0]; {
+		case l.Name == "$" && l.Skip == 0 && !l.Core:
+			return ast.Buc{Tok: w.Tok}
+		case l.Name != "" && l.Skip == 0 && !l.Core:
+			return ast.Face{Tok: w.Tok, Name: l.Name}
+		}
+	}
+	return w
+}
+
+// parseLimb parses a limb whose first token, t, has already been consumed.
+func (p *Parser) parseLimb(t token.Token, lit string) ast.Limb {
+	switch t {
+	case token.Face:
+		return ast.Limb{Name: lit}
+	case token.Buc:
+		return ast.Limb{Name: "$"}
+	case token.Ket:
+		skip := 1
+		for p.tok == token.Ket {
+			skip++
+			p.next()
+		}
+		l := p.parseLimb(p.tok, p.lit)
+		if l.Name == "" || l.Skip != 0 {
+			p.errorf("expected face after ^, got %q", l)
+		}
+		p.next()
+		l.Skip = skip
+		return l
+	case token.Com:
+		return ast.Limb{Lit: ","}
+	case token.Lus, token.Pam, token.Bar:
+		if p.tok == token.Num {
+			n := p.lit
+			p.next()
+			return ast.Limb{Axis: p.axis(t, n), Lit: lit + n}
+		} else if t != token.Lus {
+			p.errorf("expected number after %v, got %q", lit, p.tok)
+		}
+		fallthrough
+	case token.Hep:
+		// alternating -/+ and </>, e.g. -, +<, ->-
+		sb := []byte(lit)
+		for {
+			want := []token.Token{token.Gal, token.Gar}
+			if sb[len(sb)-1] == '<' || sb[len(sb)-1] == '>' {
+				want = []token.Token{token.Hep, token.Lus}
+			}
+			if p.tok != want[0] && p.tok != want[1] {
+				break
+			}
+			sb = append(sb, p.lit...)
+			p.next()
+		}
+		a := big.NewInt(1)
+		for _, c := range sb {
+			a.Lsh(a, 1)
+			if c == '+' || c == '>' {
+				a.SetBit(a, 0, 1)
+			}
+		}
+		return ast.Limb{Axis: a.String(), Lit: string(sb)}
+	default:
+		p.errorf("expected limb, got %q", lit)
+	}
+	return ast.Limb{}
+}
+
+// axis computes the axis denoted by +n, &n, or |n. &n is the nth element of
+// a list, and |n is the nth tail.
+func (p *Parser) axis(t token.Token, n string) string {
+	a, ok := new(big.Int).SetString(n, 10)
+	if !ok || (n[0] == '0' && n != "0") {
+		p.errorf("invalid axis %q", n)
+	}
+	if t == token.Lus {
+		return a.String()
+	}
+	// &0 is 0, &n is 2*(&(n-1)+1); |0 is 1, |n is 2*|(n-1)+1
+	r := big.NewInt(0)
+	if t == token.Bar {
+		r.SetInt64(1)
+	}
+	for i := int64(0); i < a.Int64(); i++ {
+		if t == token.Pam {
+			r.Add(r, big.NewInt(1)).Lsh(r, 1)
+		} else {
+			r.Lsh(r, 1).Add(r, big.NewInt(1))
+		}
+	}
+	return r.String()
 }
 
 func (p *Parser) parseRune(tok token.Token, lit string) ast.Node {
+	if _, ok := armTab[lit]; ok {
+		p.errorf("arm %v outside of core", lit)
+	}
 	e, ok := runeTab[lit]
 	if !ok {
-		panic("unhandled rune")
+		p.errorf("unhandled rune %v", lit)
 	}
 	n := ast.Rune{
 		Tok: tok,
 		Lit: lit,
 	}
-	if p.tok == token.Pal {
+	if e.args == 0 && !e.jogging && !e.arms {
+		return n // e.g. !!
+	} else if p.tok == token.Pal {
 		p.next()
-		n.Args = p.consumeWide()
-	} else {
-		n.Args = make([]ast.Node, e.args)
-		for i := range n.Args {
-			p.expect(token.Gap)
-			n.Args[i] = p.parseExpr()
+		n.Args = p.consumeWideRune(lit, e)
+		return n
+	}
+	for i := 0; i < e.args; i++ {
+		p.expect(token.Gap)
+		n.Args = append(n.Args, p.parseExpr())
+	}
+	if e.jogging {
+		jog := p.consumeTall(token.TisTis)
+		if e.pairs && len(jog)%2 != 0 {
+			p.errorf("%v requires pairs of arguments", lit)
 		}
+		n.Args = append(n.Args, jog...)
+	}
+	for i := 0; i < e.post; i++ {
+		p.expect(token.Gap)
+		n.Args = append(n.Args, p.parseExpr())
 	}
-	if hasArms(lit) {
-		n.Args = append(n.Args, p.consumeTall(token.HepHep)...)
-	} else if e.jogging {
-		n.Args = append(n.Args, p.consumeTall(token.TisTis)...)
+	if e.arms {
+		n.Args = append(n.Args, p.consumeArms()...)
 	}
 	return n
 }
 
-func (p *Parser) parseCons() ast.Node {
-	e := p.parseExpr()
-	if p.tok == token.Ser {
-		return e
+// consumeWideRune parses the arguments of a rune in wide form, after the
+// opening parenthesis.
+func (p *Parser) consumeWideRune(lit string, e runeEntry) []ast.Node {
+	if e.arms || e.post > 0 {
+		p.errorf("%v has no wide form", lit)
+	}
+	var args []ast.Node
+	if e.pairs {
+		for i := 0; i < e.args; i++ {
+			args = append(args, p.parseExpr())
+			p.expect(token.Ace)
+		}
+		return append(args, p.consumeWideComma()...)
 	}
-	p.expect(token.Ace)
-	return ast.Cell{
-		Tok:  p.tok,
-		Head: e,
-		Tail: p.parseCons(),
+	args = p.consumeWide()
+	if len(args) < e.args || (!e.jogging && len(args) != e.args) {
+		p.errorf("%v takes %v arguments, got %v", lit, e.args, len(args))
+	}
+	return args
+}
+
+// consumeArms parses the arms of a core, up to and including the
+// terminating --.
+func (p *Parser) consumeArms() []ast.Node {
+	var arms []ast.Node
+	p.expect(token.Gap)
+	for p.tok != token.HepHep {
+		t, lit, start := p.tok, p.lit, p.pos
+		if !isArm(t, lit) {
+			p.errorf("expected arm, got %q", lit)
+		}
+		e := armTab[lit]
+		p.next()
+		arm := ast.Rune{Tok: t, Lit: lit}
+		for i := 0; i < e.args; i++ {
+			p.expect(token.Gap)
+			arm.Args = append(arm.Args, p.parseExpr())
+		}
+		// +* aliases continue until the next arm
+		for e.jogging && !p.atArm() {
+			p.expect(token.Gap)
+			arm.Args = append(arm.Args, p.parseExpr())
+			p.expect(token.Gap)
+			arm.Args = append(arm.Args, p.parseExpr())
+		}
+		arms = append(arms, p.finishHere(arm, start))
+		p.expect(token.Gap)
+	}
+	p.next()
+	return arms
+}
+
+// atArm reports whether the current token is a gap followed by an arm or the
+// end of a core.
+func (p *Parser) atArm() bool {
+	return p.tok == token.Gap && p.pend && (p.pendTok == token.HepHep || isArm(p.pendTok, p.pendLit))
+}
+
+// parseLiteral parses an atom literal, the first part of which, prefix, has
+// already been consumed.
+func (p *Parser) parseLiteral(tok token.Token, prefix string) ast.Node {
+	lit := prefix
+	for {
+		switch p.tok {
+		case token.Face, token.Num, token.Dot, token.Hep, token.HepHep, token.Sig:
+			lit += p.lit
+			p.next()
+			continue
+		}
+		break
+	}
+	if _, err := atom.Parse(lit); err != nil {
+		p.errorf("invalid literal %q: %v", lit, err)
+	}
+	return ast.Literal{Tok: tok, Lit: lit}
+}
+
+// consumeSeq parses the space-separated elements of a bracketed sequence, up
+// to and including the closing bracket.
+func (p *Parser) consumeSeq() []ast.Node {
+	var nodes []ast.Node
+	for {
+		nodes = append(nodes, p.parseExpr())
+		if p.tok == token.Ser {
+			p.next()
+			return nodes
+		}
+		p.expect(token.Ace)
 	}
 }
 
@@ -363,3 +1109,12 @@ func New(s *scanner.Scanner) *Parser {
 	p.next()
 	return p
 }
+
+// NewConcrete returns a Parser that attaches concrete syntax to every node
+// it produces, so that the source can be reproduced exactly with
+// ast.PrintConcrete.
+func NewConcrete(s *scanner.Scanner) *Parser {
+	p := New(s)
+	p.concrete = true
+	return p
+}
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -68,10 +71,10 @@ func TestRoundTrip(t *testing.T) {
                    ++  g  |=  b=@
                           (add b n)
                    --`,
-			exp: `=/(x 58 |%(++(n (add 42 x)) ++(g |=(b=@ (add b n)))))`,
+			exp: `=/  x  58  |%  ++  n  (add 42 x)  ++  g  |=(b=@ (add b n))  --`,
 		},
 	}
-	for _, test := range tests[len(tests)-1:] {
+	for _, test := range tests {
 		var sb strings.Builder
 		ast.Print(&sb, New(scanner.New([]byte(test.prog))).Parse())
 		if got := sb.String(); got != test.exp {
@@ -79,3 +82,432 @@ func TestRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestWing(t *testing.T) {
+	var tests = []struct {
+		prog  string
+		axes  []string
+		names []string
+	}{
+		{prog: `a.b.c`, names: []string{"a", "b", "c"}},
+		{prog: `+<`, axes: []string{"6"}},
+		{prog: `-`, axes: []string{"2"}},
+		{prog: `+`, axes: []string{"3"}},
+		{prog: `->-`, axes: []string{"10"}},
+		{prog: `-.foo`, axes: []string{"2", ""}, names: []string{"", "foo"}},
+		{prog: `+3`, axes: []string{"3"}},
+		{prog: `&1`, axes: []string{"2"}},
+		{prog: `&2`, axes: []string{"6"}},
+		{prog: `&3`, axes: []string{"14"}},
+		{prog: `|0`, axes: []string{"1"}},
+		{prog: `|1`, axes: []string{"3"}},
+		{prog: `|2`, axes: []string{"7"}},
+		{prog: `+3.a`, axes: []string{"3", ""}, names: []string{"", "a"}},
+		{prog: `+6.a`, axes: []string{"6", ""}, names: []string{"", "a"}},
+		{prog: `&2.a`, axes: []string{"6", ""}, names: []string{"", "a"}},
+		{prog: `|1.a`, axes: []string{"3", ""}, names: []string{"", "a"}},
+		{prog: `-.+3.a`, axes: []string{"2", "3", ""}, names: []string{"", "", "a"}},
+		{prog: `a.+3.b`, axes: []string{"", "3", ""}, names: []string{"a", "", "b"}},
+		{prog: `^face`, names: []string{"face"}},
+		{prog: `^^face.b`, names: []string{"face", "b"}},
+		{prog: `..arm`, names: []string{"arm"}},
+		{prog: `+<.$`, axes: []string{"6", ""}, names: []string{"", "$"}},
+		{prog: `,.a`, names: []string{"", "a"}},
+		{prog: `.`},
+	}
+	for _, test := range tests {
+		n := New(scanner.New([]byte(test.prog))).Parse()
+		w, ok := n.(ast.Wing)
+		if !ok {
+			t.Errorf("%v: expected wing, got %T", test.prog, n)
+			continue
+		}
+		var sb strings.Builder
+		ast.Print(&sb, w)
+		if sb.String() != test.prog {
+			t.Errorf("%v: bad round trip: %v", test.prog, sb.String())
+		}
+		for i, l := range w.Limbs {
+			if i < len(test.axes) && l.Axis != test.axes[i] {
+				t.Errorf("%v: limb %v: expected axis %v, got %v", test.prog, i, test.axes[i], l.Axis)
+			}
+			if i < len(test.names) && l.Name != test.names[i] {
+				t.Errorf("%v: limb %v: expected name %v, got %v", test.prog, i, test.names[i], l.Name)
+			}
+		}
+	}
+
+	// single faces and $ are not wings
+	if _, ok := New(scanner.New([]byte(`foo`))).Parse().(ast.Face); !ok {
+		t.Error("expected face")
+	} else if _, ok := New(scanner.New([]byte(`$`))).Parse().(ast.Buc); !ok {
+		t.Error("expected buc")
+	}
+
+	// wings in larger expressions
+	for _, test := range []struct{ prog, exp string }{
+		{`(add a.b +<)`, `(add a.b +<)`},
+		{`[-.foo +.foo]`, `[-.foo +.foo]`},
+		{`a.b(c 1)`, `%=(a.b c 1)`},
+		{`..arm(a 1)`, `%=(..arm a 1)`},
+		{`=/(a 1 +<:a)`, `=/(a 1 =<(+< a))`},
+		{`[+3.a 1.000]`, `[+3.a 1.000]`},
+	} {
+		var sb strings.Builder
+		ast.Print(&sb, New(scanner.New([]byte(test.prog))).Parse())
+		if sb.String() != test.exp {
+			t.Errorf("%v: expected %v, got %v", test.prog, test.exp, sb.String())
+		}
+	}
+}
+
+func TestRunes(t *testing.T) {
+	var tests = []struct {
+		prog string
+		exp  string
+	}{
+		// dot
+		{".^  @  a", ".^(@ a)"},
+		{".+  a", ".+(a)"},
+		{".*  a  b", ".*(a b)"},
+		{".=  a  b", ".=(a b)"},
+		{".?  a", ".?(a)"},
+		// zap
+		{"!>  a", "!>(a)"},
+		{"!<  @  a", "!<(@ a)"},
+		{"!:  a", "!:(a)"},
+		{"!.  a", "!.(a)"},
+		{"!=  a", "!=(a)"},
+		{"!?  144  a", "!?(144 a)"},
+		{"!!", "!!"},
+		{"!,  *hoon  a", "!,(^*(hoon) a)"},
+		{"!@  a  b  c", "!@(a b c)"},
+		// tis
+		{"=+  a  b", "=+(a b)"},
+		{"=-  a  b", "=-(a b)"},
+		{"=|  @  a", "=|(@ a)"},
+		{"=/  a  1  a", "=/(a 1 a)"},
+		{"=;  a=@  a  1", "=;(a=@ a 1)"},
+		{"=.  a  1  a", "=.(a 1 a)"},
+		{"=:  a  1\n  b  2\n==\n[a b]", "=:  a  1  b  2  ==  [a b]"},
+		{"=?  a  b  1  a", "=?(a b 1 a)"},
+		{"=*  a  b  a", "=*(a b a)"},
+		{"=>  a  b", "=>(a b)"},
+		{"=<  a  b", "=<(a b)"},
+		{"=~  a\n  b\n  c\n==", "=~(a b c)"},
+		{"=,  a  b", "=,(a b)"},
+		{"=^  a  b  c  d", "=^(a b c d)"},
+		// wut
+		{"?>  a  b", "?>(a b)"},
+		{"?<  a  b", "?<(a b)"},
+		{"?|  a\n  b\n==", "?|(a b)"},
+		{"?&  a\n  b\n==", "?&(a b)"},
+		{"?!  a", "?!(a)"},
+		{"?=  @  a", "?=(@ a)"},
+		{"?:  a  b  c", "?:(a b c)"},
+		{"?.  a  b  c", "?.(a b c)"},
+		{"?@  a  b  c", "?@(a b c)"},
+		{"?^  a  b  c", "?^(a b c)"},
+		{"?~  a  b  c", "?~(a b c)"},
+		{"?-  a\n  %b  1\n  %c  2\n==", "?-(a %b 1, %c 2)"},
+		{"?+  a  0\n  %b  1\n==", "?+(a 0 %b 1)"},
+		// bar
+		{"|_  a=@\n++  b  a\n--", "|_  a=@  ++  b  a  --"},
+		{"|%\n++  a  1\n+$  b  @\n+|  %c\n++  d  2\n--", "|%  ++  a  1  +$  b  @  +|  %c  ++  d  2  --"},
+		{"|:  a  b", "|:(a b)"},
+		{"|.  a", "|.(a)"},
+		{"|-  a", "|-(a)"},
+		{"|?  a", "|?(a)"},
+		{"|^  a\n++  a  1\n--", "|^  a  ++  a  1  --"},
+		{"|~  @  a", "|~(@ a)"},
+		{"|=  a=@  a", "|=(a=@ a)"},
+		{"|*  a=@  a", "|*(a=@ a)"},
+		{"|@\n++  a  1\n--", "|@  ++  a  1  --"},
+		{"|$  a  b", "|$(a b)"},
+		{"|_  a=@\n+*  b  a\n    c  .\n++  d  b\n--", "|_  a=@  +*  b  a  c  .  ++  d  b  --"},
+		// col
+		{":-  a  b", ":-(a b)"},
+		{":_  a  b", ":_(a b)"},
+		{":+  a  b  c", ":+(a b c)"},
+		{":^  a  b  c  d", ":^(a b c d)"},
+		{":*  a\n  b\n==", ":*(a b)"},
+		{":~  a\n  b\n==", ":~(a b)"},
+		// cen
+		{"%~  a  b  c", "%~(a b c)"},
+		{"%-  a  b", "(a b)"},
+		{"%.  a  b", "%.(a b)"},
+		{"%+  a  b  c", "%+(a b c)"},
+		{"%^  a  b  c  d", "%^(a b c d)"},
+		{"%:  a\n  b\n  c\n==", "%:(a b c)"},
+		{"%=  a\n  b  1\n==", "%=(a b 1)"},
+		{"%_  a\n  b  1\n  c  2\n==", "%_(a b 1, c 2)"},
+		{"%*  a  b\n  c  1\n==", "%*(a b c 1)"},
+		// ket
+		{"^|  a", "^|(a)"},
+		{"^&  a", "^&(a)"},
+		{"^?  a", "^?(a)"},
+		{"^:  a", "^:(a)"},
+		{"^.  a  b", "^.(a b)"},
+		{"^-  @  a", "^-(@ a)"},
+		{"^+  a  b", "^+(a b)"},
+		{"^~  a", "^~(a)"},
+		{"^*  @", "^*(@)"},
+		{"^=  a  b", "^=(a b)"},
+		// buc
+		{"$_  a", "$_(a)"},
+		{"$%  [%a @]\n  [%b ~]\n==", "$%([%a @] [%b ~])"},
+		{"$:  a=@\n  b=@\n==", "$:(a=@ b=@)"},
+		{"$?  %a\n  %b\n==", "$?(%a %b)"},
+		{"$<  %a  b", "$<(%a b)"},
+		{"$>  %a  b", "$>(%a b)"},
+		{"$-  @  @", "$-(@ @)"},
+		{"$@  @  *", "$@(@ *)"},
+		{"$^  a  b", "$^(a b)"},
+		{"$~  0  @", "$~(0 @)"},
+		{"$=  a  @", "$=(a @)"},
+		{"$;  a", "$;(a)"},
+		{"$&  a  b", "$&(a b)"},
+		{"$|  @  a", "$|(@ a)"},
+		// mic
+		{";:  a\n  b\n  c\n==", ";:(a b c)"},
+		{";+  a", ";+(a)"},
+		{";/  a", ";/(a)"},
+		{";*  a", ";*(a)"},
+		{";=  a\n  b\n==", ";=(a b)"},
+		{";;  @  a", ";;(@ a)"},
+		{";~  a\n  b\n  c\n==", ";~(a b c)"},
+		{";<  a=@  b  c  d", ";<(a=@ b c d)"},
+		// sig
+		{"~>  %a  b", "~>(%a b)"},
+		{"~|  'error'  a", "~|('error' a)"},
+		{"~_  a  b", "~_(a b)"},
+		{"~$  %a  b", "~$(%a b)"},
+		{"~%  %a  ..b  ~  c", "~%(%a ..b ~ c)"},
+		{"~<  %a  b", "~<(%a b)"},
+		{"~+  a", "~+(a)"},
+		{"~/  %a  b", "~/(%a b)"},
+		{"~&  \"a\"  b", `~&("a" b)`},
+		{"~?  a  b  c", "~?(a b c)"},
+		{"~!  a  b", "~!(a b)"},
+		{"~=  a  b", "~=(a b)"},
+	}
+	for _, test := range tests {
+		var sb strings.Builder
+		ast.Print(&sb, New(scanner.New([]byte(test.prog))).Parse())
+		if got := sb.String(); got != test.exp {
+			t.Errorf("%q: expected %v, got %v", test.prog, test.exp, got)
+		}
+		// printed form should round-trip
+		sb.Reset()
+		ast.Print(&sb, New(scanner.New([]byte(test.exp))).Parse())
+		if got := sb.String(); got != test.exp {
+			t.Errorf("%q: bad wide round trip: %v", test.exp, got)
+		}
+	}
+}
+
+func TestIrregular(t *testing.T) {
+	var tests = []struct {
+		prog string
+		exp  string
+	}{
+		{`[a b c]`, `[a [b c]]`},
+		{`[a [b c]]~`, `[[a [b c]] ~]`},
+		{`~[a [b c]]`, `:~(a [b c])`},
+		{`~`, `~`},
+		{`(f a b)`, `(f a b)`},
+		{`+(a)`, `.+(a)`},
+		{`=(a b)`, `.=(a b)`},
+		{`?(%a %b)`, `$?(%a %b)`},
+		{`!a`, `?!(a)`},
+		{`&(a b)`, `?&(a b)`},
+		{`|(a b)`, `?|(a b)`},
+		{"`a", `[~ a]`},
+		{"`@ux`a", `^-(@ux a)`},
+		{`~(arm core arg)`, `%~(arm core arg)`},
+		{`~(arm core a b)`, `%~(arm core [a b])`},
+		{`~(arm core)`, `=<(arm core)`},
+		{`:(add a b c)`, `;:(add a b c)`},
+		{`*@ud`, `^*(@ud)`},
+		{`a=*`, `a=*`},
+		{`a=?`, `a=?`},
+		{`a=~`, `a=~`},
+		{`a=b:c`, `a==<(b c)`},
+		{`[=a =b:c]`, `[a=a b==<(b c)]`},
+		{`leaf+"text"`, `[%leaf "text"]`},
+		{`_a`, `$_(a)`},
+		{`,[a b]`, `^:([a b])`},
+		{`?=(^ a)`, `?=(^ a)`},
+		{`~>(%mean.'need' !!)`, `~>([%mean 'need'] !!)`},
+		{`/foo/(scot %ud 1)/~.bar`, `/foo/(scot %ud 1)/~.bar`},
+		{`/`, `/`},
+		{`;p.big: some text {(trip a)}`, `;p(class "big"): some text {(trip a)}`},
+		{`"a {"b"} c"`, `"a {"b"} c"`},
+		{`a:b`, `=<(a b)`},
+		{`a^b`, `[a b]`},
+		{`a^b^c`, `[a [b c]]`},
+		{`[%foo a^b]`, `[%foo [a b]]`},
+		{`a=b^c`, `a=[b c]`},
+		{`a=^+(b c)`, `a=^+(b c)`},
+		{`a=@F`, `a=@F`},
+		{`a^b:c`, `=<([a b] c)`},
+		{`a(b 1)`, `%=(a b 1)`},
+		{`[%foo %.y %.n %$ %& %| & |]`, `[%foo [%.y [%.n [%$ [%& [%| [& |]]]]]]]`},
+		{`[~zod ~2020.1.1 ~.knot 0x10 --5 -5 .1.5 .~zod]`, `[~zod [~2020.1.1 [~.knot [0x10 [--5 [-5 [.1.5 .~zod]]]]]]]`},
+		{`[@ @tD sha256]`, `[@ [@tD sha256]]`},
+		{`['cord' "tape"]`, `['cord' "tape"]`},
+	}
+	for _, test := range tests {
+		var sb strings.Builder
+		ast.Print(&sb, New(scanner.New([]byte(test.prog))).Parse())
+		if got := sb.String(); got != test.exp {
+			t.Errorf("%q: expected %v, got %v", test.prog, test.exp, got)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, prog := range []string{
+		`?-  a\n  %b\n==`,
+		`%=(a b)`,
+		`=/(a 1)`,
+		`.+(a b)`,
+		`++  a  1`,
+		`~bad-literal`,
+		`~(a)`,
+		`|%(++(a 1))`,
+		`/foo/`,
+		`;p:text`,
+		`=:(a 1 b)`,
+		`)`,
+		"=/  a\n",
+		`{a b}`,
+		``,
+	} {
+		if _, err := New(scanner.New([]byte(prog))).ParseFile(); err == nil {
+			t.Errorf("%q: expected parse error", prog)
+		} else if _, ok := err.(*Error); !ok {
+			t.Errorf("%q: expected *Error, got %T", prog, err)
+		}
+	}
+
+	_, err := New(scanner.New([]byte("=/  a  1\n[a )"))).ParseFile()
+	if e, ok := err.(*Error); !ok || e.Line != 2 || e.Col != 4 {
+		t.Errorf("expected error at 2:4, got %v", err)
+	}
+}
+
+// TestParseFiles parses every file in testdata. Most are hand-written
+// samples in the style of upstream code; TestParseArvo covers upstream
+// itself.
+func TestParseFiles(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.hoon"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(files) == 0 {
+		t.Fatal("no test files")
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := New(scanner.New(src)).ParseFile()
+		if err != nil {
+			t.Errorf("%v: %v", file, err)
+		} else if file == filepath.Join("testdata", "app", "counter.hoon") && len(f.Ford) != 4 {
+			t.Errorf("%v: expected 4 Ford runes, got %v", file, len(f.Ford))
+		}
+	}
+}
+
+// TestParseArvo parses the upstream arms in testdata/arvo and, if
+// URBIT_ARVO is set to the pkg/arvo directory of an urbit checkout, the
+// kernel and the /app and /lib files of that checkout.
+func TestParseArvo(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "arvo", "*.hoon"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(files) == 0 {
+		t.Fatal("no test files")
+	}
+	if dir := os.Getenv("URBIT_ARVO"); dir != "" {
+		files = append(files,
+			filepath.Join(dir, "sys", "hoon.hoon"),
+			filepath.Join(dir, "sys", "arvo.hoon"),
+		)
+		for _, sub := range []string{"app", "lib"} {
+			fs, err := filepath.Glob(filepath.Join(dir, sub, "*.hoon"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, fs...)
+		}
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(scanner.New(src)).ParseFile(); err != nil {
+			t.Errorf("%v: %v", file, err)
+		}
+	}
+}
+
+func TestConcrete(t *testing.T) {
+	srcs := []string{
+		`[1 2 3]`,
+		"::  leading comment\n=/  n  1  ::  trailing\n[. .]:n\n",
+		`=(a +(b))`,
+		"|=  [a=@ b=@]\n^-  @\n?:  =(a 0)\n  b\n$(a (dec a), b +(b))",
+		"%=  $\n  n    (dec n)   :: decrement\n  acc  (mul acc n)\n==",
+		"`a",
+		`[=bowl a=~ b=*]`,
+		`[a b]~`,
+		`~[a [b c] d]`,
+		`~(a b c d)`,
+		`leaf+"text"`,
+		`%mean.'need'`,
+		`[/foo/(scot %ud 1)/bar 1]`,
+		"|%\n+*  this  .\n    def  ~\n::\n++  foo\n  1\n::  comment\n+$  bar  @\n--",
+		";div.foo#bar(title \"x\")\n  ;p: hello\n==",
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.hoon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, string(src))
+	}
+	for _, src := range srcs {
+		f, err := NewConcrete(scanner.New([]byte(src))).ParseFile()
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		var sb strings.Builder
+		ast.PrintConcrete(&sb, f)
+		if sb.String() != src {
+			t.Errorf("bad concrete print:\nexp: %q\ngot: %q", src, sb.String())
+		}
+	}
+
+	// rewriting a node should preserve the formatting around it
+	src := "=/  n  1  ::  one\n[n n]"
+	f, err := NewConcrete(scanner.New([]byte(src))).ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := f.Body.(ast.Rune)
+	r.Args[1] = ast.Num{Tok: r.Args[1].(ast.Num).Tok, Int: "2"}
+	var sb strings.Builder
+	ast.PrintConcrete(&sb, f)
+	if exp := "=/  n  2  ::  one\n[n n]"; sb.String() != exp {
+		t.Errorf("bad rewrite:\nexp: %q\ngot: %q", exp, sb.String())
+	}
+}
@@ -22,7 +22,6 @@ func (s *Scanner) next() {
 	s.ch = rune(s.src[s.off])
 }
 
-// TODO: this is currently unused; is it necessary?
 func (s *Scanner) peek() rune {
 	if s.off+1 < len(s.src) {
 		return rune(s.src[s.off+1])
@@ -58,43 +57,152 @@ func isKebab(c rune) bool {
 	return c == '-' || ('a' <= c && c <= 'z')
 }
 
-func isNumber(c rune) bool {
-	return c == '.' || ('0' <= c && c <= '9')
+func isFaceEnd(c rune) bool {
+	return ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || ('A' <= c && c <= 'Z')
 }
 
+func isDigit(c rune) bool {
+	return '0' <= c && c <= '9'
+}
+
+// groupFollows reports whether the current character, a '.', is followed by
+// a group of three digits, as in 1.000. Otherwise, the dot is not part of the
+// number, e.g. in the wing +3.a.
+func (s *Scanner) groupFollows() bool {
+	if s.off+3 >= len(s.src) {
+		return false
+	}
+	for _, c := range s.src[s.off+1 : s.off+4] {
+		if !isDigit(rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanFace scans a face. After the first letter, faces may also contain
+// digits, and uppercase letters (which only appear in auras, e.g. @tD).
 func (s *Scanner) scanFace() (token.Token, string) {
 	var sb strings.Builder
-	for isKebab(s.ch) {
+	for isKebab(s.ch) || ('0' <= s.ch && s.ch <= '9') || ('A' <= s.ch && s.ch <= 'Z') {
 		sb.WriteRune(s.ch)
 		s.next()
 	}
 	return token.Face, sb.String()
 }
 
+func isBaseDigit(c rune) bool {
+	return c == '.' || c == '-' || c == '~' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
 func (s *Scanner) scanNumber() (token.Token, string) {
 	// TODO: this only parses very simple numbers
 	var sb strings.Builder
-	for isNumber(s.ch) {
+	if s.ch == '0' && strings.ContainsRune("xbvwc", s.peek()) {
+		// 0x, 0b, 0v, 0w, or 0c prefix
+		sb.WriteRune(s.ch)
+		s.next()
+		for isBaseDigit(s.ch) {
+			sb.WriteRune(s.ch)
+			s.next()
+		}
+		return token.Num, sb.String()
+	}
+	for isDigit(s.ch) || (s.ch == '.' && s.groupFollows()) {
 		sb.WriteRune(s.ch)
 		s.next()
 	}
 	return token.Num, sb.String()
 }
 
+// scanString scans a quoted literal, returning it verbatim (including the
+// quotes). A backslash escapes the following character. Tapes may contain
+// interpolated expressions in braces, which may themselves contain strings.
+func (s *Scanner) scanString(tok token.Token, quote rune) (token.Token, string) {
+	start := s.off - 1
+	if s.ch == quote && s.peek() == quote {
+		return s.scanBlock(tok, quote)
+	}
+	depth := 0
+	for s.ch != quote || depth > 0 {
+		switch {
+		case s.ch == -1:
+			return token.ILLEGAL, string(s.src[start:s.off])
+		case s.ch == '\\':
+			s.next()
+			if s.ch == -1 {
+				continue
+			}
+		case tok == token.Tape && s.ch == '{':
+			depth++
+		case depth > 0 && s.ch == '}':
+			depth--
+		case depth > 0 && (s.ch == '"' || s.ch == '\''):
+			q := s.ch
+			s.next()
+			if t, _ := s.scanString(stringTokens[q], q); t == token.ILLEGAL {
+				return token.ILLEGAL, string(s.src[start:s.off])
+			}
+			continue
+		}
+		s.next()
+	}
+	s.next()
+	return tok, string(s.src[start:s.off])
+}
+
+var stringTokens = map[rune]token.Token{'\'': token.Cord, '"': token.Tape}
+
+// scanBlock scans a multiline cord or tape, delimited by triple quotes, whose
+// first quote has already been consumed. The block is terminated by a line
+// containing only the triple quote, and is returned verbatim.
+func (s *Scanner) scanBlock(tok token.Token, quote rune) (token.Token, string) {
+	start := s.off - 1
+	s.next()
+	s.next()
+	for s.ch != -1 {
+		if s.ch != '\n' {
+			s.next()
+			continue
+		}
+		s.next()
+		for s.ch == ' ' {
+			s.next()
+		}
+		if strings.HasPrefix(string(s.src[s.off:]), strings.Repeat(string(quote), 3)) {
+			s.next()
+			s.next()
+			s.next()
+			return tok, string(s.src[start:s.off])
+		}
+	}
+	return token.ILLEGAL, string(s.src[start:s.off])
+}
+
+// ScanLine returns the remainder of the current line verbatim, excluding the
+// newline. It is used for Sail text, which is not tokenized.
+func (s *Scanner) ScanLine() string {
+	start := s.off
+	for s.ch != '\n' && s.ch != -1 {
+		s.next()
+	}
+	return string(s.src[start:s.off])
+}
+
 var runeTab = func() map[int32]string {
 	m := make(map[int32]string)
 	for _, r := range strings.Fields(`
 	.^ .+ .* .= .?
-	!> !< !: !. != !? !!
+	!> !< !: !. != !? !! !, !@
 	=+ =- =| =/ =; =. =: =? =* => =< =~ =, =^
 	?> ?< ?| ?& ?! ?= ?: ?. ?@ ?^ ?~ ?- ?+
-	|_ |% |: |. |- |? |^ |~ |= |* |@
+	|_ |% |: |. |- |? |^ |~ |= |* |@ |$
 	++ +$ +* +|
 	:- :_ :+ :^ :* :~
 	%~ %- %. %+ %^ %: %= %_ %*
 	^| ^& ^? ^: ^. ^- ^+ ^~ ^* ^=
-	$_ $% $: $? $< $> $- $@ $^ $~ $=
-	;: ;+ ;/ ;* ;= ;; ;~
+	$_ $% $: $? $< $> $- $@ $^ $~ $= $; $& $| $+
+	;: ;+ ;/ ;* ;= ;; ;~ ;<
 	~> ~| ~_ ~$ ~% ~< ~+ ~/ ~& ~? ~! ~=
 	`) {
 		key := (int32(r[0]) << 16) | int32(r[1])
@@ -152,21 +260,40 @@ func (s *Scanner) Scan() (token.Token, string) {
 		return s.scanWhitespace()
 	case 'a' <= c && c <= 'z':
 		return s.scanFace()
+	case 'A' <= c && c <= 'Z' && s.off > 0 && s.src[s.off-1] == '@':
+		// an aura that is only a size, e.g. @F
+		return s.scanFace()
 	case '0' <= c && c <= '9':
 		return s.scanNumber()
 	case c == -1:
 		return token.EOF, ""
 	default:
 		s.next() // always make progress
+		if c == '\'' {
+			return s.scanString(token.Cord, c)
+		} else if c == '"' {
+			return s.scanString(token.Tape, c)
+		}
 		if isComment(c, s.ch) {
 			s.next()
 			return s.scanComment()
 		}
 		if tok, lit := isRune(c, s.ch); lit != "" {
+			if n := s.peek(); c == '.' && s.ch == '+' && ('0' <= n && n <= '9' || n == '<' || n == '>') {
+				// not a rune, e.g. a.+6 is the wing a then +6
+				return isSingleCharToken(c)
+			} else if c == '=' && s.off >= 2 && isFaceEnd(rune(s.src[s.off-2])) {
+				// not a rune, e.g. a=^+(b c) is a=(^+(b c))
+				return isSingleCharToken(c)
+			}
 			s.next()
 			return tok, lit
 		}
 		if tok, lit := isTerminator(c, s.ch); lit != "" {
+			if _, r := isRune(s.ch, s.peek()); r != "" {
+				// not a terminator, e.g. a==>(b c) is a=(=>(b c))
+				return isSingleCharToken(c)
+			}
 			s.next()
 			return tok, lit
 		}
@@ -177,9 +304,23 @@ func (s *Scanner) Scan() (token.Token, string) {
 	}
 }
 
+// Offset returns the byte offset of the next character to be scanned.
+func (s *Scanner) Offset() int {
+	return s.off
+}
+
+// Source returns the source being scanned.
+func (s *Scanner) Source() []byte {
+	return s.src
+}
+
 func New(src []byte) *Scanner {
-	return &Scanner{
+	s := &Scanner{
 		src: src,
-		ch:  rune(src[0]),
+		ch:  -1,
+	}
+	if len(src) > 0 {
+		s.ch = rune(src[0])
 	}
+	return s
 }
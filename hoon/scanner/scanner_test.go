@@ -42,6 +42,42 @@ func TestScan(t *testing.T) {
 				TisTis,
 			},
 		},
+		{
+			hoon: `[0x1.0000 'it\'s' "a \"tape\"" ~zod]`,
+			exp:  []Token{Sel, Num, Ace, Cord, Ace, Tape, Ace, Sig, Face, Ser},
+		},
+		{
+			hoon: `[+3.a 1.000 1.00]`,
+			exp:  []Token{Sel, Lus, Num, Dot, Face, Ace, Num, Ace, Num, Dot, Num, Ser},
+		},
+		{
+			hoon: `(sha256 a=@tD)`,
+			exp:  []Token{Pal, Face, Ace, Face, Tis, Pat, Face, Par},
+		},
+		{
+			hoon: `[a=@F b=@uxF]`,
+			exp:  []Token{Sel, Face, Tis, Pat, Face, Ace, Face, Tis, Pat, Face, Ser},
+		},
+		{
+			hoon: "'''\n  a 'quoted'\n  block\n  '''  \"a {\"b\"} c\"",
+			exp:  []Token{Cord, Gap, Tape},
+		},
+		{
+			hoon: `a==>(b c)`,
+			exp:  []Token{Face, Tis, Rune, Pal, Face, Ace, Face, Par},
+		},
+		{
+			hoon: `a=^+(b c)`,
+			exp:  []Token{Face, Tis, Rune, Pal, Face, Ace, Face, Par},
+		},
+		{
+			hoon: `,.+6`,
+			exp:  []Token{Com, Dot, Lus, Num},
+		},
+		{
+			hoon: `'unterminated`,
+			exp:  []Token{ILLEGAL},
+		},
 	}
 	for _, test := range tests {
 		var ts []Token
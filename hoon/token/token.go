@@ -49,6 +49,8 @@ const (
 	Rune
 	Face
 	Num
+	Cord
+	Tape
 )
 
 var tokens = [...]string{
@@ -96,6 +98,8 @@ var tokens = [...]string{
 	Rune:   "RUNE",
 	Face:   "FACE",
 	Num:    "NUM",
+	Cord:   "CORD",
+	Tape:   "TAPE",
 }
 
 func (t Token) String() string {
@@ -0,0 +1,670 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+	"lukechampine.com/urbit/noun"
+)
+
+// Infer returns the type of n, evaluated against a subject of type sub. It
+// checks n along the way: every cast, call, and edit must nest, and every
+// wing must resolve. Errors are positioned at the innermost expression with
+// concrete syntax, if any.
+func Infer(sub Type, n ast.Node) (t Type, err error) {
+	c := &checker{pos: -1}
+	defer c.recover(&err)
+	return c.infer(sub, n), nil
+}
+
+// Mold returns the type described by the mold spec, such as @ud, [a=@ b=@],
+// or (list @), resolved against a subject of type sub.
+func Mold(sub Type, spec ast.Node) (t Type, err error) {
+	c := &checker{pos: -1}
+	defer c.recover(&err)
+	return c.mold(sub, spec), nil
+}
+
+// Check parses and checks the Hoon source file src against the Prelude,
+// returning the type of its body. Ford directives are ignored. If the file
+// does not check, the returned error is an *Error with its Line and Col set;
+// if it does not parse, the error is the parser's.
+func Check(src []byte) (Type, error) {
+	f, err := parser.NewConcrete(scanner.New(src)).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	t, err := Infer(Prelude(), f.Body)
+	locate(src, err)
+	return t, err
+}
+
+// locate sets the Line and Col of err, if it is an *Error within src.
+func locate(src []byte, err error) {
+	if e, ok := err.(*Error); ok && e.Pos >= 0 {
+		e.Line = 1 + bytes.Count(src[:e.Pos], []byte("\n"))
+		e.Col = 1 + utf8.RuneCount(src[bytes.LastIndexByte(src[:e.Pos], '\n')+1:e.Pos])
+	}
+}
+
+type checker struct {
+	pos int // offset of the innermost expression being checked
+}
+
+func (c *checker) errorf(format string, args ...interface{}) {
+	panic(&Error{Pos: c.pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) recover(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(*Error)
+		if !ok {
+			panic(r)
+		}
+		if e.Pos < 0 {
+			e.Pos = c.pos
+		}
+		*err = e
+	}
+}
+
+// at sets the position of errors to that of n, if it has concrete syntax,
+// and returns a function that restores the previous position.
+func (c *checker) at(n ast.Node) func() {
+	s := ast.SyntaxOf(n)
+	if s == nil {
+		return func() {}
+	}
+	old := c.pos
+	c.pos = s.Pos
+	return func() { c.pos = old }
+}
+
+func (c *checker) nest(want, have Type) {
+	if !(nester{}).nest(want, have) {
+		c.errorf("nest-fail: have %v, need %v", have, want)
+	}
+}
+
+func (c *checker) infer(sub Type, n ast.Node) Type {
+	defer c.at(n)()
+	switch n := n.(type) {
+	case ast.Num:
+		if t := c.literal(n.Int); t != (Atom{}) {
+			return t // e.g. 0x10
+		}
+		return Atom{Aura: "ud"}
+	case ast.Literal:
+		return c.literal(n.Lit)
+	case ast.Buc, ast.Face, ast.Wing:
+		r := c.wing(sub, n)
+		if r.arm != nil {
+			return c.armType(r.core, r.arm)
+		} else if _, ok := r.leg.(moldArg); ok {
+			c.errorf("mold %v used as a value", ast.Wing{Limbs: toLimbs(n)})
+		}
+		return r.leg
+	case ast.Cell:
+		return mkCell(c.infer(sub, n.Head), c.infer(sub, n.Tail))
+	case ast.Tis:
+		return mkFace(faceName(c, n.Left), c.infer(sub, n.Right))
+	case ast.Path:
+		var t Type = Atom{Aura: "n", Value: big.NewInt(0)}
+		for i := len(n.Segs) - 1; i >= 0; i-- {
+			var seg Type = Atom{Aura: "ta"}
+			if _, ok := n.Segs[i].(ast.Literal); !ok {
+				seg = c.infer(sub, n.Segs[i])
+			}
+			t = mkCell(seg, t)
+		}
+		return t
+	case ast.Sail:
+		return Noun{}
+	case ast.File:
+		return c.infer(sub, n.Body)
+	case ast.Rune:
+		return c.rune(sub, n)
+	case ast.Pat, ast.Tar, ast.Ket, ast.Wut:
+		return moldGate(c.mold(sub, n))
+	default:
+		panic(fmt.Sprintf("unknown node type %T", n))
+	}
+}
+
+func (c *checker) rune(sub Type, r ast.Rune) Type {
+	a := r.Args
+	switch r.Lit {
+	// subject
+	case "=>":
+		return c.infer(c.infer(sub, a[0]), a[1])
+	case "=,":
+		t := c.infer(sub, a[0])
+		if f, ok := t.(Face); ok {
+			t = f.Type
+		}
+		return c.infer(mkCell(t, sub), a[1])
+	case "=*":
+		return c.infer(mkCell(mkFace(faceName(c, a[0]), c.infer(sub, a[1])), sub), a[2])
+
+	// branches
+	case "?:":
+		c.nest(Flag, c.infer(sub, a[0]))
+		yes, no := c.branches(sub, a[0])
+		// a branch that cannot be taken is not checked
+		var yt, nt Type = Void{}, Void{}
+		if !isVoid(yes) {
+			yt = c.infer(yes, a[1])
+		}
+		if !isVoid(no) {
+			nt = c.infer(no, a[2])
+		}
+		return fork(yt, nt)
+	case "?=":
+		c.mold(sub, a[0])
+		c.infer(sub, a[1])
+		return Flag
+
+	// cores
+	case "|%", "|@":
+		return c.core(sub, a)
+	case "|$":
+		return Noun{}
+
+	// wing resolution
+	case "%=":
+		return c.edit(sub, a[0], a[1:])
+
+	// types
+	case "^+":
+		t := c.infer(sub, a[0])
+		c.nest(t, c.infer(sub, a[1]))
+		return t
+	case "^-":
+		t := c.mold(sub, a[0])
+		c.nest(t, c.infer(sub, a[1]))
+		return t
+	case "^*":
+		return c.mold(sub, a[0])
+	case "^|", "^&", "^?", "^~":
+		return c.infer(sub, a[0])
+	case "^:":
+		return moldGate(c.mold(sub, a[0]))
+
+	// nock
+	case ".+":
+		c.nest(Atom{}, c.infer(sub, a[0]))
+		return Atom{}
+	case ".=":
+		c.infer(sub, a[0])
+		c.infer(sub, a[1])
+		return Flag
+	case ".?":
+		c.infer(sub, a[0])
+		return Flag
+	case ".*":
+		c.infer(sub, a[0])
+		c.infer(sub, a[1])
+		return Noun{}
+	case ".^":
+		c.infer(sub, a[1])
+		return c.mold(sub, a[0])
+
+	// compiler directives
+	case "!!":
+		return Void{}
+	case "!>":
+		c.infer(sub, a[0])
+		return Cell{Head: Face{Name: "p", Type: Noun{}}, Tail: Face{Name: "q", Type: Noun{}}}
+	case "!<":
+		c.infer(sub, a[1])
+		return c.mold(sub, a[0])
+	case "!:", "!.", "!?", "!@":
+		return c.infer(sub, a[len(a)-1])
+	case "!=", "!,":
+		return Noun{}
+
+	// hints
+	case "~>", "~!":
+		return c.infer(sub, a[1])
+
+	// sail
+	case ";+", ";/", ";*", ";=":
+		return Noun{}
+	}
+
+	if strings.HasPrefix(r.Lit, "$") {
+		return moldGate(c.mold(sub, r))
+	}
+	n, err := r.Reduce()
+	if err != nil {
+		c.errorf("%v", err)
+	}
+	return c.infer(sub, n)
+}
+
+// branches returns the subjects of the branches of a ?: with the given test.
+// If the test is a ?= of a leg, the type of the leg is refined in each.
+func (c *checker) branches(sub Type, test ast.Node) (yes, no Type) {
+	yes, no = sub, sub
+	if t, ok := test.(ast.Rune); ok && t.Lit == "?=" && isWing(t.Args[1]) {
+		if r := c.wing(sub, t.Args[1]); r.arm == nil && r.path != nil {
+			m := c.mold(sub, t.Args[0])
+			yes = edit(sub, r.path, func(t Type) Type { return fuse(t, m) })
+			no = edit(sub, r.path, func(t Type) Type { return crop(t, m) })
+		}
+	}
+	return yes, no
+}
+
+// core returns the type of a core with the arms defined by arms, and the
+// payload sub, checking each arm.
+func (c *checker) core(sub Type, arms []ast.Node) *Core {
+	core := &Core{Payload: sub}
+	for _, arm := range arms {
+		ar, ok := arm.(ast.Rune)
+		if !ok || (ar.Lit != "++" && ar.Lit != "+$") {
+			continue // chapters and aliases
+		}
+		core.Arms = append(core.Arms, &Arm{
+			Name: faceName(c, ar.Args[0]),
+			Body: ar.Args[1],
+			Mold: ar.Lit == "+$",
+			home: core,
+		})
+	}
+	for _, arm := range core.Arms {
+		if arm.Mold {
+			c.armMold(core, arm)
+		} else {
+			c.armType(core, arm)
+		}
+	}
+	return core
+}
+
+// edit returns the type of %=(target edits...): the type of target, whose
+// legs are replaced by edits. If target is an arm, the legs are those of its
+// core, and the result is the arm's product. As with Hoon's dry cores, each
+// new value must nest in the leg it replaces, as the core was defined.
+func (c *checker) edit(sub Type, target ast.Node, edits []ast.Node) Type {
+	r := c.wing(sub, target)
+	base := r.leg
+	if r.arm != nil {
+		base = r.core
+		if r.arm.home != nil {
+			base = r.arm.home
+		}
+	}
+	for i := 0; i+1 < len(edits); i += 2 {
+		func() {
+			defer c.at(edits[i])()
+			er := c.wing(base, edits[i])
+			if er.arm != nil {
+				c.errorf("cannot edit arm %v", er.arm.Name)
+			}
+			c.nest(er.leg, c.infer(sub, edits[i+1]))
+		}()
+	}
+	if r.arm != nil {
+		return c.armType(r.core, r.arm)
+	}
+	return base
+}
+
+// armType returns the product type of arm, an arm of core, checking its body
+// the first time it is needed. An arm whose body is a cast has the type of
+// the cast even while its body is being checked, so it may call itself;
+// other arms may not.
+func (c *checker) armType(core *Core, arm *Arm) Type {
+	if arm.Type != nil {
+		return arm.Type
+	} else if arm.Mold {
+		return moldGate(c.armMold(core, arm))
+	} else if b, ok := arm.Body.(ast.Rune); ok && b.Lit == "|$" {
+		return Noun{}
+	} else if arm.busy {
+		c.errorf("arm %v is recursive, and needs a ^- cast", arm.Name)
+	}
+	if b, ok := arm.Body.(ast.Rune); ok && b.Lit == "^-" {
+		arm.Type = c.mold(core, b.Args[0])
+	}
+	arm.busy = true
+	t := c.infer(core, arm.Body)
+	arm.busy = false
+	if arm.Type == nil {
+		arm.Type = t
+	}
+	return arm.Type
+}
+
+// literal returns the type of an atom literal.
+func (c *checker) literal(lit string) Type {
+	switch {
+	case lit == "~":
+		return Atom{Aura: "n", Value: big.NewInt(0)}
+	case lit == "&" || lit == "%.y" || lit == "%&":
+		return Atom{Aura: "f", Value: big.NewInt(0)}
+	case lit == "|" || lit == "%.n" || lit == "%|":
+		return Atom{Aura: "f", Value: big.NewInt(1)}
+	case strings.HasPrefix(lit, "%"):
+		if v, ok := new(big.Int).SetString(strings.Replace(lit[1:], ".", "", -1), 10); ok {
+			return Atom{Aura: "ud", Value: v}
+		}
+		return Atom{Aura: "tas", Value: cord(lit[1:])}
+	case strings.HasPrefix(lit, "'"):
+		return Atom{Aura: "t"}
+	case strings.HasPrefix(lit, "\""):
+		return tape
+	}
+	for _, p := range []struct{ prefix, aura string }{
+		{"0x", "ux"}, {"0b", "ub"}, {"0v", "uv"}, {"0w", "uw"},
+		{"--", "sd"}, {"-", "sd"}, {"~.", "ta"}, {".~", "rd"},
+	} {
+		if strings.HasPrefix(lit, p.prefix) {
+			return Atom{Aura: p.aura}
+		}
+	}
+	switch {
+	case strings.HasPrefix(lit, "."):
+		if strings.Count(lit, ".") == 4 {
+			return Atom{Aura: "if"}
+		}
+		return Atom{Aura: "rs"}
+	case len(lit) > 2 && lit[0] == '~' && strings.ContainsRune("smhd", rune(lit[1])) && isDigit(lit[2]):
+		return Atom{Aura: "dr"}
+	case len(lit) > 1 && lit[0] == '~' && isDigit(lit[1]):
+		return Atom{Aura: "da"}
+	case len(lit) > 1 && lit[0] == '~':
+		return Atom{Aura: "p"}
+	}
+	return Atom{}
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// tape is the type of a tape, (list @tD).
+var tape = func() *Hold {
+	h := &Hold{Name: "tape"}
+	h.t = fork(
+		Atom{Aura: "n", Value: big.NewInt(0)},
+		Cell{Head: Face{Name: "i", Type: Atom{Aura: "tD"}}, Tail: Face{Name: "t", Type: h}},
+	)
+	return h
+}()
+
+// moldGate returns the type of a mold used as a value: a gate that
+// normalizes its sample to t.
+func moldGate(t Type) *Core {
+	return &Core{
+		Payload: Cell{Head: Noun{}, Tail: Noun{}},
+		Arms:    []*Arm{{Name: "$", Type: t}},
+	}
+}
+
+// faceName returns the name bound by n, a face or $.
+func faceName(c *checker, n ast.Node) string {
+	switch n := n.(type) {
+	case ast.Face:
+		return n.Name
+	case ast.Buc:
+		return "$"
+	}
+	c.errorf("expected face, got %T", n)
+	return ""
+}
+
+// A step is one step of a path into a type.
+type step int
+
+const (
+	stepFace step = iota // into a face
+	stepHead
+	stepTail
+)
+
+// A resolution is the result of resolving a wing within a subject: either a
+// leg, with its type and (if it can be edited) its path, or an arm of a core.
+// Its formula computes the leg, or the core, from the subject.
+type resolution struct {
+	leg  Type
+	path []step // nil if the leg is the product of an arm
+	core *Core
+	arm  *Arm
+	fol  noun.Noun
+}
+
+func isWing(n ast.Node) bool {
+	switch n.(type) {
+	case ast.Buc, ast.Face, ast.Wing:
+		return true
+	}
+	return false
+}
+
+func toLimbs(n ast.Node) []ast.Limb {
+	switch n := n.(type) {
+	case ast.Buc:
+		return []ast.Limb{{Name: "$"}}
+	case ast.Face:
+		return []ast.Limb{{Name: n.Name}}
+	case ast.Wing:
+		return n.Limbs
+	}
+	return nil
+}
+
+// wing resolves the wing n within sub. As in Hoon, the limbs of a wing are
+// resolved right to left.
+func (c *checker) wing(sub Type, n ast.Node) resolution {
+	if !isWing(n) {
+		c.errorf("expected wing, got %T", n)
+	}
+	limbs := toLimbs(n)
+	r := resolution{leg: sub, path: []step{}, fol: slot(big.NewInt(1))}
+	for i := len(limbs) - 1; i >= 0; i-- {
+		l := limbs[i]
+		if r.arm != nil {
+			// pull the arm, and continue within its product
+			r = resolution{leg: c.armType(r.core, r.arm), fol: pull(r)}
+		}
+		if l.Name == "" {
+			steps := axisPath(l.Axis)
+			if steps == nil && l.Axis != "1" {
+				c.errorf("bad axis %v", l)
+			}
+			r.leg = navigate(r.leg, steps)
+			if r.path != nil {
+				r.path = append(r.path, steps...)
+			}
+			r.fol = frag(r.fol, pathAxis(steps))
+			continue
+		}
+		skip := l.Skip
+		s, ok := c.search(r.leg, l.Name, &skip, nil)
+		if !ok {
+			c.errorf("find.%v", l.Name)
+		}
+		s.fol = frag(r.fol, pathAxis(s.path))
+		if r.path != nil {
+			s.path = append(append([]step{}, r.path...), s.path...)
+		} else {
+			s.path = nil
+		}
+		if s.arm != nil && l.Core {
+			// ..name is the core containing the arm
+			s = resolution{leg: s.core, path: s.path, fol: s.fol}
+		}
+		r = s
+	}
+	if r.arm == nil && r.leg == nil {
+		c.errorf("find.%v", ast.Wing{Limbs: limbs})
+	}
+	return r
+}
+
+// search finds the face or arm name within t, skipping the first skip
+// matches. The path of the result is relative to t, and appended to path.
+func (c *checker) search(t Type, name string, skip *int, path []step) (resolution, bool) {
+	switch t := t.(type) {
+	case *Hold:
+		return c.search(t.Type(), name, skip, path)
+	case Face:
+		if t.Name != name {
+			return resolution{}, false
+		} else if *skip > 0 {
+			*skip--
+			return resolution{}, false
+		}
+		return resolution{leg: t.Type, path: append(path, stepFace)}, true
+	case Cell:
+		if r, ok := c.search(t.Head, name, skip, append(path, stepHead)); ok {
+			return r, true
+		}
+		return c.search(t.Tail, name, skip, append(path, stepTail))
+	case *Core:
+		if a := t.arm(name); a != nil {
+			if *skip == 0 {
+				return resolution{core: t, arm: a, path: path}, true
+			}
+			*skip--
+		}
+		return c.search(t.Payload, name, skip, append(path, stepTail))
+	case Fork:
+		// the name must resolve to the same leg in every branch
+		var r resolution
+		var legs []Type
+		for i, b := range t.Types {
+			s := *skip
+			br, ok := c.search(b, name, &s, path)
+			if !ok {
+				return resolution{}, false
+			} else if i > 0 && (br.arm != nil || !samePath(br.path, r.path)) {
+				c.errorf("find.%v: ambiguous in %v", name, t)
+			}
+			r = br
+			legs = append(legs, br.leg)
+		}
+		if r.arm == nil {
+			r.leg = fork(legs...)
+		}
+		return r, true
+	}
+	return resolution{}, false
+}
+
+func samePath(a, b []step) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// axisPath returns the steps to axis, e.g. [head tail] for 5, or nil if
+// axis is invalid.
+func axisPath(axis string) []step {
+	a, ok := new(big.Int).SetString(axis, 10)
+	if !ok || a.Sign() <= 0 {
+		return nil
+	}
+	steps := []step{}
+	for i := a.BitLen() - 2; i >= 0; i-- {
+		if a.Bit(i) == 0 {
+			steps = append(steps, stepHead)
+		} else {
+			steps = append(steps, stepTail)
+		}
+	}
+	return steps
+}
+
+// navigate returns the type at path within t.
+func navigate(t Type, path []step) Type {
+	return walkPath(t, path, func(t Type) Type { return t }, false)
+}
+
+// edit returns t with the type at path replaced by fn of it.
+func edit(t Type, path []step, fn func(Type) Type) Type {
+	return walkPath(t, path, fn, true)
+}
+
+// walkPath follows path within t, applying fn at its end. If rebuild is set,
+// it returns t with the result of fn in place; otherwise, it returns the
+// result itself. Faces are transparent to head and tail steps.
+func walkPath(t Type, path []step, fn func(Type) Type, rebuild bool) Type {
+	if len(path) == 0 {
+		return fn(t)
+	}
+	switch tt := t.(type) {
+	case *Hold:
+		return walkPath(tt.Type(), path, fn, rebuild)
+	case Fork:
+		ts := make([]Type, len(tt.Types))
+		for i, b := range tt.Types {
+			ts[i] = walkPath(b, path, fn, rebuild)
+		}
+		return fork(ts...)
+	case Void:
+		return t
+	case Face:
+		rest := path
+		if path[0] == stepFace {
+			rest = path[1:]
+		}
+		in := walkPath(tt.Type, rest, fn, rebuild)
+		if !rebuild {
+			return in
+		}
+		return mkFace(tt.Name, in)
+	case Noun:
+		if path[0] == stepFace {
+			return Void{}
+		}
+		return walkPath(Noun{}, path[1:], fn, rebuild)
+	case Cell:
+		switch path[0] {
+		case stepHead:
+			in := walkPath(tt.Head, path[1:], fn, rebuild)
+			if !rebuild {
+				return in
+			}
+			return mkCell(in, tt.Tail)
+		case stepTail:
+			in := walkPath(tt.Tail, path[1:], fn, rebuild)
+			if !rebuild {
+				return in
+			}
+			return mkCell(tt.Head, in)
+		}
+	case *Core:
+		switch path[0] {
+		case stepHead:
+			// the battery
+			in := walkPath(Noun{}, path[1:], fn, rebuild)
+			if !rebuild {
+				return in
+			}
+			return tt
+		case stepTail:
+			in := walkPath(tt.Payload, path[1:], fn, rebuild)
+			if !rebuild {
+				return in
+			} else if isVoid(in) {
+				return in
+			}
+			return tt.withPayload(in)
+		}
+	}
+	// atoms have no legs
+	return Void{}
+}
@@ -0,0 +1,449 @@
+package types
+
+import (
+	"math/big"
+	"strings"
+
+	"lukechampine.com/urbit/atom"
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+	"lukechampine.com/urbit/noun"
+)
+
+// Mint compiles n, evaluated against a subject of type sub, to a Nock
+// formula, returning the type of its product along with the formula. As in
+// Hoon's ++mint, faces and arms are resolved to axes within the subject,
+// cores are compiled to [battery payload] cells, and %= edits legs in place.
+// n is checked as by Infer.
+//
+// Arms are laid out in a balanced tree, in the order they are defined.
+// Molds are only compiled to types: mold arms and mold builders crash if
+// they are pulled, and molds cannot be used as values. Scrys, vases, and
+// Sail are not supported, nor are ~| and ~&, whose clues are vases.
+func Mint(sub Type, n ast.Node) (t Type, fol noun.Noun, err error) {
+	c := &checker{pos: -1}
+	defer c.recover(&err)
+	t, fol = c.mint(sub, n)
+	return t, fol, nil
+}
+
+// Compile parses, checks, and compiles the Hoon source file src, returning
+// the type of its body and a formula that computes it. The formula expects
+// the subject ~, and builds the Prelude itself. Errors are reported as by
+// Check.
+func Compile(src []byte) (Type, noun.Noun, error) {
+	f, err := parser.NewConcrete(scanner.New(src)).ParseFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	pt, pf, err := Mint(null, parsePrelude())
+	if err != nil {
+		panic(err)
+	}
+	t, fol, err := Mint(pt, f.Body)
+	if err != nil {
+		locate(src, err)
+		return nil, nil, err
+	}
+	return t, op(7, pf, fol), nil
+}
+
+func (c *checker) mint(sub Type, n ast.Node) (Type, noun.Noun) {
+	defer c.at(n)()
+	switch n := n.(type) {
+	case ast.Num:
+		t := c.infer(sub, n)
+		return t, op(1, c.value(t, n.Int))
+	case ast.Literal:
+		t := c.infer(sub, n)
+		return t, op(1, c.value(t, n.Lit))
+	case ast.Buc, ast.Face, ast.Wing:
+		r := c.wing(sub, n)
+		if r.arm != nil {
+			return c.armType(r.core, r.arm), pull(r)
+		} else if _, ok := r.leg.(moldArg); ok {
+			c.errorf("mold %v used as a value", ast.Wing{Limbs: toLimbs(n)})
+		}
+		return r.leg, r.fol
+	case ast.Cell:
+		ht, hf := c.mint(sub, n.Head)
+		tt, tf := c.mint(sub, n.Tail)
+		return mkCell(ht, tt), noun.Cell{Head: hf, Tail: tf}
+	case ast.Tis:
+		t, f := c.mint(sub, n.Right)
+		return mkFace(faceName(c, n.Left), t), f
+	case ast.Path:
+		var t Type = null
+		var f noun.Noun = op(1, noun.Null)
+		for i := len(n.Segs) - 1; i >= 0; i-- {
+			var st Type = Atom{Aura: "ta"}
+			var sf noun.Noun
+			if lit, ok := n.Segs[i].(ast.Literal); ok {
+				sf = op(1, noun.Atom{Atom: atom.New(cord(lit.Lit))})
+			} else {
+				st, sf = c.mint(sub, n.Segs[i])
+			}
+			t, f = mkCell(st, t), noun.Cell{Head: sf, Tail: f}
+		}
+		return t, f
+	case ast.File:
+		return c.mint(sub, n.Body)
+	case ast.Rune:
+		return c.mintRune(sub, n)
+	case ast.Pat, ast.Tar, ast.Ket, ast.Wut:
+		c.errorf("cannot compile mold as a value")
+	default:
+		c.errorf("cannot compile %T", n)
+	}
+	return nil, nil
+}
+
+func (c *checker) mintRune(sub Type, r ast.Rune) (Type, noun.Noun) {
+	a := r.Args
+	switch r.Lit {
+	// subject
+	case "=>":
+		pt, pf := c.mint(sub, a[0])
+		t, f := c.mint(pt, a[1])
+		return t, op(7, pf, f)
+	case "=,":
+		pt, pf := c.mint(sub, a[0])
+		if f, ok := pt.(Face); ok {
+			pt = f.Type
+		}
+		t, f := c.mint(mkCell(pt, sub), a[1])
+		return t, op(8, pf, f)
+	case "=*":
+		pt, pf := c.mint(sub, a[1])
+		t, f := c.mint(mkCell(mkFace(faceName(c, a[0]), pt), sub), a[2])
+		return t, op(8, pf, f)
+
+	// branches
+	case "?:":
+		tt, tf := c.mint(sub, a[0])
+		c.nest(Flag, tt)
+		yes, no := c.branches(sub, a[0])
+		// a branch that cannot be taken is compiled to a crash
+		var yt, nt Type = Void{}, Void{}
+		yf, nf := crash, crash
+		if !isVoid(yes) {
+			yt, yf = c.mint(yes, a[1])
+		}
+		if !isVoid(no) {
+			nt, nf = c.mint(no, a[2])
+		}
+		return fork(yt, nt), op(6, tf, yf, nf)
+	case "?=":
+		m := c.mold(sub, a[0])
+		_, f := c.mint(sub, a[1])
+		return Flag, op(7, f, c.fish(m, big.NewInt(1), nil))
+
+	// cores
+	case "|%", "|@":
+		core := c.core(sub, a)
+		fols := make([]noun.Noun, len(core.Arms))
+		for i, arm := range core.Arms {
+			fols[i] = crash
+			if b, ok := arm.Body.(ast.Rune); !arm.Mold && !(ok && b.Lit == "|$") {
+				_, fols[i] = c.mint(core, arm.Body)
+			}
+		}
+		return core, noun.Cell{Head: op(1, battery(fols)), Tail: slot(big.NewInt(1))}
+	case "|$":
+		c.errorf("cannot compile mold builder as a value")
+
+	// wing resolution
+	case "%=":
+		return c.mintEdit(sub, a[0], a[1:])
+
+	// types
+	case "^+":
+		t := c.infer(sub, a[0])
+		ht, f := c.mint(sub, a[1])
+		c.nest(t, ht)
+		return t, f
+	case "^-":
+		t := c.mold(sub, a[0])
+		ht, f := c.mint(sub, a[1])
+		c.nest(t, ht)
+		return t, f
+	case "^*":
+		t := c.mold(sub, a[0])
+		return t, op(1, c.bunt(t, nil))
+	case "^|", "^&", "^?", "^~":
+		return c.mint(sub, a[0])
+
+	// nock
+	case ".+":
+		t, f := c.mint(sub, a[0])
+		c.nest(Atom{}, t)
+		return Atom{}, op(4, f)
+	case ".=":
+		_, f0 := c.mint(sub, a[0])
+		_, f1 := c.mint(sub, a[1])
+		return Flag, op(5, f0, f1)
+	case ".?":
+		_, f := c.mint(sub, a[0])
+		return Flag, op(3, f)
+	case ".*":
+		_, f0 := c.mint(sub, a[0])
+		_, f1 := c.mint(sub, a[1])
+		return Noun{}, op(2, f0, f1)
+
+	// compiler directives
+	case "!!":
+		return Void{}, crash
+	case "!:", "!.", "!?", "!@":
+		return c.mint(sub, a[len(a)-1])
+	case "!=":
+		_, f := c.mint(sub, a[0])
+		return Noun{}, op(1, f)
+
+	// hints
+	case "~>":
+		t, f := c.mint(sub, a[1])
+		// a hint is a term, or a term and a clue, as in %foo.clue
+		hint, clue := a[0], ast.Node(nil)
+		if h, ok := hint.(ast.Cell); ok {
+			hint, clue = h.Head, h.Tail
+		}
+		lit, ok := hint.(ast.Literal)
+		if !ok {
+			c.errorf("bad hint %T", hint)
+		}
+		tag := c.value(c.infer(sub, lit), lit.Lit)
+		if clue == nil {
+			return t, op(11, tag, f)
+		}
+		_, cf := c.mint(sub, clue)
+		return t, op(11, noun.Cell{Head: tag, Tail: cf}, f)
+	case "~!":
+		return c.mint(sub, a[1])
+	case "~|", "~&":
+		c.errorf("cannot compile %v", r.Lit)
+	}
+
+	if strings.HasPrefix(r.Lit, "$") || r.Lit == "^:" {
+		c.errorf("cannot compile mold as a value")
+	}
+	n, err := r.Reduce()
+	if err != nil {
+		c.errorf("%v", err)
+	} else if nr, ok := n.(ast.Rune); ok && nr.Lit == r.Lit {
+		// a primitive rune not handled above
+		c.errorf("cannot compile %v", r.Lit)
+	}
+	return c.mint(sub, n)
+}
+
+// mintEdit compiles %=(target edits...). Each new value is computed against
+// the original subject, and then written into target, whose core is
+// pulled if it is an arm.
+func (c *checker) mintEdit(sub Type, target ast.Node, edits []ast.Node) (Type, noun.Noun) {
+	t := c.edit(sub, target, nil)
+	r := c.wing(sub, target)
+	base := r.leg
+	if r.arm != nil {
+		base = r.core
+		if r.arm.home != nil {
+			base = r.arm.home
+		}
+	}
+	f := r.fol
+	for i := 0; i+1 < len(edits); i += 2 {
+		func() {
+			defer c.at(edits[i])()
+			er := c.wing(base, edits[i])
+			if er.arm != nil {
+				c.errorf("cannot edit arm %v", er.arm.Name)
+			} else if er.path == nil {
+				c.errorf("cannot edit %v", ast.Wing{Limbs: toLimbs(edits[i])})
+			}
+			vt, vf := c.mint(sub, edits[i+1])
+			c.nest(er.leg, vt)
+			f = op(10, noun.Cell{Head: axisNoun(pathAxis(er.path)), Tail: vf}, f)
+		}()
+	}
+	if r.arm != nil {
+		return t, op(9, axisNoun(armAxis(r.core, r.arm)), f)
+	}
+	return t, f
+}
+
+// value returns the value of the literal lit, whose type is t.
+func (c *checker) value(t Type, lit string) noun.Noun {
+	if a, ok := t.(Atom); ok && a.Value != nil {
+		return noun.Atom{Atom: atom.New(a.Value)}
+	}
+	n, err := noun.Parse(lit)
+	if err != nil {
+		c.errorf("%v", err)
+	}
+	return n
+}
+
+// bunt returns the default value of t, as in Hoon's ^*: 0 for atoms (unless
+// the atom is a constant), and the first type of a fork.
+func (c *checker) bunt(t Type, seen map[*Hold]bool) noun.Noun {
+	switch t := t.(type) {
+	case Noun:
+		return noun.Null
+	case Atom:
+		if t.Value != nil {
+			return noun.Atom{Atom: atom.New(t.Value)}
+		}
+		return noun.Null
+	case Cell:
+		return noun.Cell{Head: c.bunt(t.Head, seen), Tail: c.bunt(t.Tail, seen)}
+	case Face:
+		return c.bunt(t.Type, seen)
+	case Fork:
+		return c.bunt(t.Types[0], seen)
+	case *Hold:
+		if seen[t] {
+			c.errorf("cannot bunt %v: recursive type has no base case", t)
+		} else if seen == nil {
+			seen = make(map[*Hold]bool)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return c.bunt(t.Type(), seen)
+	}
+	c.errorf("cannot bunt %v", t)
+	return nil
+}
+
+// fish returns a formula that tests whether the noun at axis within the
+// subject is of type t, as in Hoon's ++fish.
+func (c *checker) fish(t Type, axis *big.Int, seen map[*Hold]bool) noun.Noun {
+	yes, no := op(1, noun.Uint(0)), op(1, noun.Uint(1))
+	switch t := t.(type) {
+	case Noun:
+		return yes
+	case Void:
+		return no
+	case Atom:
+		if t.Value != nil {
+			return op(5, op(1, noun.Atom{Atom: atom.New(t.Value)}), slot(axis))
+		}
+		return op(6, op(3, slot(axis)), no, yes)
+	case Cell:
+		head := c.fish(t.Head, peg(axis, big.NewInt(2)), seen)
+		tail := c.fish(t.Tail, peg(axis, big.NewInt(3)), seen)
+		return op(6, op(3, slot(axis)), op(6, head, tail, no), no)
+	case Face:
+		return c.fish(t.Type, axis, seen)
+	case Fork:
+		f := no
+		for i := len(t.Types) - 1; i >= 0; i-- {
+			f = op(6, c.fish(t.Types[i], axis, seen), yes, f)
+		}
+		return f
+	case *Hold:
+		if seen[t] {
+			c.errorf("cannot test for recursive type %v", t)
+		} else if seen == nil {
+			seen = make(map[*Hold]bool)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return c.fish(t.Type(), axis, seen)
+	}
+	c.errorf("cannot test for %v", t)
+	return nil
+}
+
+// crash is a formula that always crashes.
+var crash = slot(big.NewInt(0))
+
+// op returns the formula [code args...].
+func op(code uint64, args ...noun.Noun) noun.Noun {
+	n := args[len(args)-1]
+	for i := len(args) - 2; i >= 0; i-- {
+		n = noun.Cell{Head: args[i], Tail: n}
+	}
+	return noun.Cell{Head: noun.Uint(code), Tail: n}
+}
+
+func axisNoun(axis *big.Int) noun.Noun {
+	return noun.Atom{Atom: atom.New(axis)}
+}
+
+// slot returns the formula for the noun at axis within the subject.
+func slot(axis *big.Int) noun.Noun {
+	return op(0, axisNoun(axis))
+}
+
+// frag returns a formula for the noun at axis within the product of fol.
+func frag(fol noun.Noun, axis *big.Int) noun.Noun {
+	if axis.Cmp(big.NewInt(1)) == 0 {
+		return fol
+	}
+	if c := fol.(noun.Cell); noun.IsNull(c.Head) {
+		a := c.Tail.(noun.Atom)
+		return slot(peg(new(big.Int).SetBytes(a.Bytes()), axis))
+	}
+	return op(7, fol, slot(axis))
+}
+
+// pull returns a formula that computes the product of the arm of r, by
+// evaluating it against its core.
+func pull(r resolution) noun.Noun {
+	return op(9, axisNoun(armAxis(r.core, r.arm)), r.fol)
+}
+
+// peg returns the axis of b within the noun at axis a.
+func peg(a, b *big.Int) *big.Int {
+	n := b.BitLen() - 1
+	low := new(big.Int).Sub(b, new(big.Int).Lsh(big.NewInt(1), uint(n)))
+	return low.Or(low, new(big.Int).Lsh(a, uint(n)))
+}
+
+// pathAxis returns the axis reached by path.
+func pathAxis(path []step) *big.Int {
+	a := big.NewInt(1)
+	for _, s := range path {
+		switch s {
+		case stepHead:
+			a.Lsh(a, 1)
+		case stepTail:
+			a.Lsh(a, 1).SetBit(a, 0, 1)
+		}
+	}
+	return a
+}
+
+// armAxis returns the axis of arm within core: within the battery, at 2,
+// the arms are laid out in a balanced tree.
+func armAxis(core *Core, arm *Arm) *big.Int {
+	i := 0
+	for i < len(core.Arms) && core.Arms[i] != arm {
+		i++
+	}
+	a := big.NewInt(2)
+	lo, hi := 0, len(core.Arms)
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		a.Lsh(a, 1)
+		if i < mid {
+			hi = mid
+		} else {
+			a.SetBit(a, 0, 1)
+			lo = mid
+		}
+	}
+	return a
+}
+
+// battery returns the balanced tree of fols, as laid out by armAxis.
+func battery(fols []noun.Noun) noun.Noun {
+	switch len(fols) {
+	case 0:
+		return noun.Null
+	case 1:
+		return fols[0]
+	}
+	mid := len(fols) / 2
+	return noun.Cell{Head: battery(fols[:mid]), Tail: battery(fols[mid:])}
+}
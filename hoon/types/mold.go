@@ -0,0 +1,182 @@
+package types
+
+import (
+	"strings"
+
+	"lukechampine.com/urbit/hoon/ast"
+)
+
+// mold returns the type described by the mold n, resolved against sub.
+func (c *checker) mold(sub Type, n ast.Node) Type {
+	defer c.at(n)()
+	switch n := n.(type) {
+	case ast.Pat:
+		return Atom{Aura: n.Aura}
+	case ast.Tar:
+		return Noun{}
+	case ast.Ket:
+		return Cell{Head: Noun{}, Tail: Noun{}}
+	case ast.Wut:
+		return Flag
+	case ast.Literal:
+		if t, ok := c.literal(n.Lit).(Atom); ok && t.Value != nil {
+			return t
+		}
+		c.errorf("%v is not a constant", n.Lit)
+	case ast.Cell:
+		return mkCell(c.mold(sub, n.Head), c.mold(sub, n.Tail))
+	case ast.Tis:
+		return mkFace(faceName(c, n.Left), c.mold(sub, n.Right))
+	case ast.Buc, ast.Face, ast.Wing:
+		r := c.wing(sub, n)
+		if r.arm != nil {
+			return c.armMold(r.core, r.arm)
+		} else if m, ok := r.leg.(moldArg); ok {
+			return m.t
+		}
+		c.errorf("%v is not a mold", ast.Wing{Limbs: toLimbs(n)})
+	case ast.Rune:
+		return c.moldRune(sub, n)
+	default:
+		c.errorf("%T is not a mold", n)
+	}
+	return nil
+}
+
+func (c *checker) moldRune(sub Type, r ast.Rune) Type {
+	a := r.Args
+	molds := func(ns []ast.Node) []Type {
+		ts := make([]Type, len(ns))
+		for i, n := range ns {
+			ts[i] = c.mold(sub, n)
+		}
+		return ts
+	}
+	switch r.Lit {
+	case "$:":
+		ts := molds(a)
+		t := ts[len(ts)-1]
+		for i := len(ts) - 2; i >= 0; i-- {
+			t = mkCell(ts[i], t)
+		}
+		return t
+	case "$?", "$%", "$@", "$^":
+		return fork(molds(a)...)
+	case "$=":
+		return mkFace(faceName(c, a[0]), c.mold(sub, a[1]))
+	case "$_":
+		return c.infer(sub, a[0])
+	case "$-":
+		return &Core{
+			Payload: Cell{Head: c.mold(sub, a[0]), Tail: Noun{}},
+			Arms:    []*Arm{{Name: "$", Type: c.mold(sub, a[1])}},
+		}
+	case "$~", "$+":
+		return c.mold(sub, a[1])
+	case "$&", "$|":
+		return c.mold(sub, a[0])
+	case "%-", "%:", "%+", "%^":
+		return c.moldCall(sub, a[0], a[1:])
+	case "=>":
+		return c.mold(c.infer(sub, a[0]), a[1])
+	case "=<":
+		return c.mold(c.infer(sub, a[1]), a[0])
+	}
+	c.errorf("unsupported mold %v", r.Lit)
+	return nil
+}
+
+// armMold returns the type described by a mold arm of core, such as
+// +$  foo  [a=@ b=@].
+func (c *checker) armMold(core *Core, arm *Arm) Type {
+	if arm.Body == nil {
+		c.errorf("%v is not a mold", arm.Name)
+	} else if b, ok := arm.Body.(ast.Rune); ok && b.Lit == "|$" {
+		c.errorf("mold builder %v needs arguments", arm.Name)
+	}
+	if arm.hold == nil {
+		arm.hold = c.newHold(arm.Name, func() Type { return c.mold(core, arm.Body) })
+		arm.hold.Type()
+	}
+	return arm.hold
+}
+
+// moldCall returns the type described by a call to a mold builder, such as
+// (list @). Each distinct call is resolved once, so recursive calls, such as
+// (list item) within list, refer to the same Hold.
+func (c *checker) moldCall(sub Type, gate ast.Node, args []ast.Node) Type {
+	r := c.wing(sub, gate)
+	var b ast.Rune
+	if r.arm != nil {
+		b, _ = r.arm.Body.(ast.Rune)
+	}
+	if b.Lit != "|$" {
+		c.errorf("%v is not a mold builder", ast.Wing{Limbs: toLimbs(gate)})
+	}
+	var params []string
+	var flatten func(ast.Node)
+	flatten = func(n ast.Node) {
+		if cell, ok := n.(ast.Cell); ok {
+			flatten(cell.Head)
+			flatten(cell.Tail)
+			return
+		}
+		params = append(params, faceName(c, n))
+	}
+	flatten(b.Args[0])
+
+	ts := make([]Type, len(params))
+	for i := range ts {
+		ts[i] = Noun{}
+	}
+	if len(args) > 0 {
+		if len(args) != len(params) {
+			c.errorf("%v takes %v arguments, got %v", r.arm.Name, len(params), len(args))
+		}
+		for i, arg := range args {
+			ts[i] = c.mold(sub, arg)
+		}
+	}
+	names := make([]string, len(ts))
+	for i, t := range ts {
+		names[i] = t.String()
+	}
+	key := strings.Join(names, " ")
+	if h, ok := r.arm.calls[key]; ok {
+		return h
+	}
+	if r.arm.calls == nil {
+		r.arm.calls = make(map[string]*Hold)
+	}
+	core, body := r.core, b.Args[1]
+	h := c.newHold("("+r.arm.Name+" "+key+")", func() Type {
+		var s Type = core
+		for i := len(params) - 1; i >= 0; i-- {
+			s = Cell{Head: Face{Name: params[i], Type: moldArg{ts[i]}}, Tail: s}
+		}
+		return c.mold(s, body)
+	})
+	r.arm.calls[key] = h
+	h.Type()
+	return h
+}
+
+// newHold returns a Hold named name for the type returned by force. Callers
+// record the Hold, so that recursive references can find it, and then force
+// it immediately, so that errors in its definition are reported where it is
+// first used.
+func (c *checker) newHold(name string, force func() Type) *Hold {
+	pos := c.pos
+	h := &Hold{Name: name}
+	h.force = func() Type {
+		old := c.pos
+		c.pos = pos
+		defer func() { c.pos = old }()
+		t := force()
+		if t == Type(h) {
+			c.errorf("recursive type %v has no base case", name)
+		}
+		return t
+	}
+	return h
+}
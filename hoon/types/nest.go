@@ -0,0 +1,202 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Nest reports whether every noun of type have is also of type want, i.e.
+// whether a value of type have can be used where want is expected. Faces are
+// ignored.
+func Nest(want, have Type) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isErr := r.(*Error); !isErr {
+				panic(r)
+			}
+			ok = false
+		}
+	}()
+	return nester{}.nest(want, have)
+}
+
+// nester records the pairs of recursive types that are being compared, so
+// that comparing them again can be assumed to succeed.
+type nester map[string]bool
+
+func (n nester) nest(want, have Type) bool {
+	var key string
+	switch w := want.(type) {
+	case *Hold:
+		key = w.String() + "\x00" + have.String()
+	case *Core:
+		// the arms of cores can refer back to the cores themselves
+		if h, ok := have.(*Core); ok {
+			if w == h {
+				return true
+			}
+			key = fmt.Sprintf("%p\x00%p", w, h)
+		}
+	}
+	if _, ok := have.(*Hold); ok {
+		key = want.String() + "\x00" + have.String()
+	}
+	if key != "" {
+		if n[key] {
+			return true
+		}
+		n[key] = true
+	}
+
+	switch h := have.(type) {
+	case *Hold:
+		return n.nest(want, h.Type())
+	case Face:
+		return n.nest(want, h.Type)
+	case Void:
+		return true
+	case Fork:
+		for _, t := range h.Types {
+			if !n.nest(want, t) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch w := want.(type) {
+	case *Hold:
+		return n.nest(w.Type(), have)
+	case Face:
+		return n.nest(w.Type, have)
+	case Noun:
+		return true
+	case Fork:
+		for _, t := range w.Types {
+			if n.nest(t, have) {
+				return true
+			}
+		}
+		return false
+	case Atom:
+		h, ok := have.(Atom)
+		if !ok || !auraNests(w.Aura, h.Aura) {
+			return false
+		}
+		return w.Value == nil || (h.Value != nil && w.Value.Cmp(h.Value) == 0)
+	case Cell:
+		switch h := have.(type) {
+		case Cell:
+			return n.nest(w.Head, h.Head) && n.nest(w.Tail, h.Tail)
+		case *Core:
+			return n.nest(w.Head, Noun{}) && n.nest(w.Tail, h.Payload)
+		}
+		return false
+	case *Core:
+		h, ok := have.(*Core)
+		if !ok || !n.nest(w.Payload, h.Payload) {
+			return false
+		}
+		for _, wa := range w.Arms {
+			ha := h.arm(wa.Name)
+			if ha == nil {
+				return false
+			} else if wa.Type != nil && ha.Type != nil && !n.nest(wa.Type, ha.Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// auraNests reports whether atoms with aura have can be used as atoms with
+// aura want. As in Hoon, an empty aura nests with any other, and otherwise
+// one aura must refine the other, e.g. @u and @ud.
+func auraNests(want, have string) bool {
+	return strings.HasPrefix(want, have) || strings.HasPrefix(have, want)
+}
+
+// fuse returns the part of t that is also of type m, e.g. the type of a
+// value of type t after a ?= test against m succeeds.
+func fuse(t, m Type) Type {
+	switch tt := t.(type) {
+	case *Hold:
+		return fuse(tt.Type(), m)
+	case Face:
+		return mkFace(tt.Name, fuse(tt.Type, m))
+	case Fork:
+		ts := make([]Type, len(tt.Types))
+		for i, t := range tt.Types {
+			ts[i] = fuse(t, m)
+		}
+		return fork(ts...)
+	case Void:
+		return t
+	}
+
+	switch mm := m.(type) {
+	case *Hold:
+		return fuse(t, mm.Type())
+	case Face:
+		return fuse(t, mm.Type)
+	case Fork:
+		ts := make([]Type, len(mm.Types))
+		for i, m := range mm.Types {
+			ts[i] = fuse(t, m)
+		}
+		return fork(ts...)
+	case Noun:
+		return t
+	case Void:
+		return m
+	}
+
+	switch tt := t.(type) {
+	case Noun:
+		return m
+	case Atom:
+		ma, ok := m.(Atom)
+		if !ok || !auraNests(ma.Aura, tt.Aura) {
+			return Void{}
+		} else if tt.Value != nil {
+			if ma.Value != nil && ma.Value.Cmp(tt.Value) != 0 {
+				return Void{}
+			}
+			return tt
+		}
+		return ma
+	case Cell:
+		if mc, ok := m.(Cell); ok {
+			return mkCell(fuse(tt.Head, mc.Head), fuse(tt.Tail, mc.Tail))
+		}
+		return Void{}
+	case *Core:
+		if _, ok := m.(Atom); ok {
+			return Void{}
+		}
+		return tt
+	}
+	return t
+}
+
+// crop returns the part of t that is not of type m, e.g. the type of a
+// value of type t after a ?= test against m fails.
+func crop(t, m Type) Type {
+	switch tt := t.(type) {
+	case *Hold:
+		return crop(tt.Type(), m)
+	case Face:
+		return mkFace(tt.Name, crop(tt.Type, m))
+	case Fork:
+		ts := make([]Type, len(tt.Types))
+		for i, t := range tt.Types {
+			ts[i] = crop(t, m)
+		}
+		return fork(ts...)
+	}
+	if Nest(m, t) {
+		return Void{}
+	}
+	return t
+}
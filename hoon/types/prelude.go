@@ -0,0 +1,91 @@
+package types
+
+import (
+	"math/big"
+
+	"lukechampine.com/urbit/hoon/ast"
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+)
+
+// preludeSrc defines a small subset of the Hoon standard library.
+const preludeSrc = `
+|%
+++  list  |$  [item]  $@(~ [i=item t=(list item)])
+++  lest  |$  [item]  [i=item t=(list item)]
+++  unit  |$  [item]  $@(~ [~ u=item])
+++  pair  |$  [head tail]  [p=head q=tail]
++$  tape  (list @tD)
++$  path  (list @ta)
++$  cord  @t
++$  knot  @ta
++$  term  @tas
++$  flag  ?
++$  noun  *
+++  dec
+  |=  a=@
+  ^-  @
+  ?<  =(0 a)
+  =|  b=@
+  |-  ^-  @
+  ?:  =(a +(b))  b
+  $(b +(b))
+++  add
+  |=  [a=@ b=@]
+  ^-  @
+  ?:  =(0 a)  b
+  $(a (dec a), b +(b))
+++  sub
+  |=  [a=@ b=@]
+  ^-  @
+  ?:  =(0 b)  a
+  $(a (dec a), b (dec b))
+++  mul
+  |=  [a=@ b=@]
+  ^-  @
+  =|  c=@
+  |-  ^-  @
+  ?:  =(0 a)  c
+  $(a (dec a), c (add b c))
+++  lth
+  |=  [a=@ b=@]
+  ^-  ?
+  ?&  ?!(=(a b))
+      |-  ^-  ?
+      ?|  =(0 a)
+          ?&  ?!(=(0 b))
+              $(a (dec a), b (dec b))
+  ==  ==  ==
+++  gth  |=([a=@ b=@] ^-(? (lth b a)))
+++  lte  |=([a=@ b=@] ^-(? |(=(a b) (lth a b))))
+++  gte  |=([a=@ b=@] ^-(? |(=(a b) (gth a b))))
+++  max  |=([a=@ b=@] ^-(@ ?:((gth a b) a b)))
+++  min  |=([a=@ b=@] ^-(@ ?:((lth a b) a b)))
+++  lent
+  |=  a=(list)
+  ^-  @
+  =|  b=@
+  |-  ^-  @
+  ?~  a  b
+  $(a t.a, b +(b))
+--
+`
+
+// Prelude returns the type of a core containing a small subset of the Hoon
+// standard library: the mold builders list, lest, unit, and pair; the molds
+// tape, path, cord, knot, term, flag, and noun; and the gates dec, add, sub,
+// mul, lth, gth, lte, gte, max, min, and lent. Check uses it as the subject.
+func Prelude() Type {
+	t, err := Infer(null, parsePrelude())
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// null is the type of ~.
+var null = Atom{Aura: "n", Value: big.NewInt(0)}
+
+func parsePrelude() ast.Node {
+	return parser.New(scanner.New([]byte(preludeSrc))).Parse()
+}
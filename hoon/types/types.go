@@ -0,0 +1,294 @@
+// Package types implements Hoon's type system.
+//
+// A Type describes a set of nouns, as in Hoon: atoms, with an aura and
+// possibly a constant value; cells; cores, which pair arms with a payload;
+// faces, which name a type; forks, which are unions of types; and holds,
+// which are computed lazily, allowing recursive types such as (list @).
+// Molds are resolved to types by Mold, and the types of expressions are
+// inferred by Infer, which checks that every cast, call, and edit nests.
+// Mint goes one step further, compiling expressions to Nock formulas, which
+// can be run by package nock.
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"lukechampine.com/urbit/hoon/ast"
+)
+
+// A Type is a Hoon type.
+type Type interface {
+	isType()
+	String() string
+}
+
+func (Noun) isType()    {}
+func (Void) isType()    {}
+func (Atom) isType()    {}
+func (Cell) isType()    {}
+func (Face) isType()    {}
+func (Fork) isType()    {}
+func (*Core) isType()   {}
+func (*Hold) isType()   {}
+func (moldArg) isType() {}
+
+// Noun is the type of every noun, *.
+type Noun struct{}
+
+// Void is the type of no noun, e.g. the type of !!.
+type Void struct{}
+
+// An Atom is an atom type, such as @ or @ud. If Value is non-nil, the type
+// contains only that atom, e.g. %foo or ~.
+type Atom struct {
+	Aura  string
+	Value *big.Int
+}
+
+// A Cell is a cell type, such as [@ @].
+type Cell struct {
+	Head Type
+	Tail Type
+}
+
+// A Face is a type with a name, such as a=@.
+type Face struct {
+	Name string
+	Type Type
+}
+
+// A Fork is a union of types, such as ?(%foo %bar).
+type Fork struct {
+	Types []Type
+}
+
+// A Core is a battery of arms paired with a payload. The product types of
+// its arms are computed as they are needed. Cores that differ only in their
+// payloads, such as a gate and the same gate with a new sample, share their
+// arms.
+type Core struct {
+	Payload Type
+	Arms    []*Arm
+}
+
+// An Arm is an arm of a Core.
+type Arm struct {
+	Name string
+	Body ast.Node // nil for arms of cores built from $- molds
+	Mold bool     // defined with +$
+	Type Type     // product type, once known
+
+	home  *Core // the core the arm was defined in
+	busy  bool
+	hold  *Hold            // for mold arms
+	calls map[string]*Hold // for mold builders, by argument types
+}
+
+// A Hold is a type that is computed lazily, such as a mold arm or a call
+// to a mold builder. Recursive types, such as (list @), refer to themselves
+// through holds.
+type Hold struct {
+	Name string
+
+	t     Type
+	busy  bool
+	force func() Type
+}
+
+// Type returns the type that h stands for.
+func (h *Hold) Type() Type {
+	if h.t == nil {
+		if h.busy {
+			panic(&Error{Pos: -1, Msg: "recursive type " + h.Name + " has no base case"})
+		}
+		h.busy = true
+		h.t = h.force()
+		h.busy = false
+	}
+	return h.t
+}
+
+// moldArg is the type of an argument of a mold builder, such as item in
+// (list item). It is a mold for t.
+type moldArg struct {
+	t Type
+}
+
+// Flag is the loobean type, ?.
+var Flag Type = Fork{Types: []Type{
+	Atom{Aura: "f", Value: big.NewInt(0)},
+	Atom{Aura: "f", Value: big.NewInt(1)},
+}}
+
+func (Noun) String() string { return "*" }
+func (Void) String() string { return "%void" }
+
+func (a Atom) String() string {
+	if a.Value == nil {
+		return "@" + a.Aura
+	}
+	switch a.Aura {
+	case "n":
+		return "~"
+	case "f":
+		if a.Value.Sign() == 0 {
+			return "%.y"
+		}
+		return "%.n"
+	case "tas":
+		return "%" + cordString(a.Value)
+	default:
+		return "%" + a.Value.String()
+	}
+}
+
+func (c Cell) String() string {
+	parts := []string{c.Head.String()}
+	t := c.Tail
+	for {
+		tc, ok := t.(Cell)
+		if !ok {
+			break
+		}
+		parts = append(parts, tc.Head.String())
+		t = tc.Tail
+	}
+	parts = append(parts, t.String())
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func (f Face) String() string { return f.Name + "=" + f.Type.String() }
+
+func (f Fork) String() string {
+	if len(f.Types) == 2 {
+		a, b := f.Types[0].String(), f.Types[1].String()
+		if (a == "%.y" && b == "%.n") || (a == "%.n" && b == "%.y") {
+			return "?"
+		}
+	}
+	parts := make([]string, len(f.Types))
+	for i, t := range f.Types {
+		parts[i] = t.String()
+	}
+	return "?(" + strings.Join(parts, " ") + ")"
+}
+
+func (c *Core) String() string {
+	if len(c.Arms) == 1 && c.Arms[0].Name == "$" {
+		return "<gate>"
+	}
+	names := make([]string, 0, len(c.Arms))
+	for _, a := range c.Arms {
+		names = append(names, a.Name)
+	}
+	if len(names) > 3 {
+		names = append(names[:3], "...")
+	}
+	return "<core " + strings.Join(names, " ") + ">"
+}
+
+func (h *Hold) String() string { return h.Name }
+
+func (m moldArg) String() string { return "$-(* " + m.t.String() + ")" }
+
+// cord returns the atom for the text s, e.g. the value of the term %foo.
+func cord(s string) *big.Int {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// cordString returns the text of the cord c.
+func cordString(c *big.Int) string {
+	b := c.Bytes()
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// mkCell returns the cell of h and t, or Void if either is Void.
+func mkCell(h, t Type) Type {
+	if isVoid(h) || isVoid(t) {
+		return Void{}
+	}
+	return Cell{Head: h, Tail: t}
+}
+
+// mkFace returns t with the face name, or Void if t is Void.
+func mkFace(name string, t Type) Type {
+	if isVoid(t) {
+		return Void{}
+	}
+	return Face{Name: name, Type: t}
+}
+
+// fork returns the union of ts, flattening nested forks and omitting Void
+// and duplicate types.
+func fork(ts ...Type) Type {
+	var flat []Type
+	seen := make(map[string]bool)
+	var add func(t Type)
+	add = func(t Type) {
+		switch t := t.(type) {
+		case Void:
+		case Fork:
+			for _, t := range t.Types {
+				add(t)
+			}
+		default:
+			if s := t.String(); !seen[s] {
+				seen[s] = true
+				flat = append(flat, t)
+			}
+		}
+	}
+	for _, t := range ts {
+		add(t)
+	}
+	switch len(flat) {
+	case 0:
+		return Void{}
+	case 1:
+		return flat[0]
+	}
+	return Fork{Types: flat}
+}
+
+func isVoid(t Type) bool {
+	_, ok := t.(Void)
+	return ok
+}
+
+// arm returns the arm of c named name, or nil.
+func (c *Core) arm(name string) *Arm {
+	for _, a := range c.Arms {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// withPayload returns a copy of c with payload p, sharing c's arms.
+func (c *Core) withPayload(p Type) *Core {
+	return &Core{Payload: p, Arms: c.Arms}
+}
+
+// An Error is a type error.
+type Error struct {
+	Pos       int // byte offset of the offending expression, or -1
+	Line, Col int // set by Check
+	Msg       string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%v:%v: %v", e.Line, e.Col, e.Msg)
+	}
+	return e.Msg
+}
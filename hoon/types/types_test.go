@@ -0,0 +1,188 @@
+package types
+
+import (
+	"testing"
+
+	"lukechampine.com/urbit/hoon/parser"
+	"lukechampine.com/urbit/hoon/scanner"
+	"lukechampine.com/urbit/nock"
+	"lukechampine.com/urbit/noun"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		src string
+		exp string
+	}{
+		{"1", "@ud"},
+		{"%foo", "%foo"},
+		{"[~ & 'a' 0x10]", "[~ %.y @t @ux]"},
+		{"=/  a  1\na", "@ud"},
+		{"=/  a=@  1\n[a a]", "[@ @]"},
+		{"^-  (list @)  ~[1 2 3]", "(list @)"},
+		{"(add 1 2)", "@"},
+		{"(lth 1 2)", "?"},
+		{"=/  l=(list @ud)  ~[1 2]\n(lent l)", "@"},
+		{"=/  l=(list @ud)  ~[1 2]\n?~  l  ~  i.l", "?(~ @ud)"},
+		{"=/  g  |=(a=@ +(a))\n(g 5)", "@"},
+		{"=/  u=(unit @)  [~ 5]\n?~  u  0  u.u", "?(@ud @)"},
+		{"|%\n+$  act  $%([%inc p=@] [%dec q=@])\n++  run\n  |=  a=act\n  ^-  @\n  ?-  -.a\n    %inc  p.a\n    %dec  q.a\n  ==\n--", "<core act run>"},
+		{"=|  a=[b=@ c=?]\nc.a", "?"},
+		{"~|  %foo  1", "@ud"},
+		{"~&  %foo  1", "@ud"},
+		{"=/  a  5\n=.  a  6\na", "@ud"},
+		{"\"abc\"", "tape"},
+		{"^-  tape  \"abc\"", "tape"},
+		{"=/  f  |=  n=@\n  ^-  @\n  ?:  =(0 n)  1\n  (mul n $(n (dec n)))\n(f 5)", "@"},
+		{"`@ud`5", "@ud"},
+		{"?:  &  1  2", "@ud"},
+	}
+	for _, test := range tests {
+		typ, err := Check([]byte(test.src))
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+		} else if typ.String() != test.exp {
+			t.Errorf("%q: expected %v, got %v", test.src, test.exp, typ)
+		}
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		exp string
+	}{
+		{"^-  @ud  0x10", "1:1: nest-fail: have @ux, need @ud"},
+		{"=/  a  1\n(add a [2 3])", "2:1: nest-fail: have [@ud @ud @ud], need [a=@ b=@]"},
+		{"=/  a  1\nb", "2:1: find.b"},
+		{"=/  l=(list @)  ~[1 2]\ni.l", "2:1: find.i"},
+		{"|-  ?:  &  1  $", "1:15: arm $ is recursive, and needs a ^- cast"},
+		{"?:  1  2  3", "1:1: nest-fail: have @ud, need ?"},
+		{"=/  a=@  1\n$(a [1 2])", "2:1: find.$"},
+		{"^-  (list)  [1 2]", "1:1: nest-fail: have [@ud @ud], need (list *)"},
+		{"^-  (unit @ud @ud)  ~", "1:5: unit takes 1 arguments, got 2"},
+		{"^-  foo  ~", "1:5: find.foo"},
+		{"=/  a\n", "parse: 2:1: unexpected \"EOF\""},
+	}
+	for _, test := range tests {
+		_, err := Check([]byte(test.src))
+		if err == nil {
+			t.Errorf("%q: expected error", test.src)
+		} else if err.Error() != test.exp {
+			t.Errorf("%q: expected %q, got %q", test.src, test.exp, err)
+		}
+	}
+}
+
+func TestNest(t *testing.T) {
+	mold := func(s string) Type {
+		t.Helper()
+		typ, err := Mold(Prelude(), parser.New(scanner.New([]byte(s))).Parse())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return typ
+	}
+	tests := []struct {
+		want, have string
+		exp        bool
+	}{
+		{"@", "@ud", true},
+		{"@ud", "@", true},
+		{"@u", "@ud", true},
+		{"@ud", "@ux", false},
+		{"?", "%.y", true},
+		{"%.y", "?", false},
+		{"*", "[@ @]", true},
+		{"@", "[@ @]", false},
+		{"[a=@ b=?]", "[@ud %.n]", true},
+		{"(list @)", "[@ [@ ~]]", true},
+		{"(list @)", "[@ @]", false},
+		{"(list *)", "(list @)", true},
+		{"(list @)", "(list *)", false},
+		{"(list @)", "tape", true},
+		{"$%([%a p=@] [%b q=?])", "[%b %.y]", true},
+		{"$%([%a p=@] [%b q=?])", "[%c %.y]", false},
+		{"$-(@ @)", "$-(@ @ud)", true},
+		{"(unit @)", "$@(~ [~ @ud])", true},
+	}
+	for _, test := range tests {
+		if got := Nest(mold(test.want), mold(test.have)); got != test.exp {
+			t.Errorf("Nest(%v, %v): expected %v, got %v", test.want, test.have, test.exp, got)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		src string
+		exp string
+	}{
+		{"42", "42"},
+		{"[1 %foo ~]", "[1 7.303.014 0]"},
+		{"+(41)", "42"},
+		{"=/  a  1\n=/  b  2\n[b a]", "[2 1]"},
+		{"(dec 10)", "9"},
+		{"(add 2 3)", "5"},
+		{"(sub 10 3)", "7"},
+		{"(mul 6 7)", "42"},
+		{"[(lth 2 3) (gth 2 3) (max 2 3) (min 2 3)]", "[0 1 3 2]"},
+		{"(lent ~[1 2 3])", "3"},
+		{"(lent \"hello\")", "5"},
+		{"=/  f  |=  n=@\n  ^-  @\n  ?:  =(0 n)  1\n  (mul n $(n (dec n)))\n(f 5)", "120"},
+		{"=|  a=[b=@ c=?]\n=.  b.a  5\na", "[5 0]"},
+		{"=/  a  [1 2]\n=.  +.a  3\na", "[1 3]"},
+		{"=/  u=(unit @)  [~ 5]\n?~  u  0  +(u.u)", "6"},
+		{"=/  l=(list @)  ~[1 2]\n?@  l  ~  [i.l t.l]", "[1 2 0]"},
+		{"=/  a=?(%foo %bar)  %bar\n?-  a  %foo  1  %bar  2  ==", "2"},
+		{"^*  [@ud ? (list @)]", "[0 0 0]"},
+		{"|%\n++  x  3\n++  y  +(x)\n++  z  [x y]\n--\n", "<core>"},
+		{"=<  z\n|%\n++  x  3\n++  y  +(x)\n++  z  [x y]\n--", "[3 4]"},
+		{"=/  g  |=([a=@ b=@] [b a])\n(g 1 2)", "[2 1]"},
+		{"=/  c  |_  a=@\n++  get  a\n++  inc  ..inc(a +(a))\n--\nget:inc:inc:c", "2"},
+		{"=/  p  /foo/(add 1 2)\np", "[7.303.014 3 0]"},
+		{"!=(+(1))", "[4 1 1]"},
+		{"~>  %foo  5", "5"},
+	}
+	for _, test := range tests {
+		_, fol, err := Compile([]byte(test.src))
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+			continue
+		}
+		res, err := nock.Nock(noun.Null, fol)
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+		} else if _, ok := res.(noun.Cell); ok && test.exp == "<core>" {
+			continue
+		} else if s := noun.Format(res, nil, 0); s != test.exp {
+			t.Errorf("%q: expected %v, got %v", test.src, test.exp, s)
+		}
+	}
+
+	for _, src := range []string{"(dec 0)", "!!", "=/  a=@  1\n?>  =(a 2)  a"} {
+		_, fol, err := Compile([]byte(src))
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+		} else if res, err := nock.Nock(noun.Null, fol); err == nil {
+			t.Errorf("%q: expected crash, got %v", src, res)
+		}
+	}
+
+	for _, test := range []struct{ src, exp string }{
+		{"^-  @ud  0x10", "1:1: nest-fail: have @ux, need @ud"},
+		{"=/  a  1\n[a (list @)]", "2:10: cannot compile mold as a value"},
+		{"|$  [a]  a", "1:1: cannot compile mold builder as a value"},
+		{"?=((list @) ~)", "1:1: cannot test for recursive type (list @)"},
+		{"!>(1)", "1:1: cannot compile !>"},
+		{"~|  %foo  1", "1:1: cannot compile ~|"},
+		{"~?  &  %foo  1", "1:1: cannot compile ~&"},
+		{"{a b}", "parse: 1:1: unexpected \"{\""},
+	} {
+		if _, _, err := Compile([]byte(test.src)); err == nil {
+			t.Errorf("%q: expected error", test.src)
+		} else if err.Error() != test.exp {
+			t.Errorf("%q: expected %q, got %q", test.src, test.exp, err)
+		}
+	}
+}
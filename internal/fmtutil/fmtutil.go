@@ -0,0 +1,28 @@
+// Package fmtutil provides helpers for implementing fmt.Formatter.
+package fmtutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatString reconstructs the formatting directive of f, with the specified
+// verb, as in fmt.FormatString, which requires Go 1.20.
+func FormatString(f fmt.State, verb rune) string {
+	var sb strings.Builder
+	sb.WriteByte('%')
+	for _, c := range "+-# 0" {
+		if f.Flag(int(c)) {
+			sb.WriteRune(c)
+		}
+	}
+	if w, ok := f.Width(); ok {
+		sb.WriteString(strconv.Itoa(w))
+	}
+	if p, ok := f.Precision(); ok {
+		sb.WriteString("." + strconv.Itoa(p))
+	}
+	sb.WriteRune(verb)
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package mach
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// atom returns the constant atom i. Indirect atoms are stored in globals.
+func (t *transpiler) atom(i *big.Int) value.Value {
+	if i.IsInt64() {
+		return constant.NewInt(atomType, i.Int64())
+	}
+	// the length, followed by the limbs, least significant first
+	limbs := []constant.Constant{nil}
+	mask := new(big.Int).SetUint64(math.MaxUint64)
+	for r := new(big.Int).Set(i); r.Sign() > 0; r.Rsh(r, 64) {
+		limb := new(big.Int).And(r, mask).Uint64()
+		limbs = append(limbs, constant.NewInt(types.I64, int64(limb)))
+	}
+	limbs[0] = constant.NewInt(types.I64, int64(len(limbs)-1))
+	g := t.module.NewGlobalDef(fmt.Sprintf("atom.%d", t.consts), constant.NewArray(types.NewArray(uint64(len(limbs)), types.I64), limbs...))
+	g.Linkage = enum.LinkagePrivate
+	g.Immutable = true
+	t.consts++
+	return constant.NewOr(constant.NewPtrToInt(g, atomType), constant.NewInt(atomType, math.MinInt64))
+}
+
+// helpers defines the fast paths for arithmetic on atoms. Each handles direct
+// atoms inline, and calls the corresponding Runtime function otherwise.
+func (t *transpiler) helpers() {
+	zero := constant.NewInt(atomType, 0)
+	extern := func(name string, ret types.Type, params ...types.Type) *ir.Func {
+		ps := make([]*ir.Param, len(params))
+		for i, p := range params {
+			ps[i] = ir.NewParam("", p)
+		}
+		f := ir.NewFunc(name, ret, ps...)
+		t.extern = append(t.extern, f)
+		return f
+	}
+	helper := func(name string, ret types.Type) (f *ir.Func, a, b *ir.Param) {
+		a, b = ir.NewParam("a", atomType), ir.NewParam("b", atomType)
+		f = ir.NewFunc(name, ret, a, b)
+		f.Linkage = enum.LinkagePrivate
+		f.FuncAttrs = append(f.FuncAttrs, enum.FuncAttrAlwaysInline)
+		return f, a, b
+	}
+	// indirect reports whether v is indirect, i.e. whether its high bit is set
+	indirect := func(b *ir.Block, v value.Value) value.Value {
+		return b.NewICmp(enum.IPredSLT, v, zero)
+	}
+	trap := extern("llvm.trap", types.Void)
+	umul := extern("llvm.umul.with.overflow.i64", types.NewStruct(types.I64, types.I1), types.I64, types.I64)
+	slowAdd := extern("mach_add", atomType, atomType, atomType)
+	slowSub := extern("mach_sub", atomType, atomType, atomType)
+	slowMul := extern("mach_mul", atomType, atomType, atomType)
+	slowEq := extern("mach_eq", types.I1, atomType, atomType)
+
+	// the sum of two direct atoms fits in 64 bits, but may be indirect
+	f, a, b := helper("atom.add", atomType)
+	entry, fast, slow := f.NewBlock(""), f.NewBlock("fast"), f.NewBlock("slow")
+	sum := entry.NewAdd(a, b)
+	entry.NewCondBr(indirect(entry, entry.NewOr(entry.NewOr(a, b), sum)), slow, fast)
+	fast.NewRet(sum)
+	slow.NewRet(slow.NewCall(slowAdd, a, b))
+	t.add = f
+
+	f, a, b = helper("atom.sub", atomType)
+	entry, check, fast, crash, slow := f.NewBlock(""), f.NewBlock("check"), f.NewBlock("fast"), f.NewBlock("crash"), f.NewBlock("slow")
+	entry.NewCondBr(indirect(entry, entry.NewOr(a, b)), slow, check)
+	check.NewCondBr(check.NewICmp(enum.IPredULT, a, b), crash, fast)
+	fast.NewRet(fast.NewSub(a, b))
+	crash.NewCall(trap)
+	crash.NewUnreachable()
+	slow.NewRet(slow.NewCall(slowSub, a, b))
+	t.sub = f
+
+	f, a, b = helper("atom.mul", atomType)
+	entry, check, fast, slow = f.NewBlock(""), f.NewBlock("check"), f.NewBlock("fast"), f.NewBlock("slow")
+	entry.NewCondBr(indirect(entry, entry.NewOr(a, b)), slow, check)
+	prod := check.NewCall(umul, a, b)
+	v, overflow := check.NewExtractValue(prod, 0), check.NewExtractValue(prod, 1)
+	check.NewCondBr(check.NewOr(overflow, indirect(check, v)), slow, fast)
+	fast.NewRet(v)
+	slow.NewRet(slow.NewCall(slowMul, a, b))
+	t.mul = f
+
+	// atoms are equal if they are identical; otherwise, only two indirect
+	// atoms can be equal
+	f, a, b = helper("atom.eq", types.I1)
+	entry, check, yes, no, slow := f.NewBlock(""), f.NewBlock("check"), f.NewBlock("yes"), f.NewBlock("no"), f.NewBlock("slow")
+	entry.NewCondBr(entry.NewICmp(enum.IPredEQ, a, b), yes, check)
+	check.NewCondBr(indirect(check, check.NewAnd(a, b)), slow, no)
+	yes.NewRet(constant.True)
+	no.NewRet(constant.False)
+	slow.NewRet(slow.NewCall(slowEq, a, b))
+	t.eq = f
+}
@@ -2,10 +2,12 @@ package mach
 
 import (
 	"fmt"
+	"math/big"
 	"sort"
-	"strconv"
 
+	"lukechampine.com/urbit/atom"
 	"lukechampine.com/urbit/hoon/ast"
+	hoontypes "lukechampine.com/urbit/hoon/types"
 
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
@@ -14,8 +16,14 @@ import (
 	"github.com/llir/llvm/ir/value"
 )
 
-var atomType = types.I32
+// atomType is the type of atoms. Atoms below 2^63 are direct: the i64 is the
+// atom itself. Larger atoms are indirect: the i64 is a pointer to the atom, as
+// stored by Runtime, with its high bit set. Arithmetic on direct atoms is done
+// inline, falling back to Runtime when an operand or the result is indirect.
+var atomType = types.I64
 
+// Transpile compiles n to an LLVM module whose main function returns the
+// product of n. The module must be linked with Runtime, e.g. with llvm-link.
 func Transpile(n ast.Node) string {
 	t := newTranspiler()
 	main := t.module.NewFunc("main", atomType)
@@ -53,13 +61,20 @@ func (s subject) faces() []string {
 type transpiler struct {
 	module *ir.Module
 	id     int64
+	consts int64
+
+	// fast paths, and the runtime functions they fall back to
+	add, sub, mul, eq *ir.Func
+	extern            []*ir.Func
 }
 
 func newTranspiler() *transpiler {
 	m := ir.NewModule()
-	return &transpiler{
+	t := &transpiler{
 		module: m,
 	}
+	t.helpers()
+	return t
 }
 
 func maybeRet(b *ir.Block, v value.Value) {
@@ -69,6 +84,8 @@ func maybeRet(b *ir.Block, v value.Value) {
 }
 
 func (t *transpiler) Finish() string {
+	t.module.Funcs = append(t.module.Funcs, t.add, t.sub, t.mul, t.eq)
+	t.module.Funcs = append(t.module.Funcs, t.extern...)
 	return t.module.String()
 }
 
@@ -79,24 +96,45 @@ func (t *transpiler) uid() int64 {
 }
 
 func sampleParams(n ast.Node) []*ir.Param {
-	switch n := n.(type) {
-	case ast.Tis:
-		sampleName := n.Left.(ast.Face).Name
-		var sampleType types.Type
-		switch n.Right.(type) {
-		case ast.Pat:
-			sampleType = atomType
-		default:
-			panic("unhandled sample type")
+	t, err := hoontypes.Mold(hoontypes.Noun{}, n)
+	if err != nil {
+		panic(err)
+	}
+	return typeParams(t)
+}
+
+// typeParams returns the parameters for a sample of type t, which must be a
+// face or a cell of faces whose types are atoms.
+func typeParams(t hoontypes.Type) []*ir.Param {
+	switch t := t.(type) {
+	case hoontypes.Face:
+		if !isAtom(t.Type) {
+			panic("unhandled sample type " + t.Type.String())
 		}
-		return []*ir.Param{ir.NewParam(sampleName, sampleType)}
-	case ast.Cell:
-		return append(sampleParams(n.Head), sampleParams(n.Tail)...)
+		return []*ir.Param{ir.NewParam(t.Name, atomType)}
+	case hoontypes.Cell:
+		return append(typeParams(t.Head), typeParams(t.Tail)...)
 	default:
-		panic("unhandled sample mold")
+		panic("unhandled sample mold " + t.String())
 	}
 }
 
+// isAtom reports whether t is an atom type, such as @ud or ?.
+func isAtom(t hoontypes.Type) bool {
+	switch t := t.(type) {
+	case hoontypes.Atom:
+		return true
+	case hoontypes.Fork:
+		for _, t := range t.Types {
+			if !isAtom(t) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 func (t *transpiler) expr(s subject, fn *ir.Func, b *ir.Block, n ast.Node) value.Value {
 	eval := func(n ast.Node) value.Value {
 		return t.expr(s, fn, b, n)
@@ -104,8 +142,11 @@ func (t *transpiler) expr(s subject, fn *ir.Func, b *ir.Block, n ast.Node) value
 
 	switch n := n.(type) {
 	case ast.Num:
-		i, _ := strconv.Atoi(n.Int)
-		return constant.NewInt(atomType, int64(i))
+		a, err := atom.Parse(n.Int)
+		if err != nil {
+			panic(err)
+		}
+		return t.atom(new(big.Int).SetBytes(a.Bytes()))
 	case ast.Face:
 		return s.get(n.Name)
 	case ast.Buc:
@@ -113,16 +154,9 @@ func (t *transpiler) expr(s subject, fn *ir.Func, b *ir.Block, n ast.Node) value
 	case ast.Rune:
 		switch n.Lit {
 		case ".=":
-			return b.NewICmp(
-				enum.IPredEQ,
-				eval(n.Args[0]),
-				eval(n.Args[1]),
-			)
+			return b.NewCall(t.eq, eval(n.Args[0]), eval(n.Args[1]))
 		case ".+":
-			return b.NewAdd(
-				eval(n.Args[0]),
-				constant.NewInt(atomType, 1),
-			)
+			return b.NewCall(t.add, eval(n.Args[0]), constant.NewInt(atomType, 1))
 		case "=/":
 			face := n.Args[0].(ast.Face)
 			f := eval(n.Args[1])
@@ -181,7 +215,10 @@ func (t *transpiler) expr(s subject, fn *ir.Func, b *ir.Block, n ast.Node) value
 			return fn
 		case "%-":
 			switch gate := n.Args[0].(type) {
-			case ast.Dot:
+			case ast.Wing:
+				if len(gate.Limbs) != 0 {
+					panic(fmt.Sprintf("unhandled wing %v", gate))
+				}
 				f := fn
 				args := make([]value.Value, len(f.Params)-len(n.Args[1:]), len(f.Params))
 				for i := range args {
@@ -204,13 +241,13 @@ func (t *transpiler) expr(s subject, fn *ir.Func, b *ir.Block, n ast.Node) value
 			case ast.Face:
 				switch gate.Name {
 				case "dec":
-					return b.NewSub(eval(n.Args[1]), constant.NewInt(atomType, 1))
+					return b.NewCall(t.sub, eval(n.Args[1]), constant.NewInt(atomType, 1))
 				case "add":
-					return b.NewAdd(eval(n.Args[1]), eval(n.Args[2]))
+					return b.NewCall(t.add, eval(n.Args[1]), eval(n.Args[2]))
 				case "sub":
-					return b.NewSub(eval(n.Args[1]), eval(n.Args[2]))
+					return b.NewCall(t.sub, eval(n.Args[1]), eval(n.Args[2]))
 				case "mul":
-					return b.NewMul(eval(n.Args[1]), eval(n.Args[2]))
+					return b.NewCall(t.mul, eval(n.Args[1]), eval(n.Args[2]))
 				default:
 					v := s.get(gate.Name)
 					if v == nil {
@@ -1,6 +1,12 @@
 package mach
 
 import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"lukechampine.com/urbit/hoon/ast"
@@ -22,9 +28,9 @@ func Test(t *testing.T) {
 			desc: "atom literal",
 			hoon: `1`,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	ret i32 1
+	ret i64 1
 }
 `[1:],
 		},
@@ -35,9 +41,9 @@ define i32 @main() {
 n
 `,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	ret i32 1
+	ret i64 1
 }
 `[1:],
 		},
@@ -49,10 +55,10 @@ define i32 @main() {
 (add a b)
 `,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	%1 = add i32 2, 2
-	ret i32 %1
+	%1 = call i64 @atom.add(i64 2, i64 2)
+	ret i64 %1
 }
 `[1:],
 		},
@@ -67,17 +73,17 @@ define i32 @main() {
 b
 `,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	%1 = add i32 7, 1
-	%2 = icmp eq i32 8, %1
+	%1 = call i64 @atom.add(i64 7, i64 1)
+	%2 = call i1 @atom.eq(i64 8, i64 %1)
 	br i1 %2, label %3, label %4
 
 3:
-	ret i32 8
+	ret i64 8
 
 4:
-	ret i32 7
+	ret i64 7
 }
 `[1:],
 		},
@@ -92,25 +98,25 @@ define i32 @main() {
 $(acc (mul acc n), n +(n))
 `,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	%1 = call i32 @0(i32 1, i32 1)
-	ret i32 %1
+	%1 = call i64 @0(i64 1, i64 1)
+	ret i64 %1
 }
 
-define private i32 @0(i32 %acc, i32 %n) {
+define private i64 @0(i64 %acc, i64 %n) {
 0:
-	%1 = icmp eq i32 %n, 6
+	%1 = call i1 @atom.eq(i64 %n, i64 6)
 	br i1 %1, label %2, label %3
 
 2:
-	ret i32 %acc
+	ret i64 %acc
 
 3:
-	%4 = mul i32 %acc, %n
-	%5 = add i32 %n, 1
-	%6 = call i32 @0(i32 %4, i32 %5)
-	ret i32 %6
+	%4 = call i64 @atom.mul(i64 %acc, i64 %n)
+	%5 = call i64 @atom.add(i64 %n, i64 1)
+	%6 = call i64 @0(i64 %4, i64 %5)
+	ret i64 %6
 }
 `[1:],
 		},
@@ -124,25 +130,25 @@ define private i32 @0(i32 %acc, i32 %n) {
 (mul n $(n (dec n)))
 `,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	%1 = call i32 @0(i32 5)
-	ret i32 %1
+	%1 = call i64 @0(i64 5)
+	ret i64 %1
 }
 
-define private i32 @0(i32 %n) {
+define private i64 @0(i64 %n) {
 0:
-	%1 = icmp eq i32 %n, 0
+	%1 = call i1 @atom.eq(i64 %n, i64 0)
 	br i1 %1, label %2, label %3
 
 2:
-	ret i32 1
+	ret i64 1
 
 3:
-	%4 = sub i32 %n, 1
-	%5 = call i32 @0(i32 %4)
-	%6 = mul i32 %n, %5
-	ret i32 %6
+	%4 = call i64 @atom.sub(i64 %n, i64 1)
+	%5 = call i64 @0(i64 %4)
+	%6 = call i64 @atom.mul(i64 %n, i64 %5)
+	ret i64 %6
 }
 `[1:],
 		},
@@ -156,25 +162,25 @@ define private i32 @0(i32 %n) {
 $(b +(b))
 `,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	%1 = call i32 @0(i32 5, i32 0)
-	ret i32 %1
+	%1 = call i64 @0(i64 5, i64 0)
+	ret i64 %1
 }
 
-define private i32 @0(i32 %a, i32 %b) {
+define private i64 @0(i64 %a, i64 %b) {
 0:
-	%1 = add i32 %b, 1
-	%2 = icmp eq i32 %a, %1
+	%1 = call i64 @atom.add(i64 %b, i64 1)
+	%2 = call i1 @atom.eq(i64 %a, i64 %1)
 	br i1 %2, label %3, label %4
 
 3:
-	ret i32 %b
+	ret i64 %b
 
 4:
-	%5 = add i32 %b, 1
-	%6 = call i32 @0(i32 %a, i32 %5)
-	ret i32 %6
+	%5 = call i64 @atom.add(i64 %b, i64 1)
+	%6 = call i64 @0(i64 %a, i64 %5)
+	ret i64 %6
 }
 `[1:],
 		},
@@ -190,30 +196,225 @@ define private i32 @0(i32 %a, i32 %b) {
 (f 5)
 `,
 			llvm: `
-define i32 @main() {
+define i64 @main() {
 0:
-	%1 = call i32 @0(i32 5)
-	ret i32 %1
+	%1 = call i64 @0(i64 5)
+	ret i64 %1
 }
 
-define private i32 @0(i32 %a) {
+define private i64 @0(i64 %a) {
 0:
-	%1 = call i32 @1(i32 %a, i32 %a)
-	ret i32 %1
+	%1 = call i64 @1(i64 %a, i64 %a)
+	ret i64 %1
 }
 
-define private i32 @1(i32 %a, i32 %b) {
+define private i64 @1(i64 %a, i64 %b) {
 0:
-	%1 = sub i32 %b, 1
-	ret i32 %1
+	%1 = call i64 @atom.sub(i64 %b, i64 1)
+	ret i64 %1
+}
+`[1:],
+		},
+		{
+			desc: "atom literal with separators",
+			hoon: `(mul 100.000 100.000)`,
+			llvm: `
+define i64 @main() {
+0:
+	%1 = call i64 @atom.mul(i64 100000, i64 100000)
+	ret i64 %1
+}
+`[1:],
+		},
+		{
+			desc: "indirect atom literal",
+			hoon: `(add 0x1.0000.0000.0000.0000 1)`,
+			llvm: `
+@atom.0 = private constant [3 x i64] [i64 2, i64 0, i64 1]
+
+define i64 @main() {
+0:
+	%1 = call i64 @atom.add(i64 or (i64 ptrtoint ([3 x i64]* @atom.0 to i64), i64 -9223372036854775808), i64 1)
+	ret i64 %1
 }
 `[1:],
 		},
 	}
 	for _, test := range tests {
 		got := Transpile(parse(test.hoon))
-		if got != test.llvm {
+		if got != test.llvm+"\n"+helpers {
 			t.Errorf("bad transpile:\nhoon: %s\nllvm: %q\ngot:  %s", test.hoon, test.llvm, got)
 		}
 	}
 }
+
+// helpers is the code for atoms that follows every transpiled module.
+var helpers = `
+define private i64 @atom.add(i64 %a, i64 %b) alwaysinline {
+0:
+	%1 = add i64 %a, %b
+	%2 = or i64 %a, %b
+	%3 = or i64 %2, %1
+	%4 = icmp slt i64 %3, 0
+	br i1 %4, label %slow, label %fast
+
+fast:
+	ret i64 %1
+
+slow:
+	%5 = call i64 @mach_add(i64 %a, i64 %b)
+	ret i64 %5
+}
+
+define private i64 @atom.sub(i64 %a, i64 %b) alwaysinline {
+0:
+	%1 = or i64 %a, %b
+	%2 = icmp slt i64 %1, 0
+	br i1 %2, label %slow, label %check
+
+check:
+	%3 = icmp ult i64 %a, %b
+	br i1 %3, label %crash, label %fast
+
+fast:
+	%4 = sub i64 %a, %b
+	ret i64 %4
+
+crash:
+	call void @llvm.trap()
+	unreachable
+
+slow:
+	%5 = call i64 @mach_sub(i64 %a, i64 %b)
+	ret i64 %5
+}
+
+define private i64 @atom.mul(i64 %a, i64 %b) alwaysinline {
+0:
+	%1 = or i64 %a, %b
+	%2 = icmp slt i64 %1, 0
+	br i1 %2, label %slow, label %check
+
+check:
+	%3 = call { i64, i1 } @llvm.umul.with.overflow.i64(i64 %a, i64 %b)
+	%4 = extractvalue { i64, i1 } %3, 0
+	%5 = extractvalue { i64, i1 } %3, 1
+	%6 = icmp slt i64 %4, 0
+	%7 = or i1 %5, %6
+	br i1 %7, label %slow, label %fast
+
+fast:
+	ret i64 %4
+
+slow:
+	%8 = call i64 @mach_mul(i64 %a, i64 %b)
+	ret i64 %8
+}
+
+define private i1 @atom.eq(i64 %a, i64 %b) alwaysinline {
+0:
+	%1 = icmp eq i64 %a, %b
+	br i1 %1, label %yes, label %check
+
+check:
+	%2 = and i64 %a, %b
+	%3 = icmp slt i64 %2, 0
+	br i1 %3, label %slow, label %no
+
+yes:
+	ret i1 true
+
+no:
+	ret i1 false
+
+slow:
+	%4 = call i1 @mach_eq(i64 %a, i64 %b)
+	ret i1 %4
+}
+
+declare void @llvm.trap()
+
+declare { i64, i1 } @llvm.umul.with.overflow.i64(i64 %0, i64 %1)
+
+declare i64 @mach_add(i64 %0, i64 %1)
+
+declare i64 @mach_sub(i64 %0, i64 %1)
+
+declare i64 @mach_mul(i64 %0, i64 %1)
+
+declare i1 @mach_eq(i64 %0, i64 %1)
+`[1:]
+
+func TestRun(t *testing.T) {
+	lli, err := exec.LookPath("lli")
+	if err != nil {
+		t.Skip("lli not found")
+	}
+	link, err := exec.LookPath("llvm-link")
+	if err != nil {
+		t.Skip("llvm-link not found")
+	}
+	dir, err := ioutil.TempDir("", "mach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, s string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	runtime := write("runtime.ll", Runtime)
+	driver := write("driver.ll", `
+declare i64 @main()
+declare void @mach_print(i64)
+
+define i32 @run() {
+	%1 = call i64 @main()
+	call void @mach_print(i64 %1)
+	ret i32 0
+}
+`)
+
+	tests := []struct {
+		hoon string
+		exp  *big.Int // printed in hex
+	}{
+		{"(add 2 3)", big.NewInt(5)},
+		{"(mul 100.000 100.000)", big.NewInt(10000000000)},
+		{"(mul (mul 100.000 100.000) (mul 100.000 100.000))", new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)},
+		{"(add 0x7fff.ffff.ffff.ffff 1)", new(big.Int).Lsh(big.NewInt(1), 63)},
+		{"(dec 0x1.0000.0000.0000.0000)", new(big.Int).SetUint64(1<<64 - 1)},
+		{"(sub 0x1.0000.0000.0000.0000 0x1.0000.0000.0000.0000)", big.NewInt(0)},
+		{`
+=/  n  1
+=/  acc  1
+|-
+?:  =(n 31)
+  acc
+$(acc (mul acc n), n +(n))
+`, new(big.Int).MulRange(1, 30)},
+		{`
+=/  a  (mul 0x1.0000.0000.0000.0000 3)
+=/  b  (add (mul 0x1.0000.0000.0000.0000 2) 0x1.0000.0000.0000.0000)
+?:  =(a b)
+  1
+0
+`, big.NewInt(1)},
+	}
+	for _, test := range tests {
+		prog := write("prog.ll", Transpile(parse(test.hoon)))
+		linked := filepath.Join(dir, "linked.bc")
+		if out, err := exec.Command(link, "-o", linked, prog, runtime, driver).CombinedOutput(); err != nil {
+			t.Fatalf("%s: %v\n%s", test.hoon, err, out)
+		}
+		out, err := exec.Command(lli, "-entry-function=run", linked).CombinedOutput()
+		if err != nil {
+			t.Errorf("%s: %v\n%s", test.hoon, err, out)
+		} else if got := strings.TrimSpace(string(out)); got != test.exp.Text(16) {
+			t.Errorf("%s: expected %x, got %s", test.hoon, test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package mach
+
+// Runtime is an LLVM IR library implementing the atoms of modules produced by
+// Transpile: the arithmetic that their fast paths fall back to, and
+// mach_print, which prints an atom in hexadecimal. It depends on calloc and
+// printf from the C library.
+const Runtime = `; An atom is an i64. Atoms below 2^63 are direct: the i64 is the atom itself.
+; Larger atoms are indirect: the i64 is a pointer to the atom, with its high
+; bit set. An indirect atom is stored as its length n, followed by its n
+; 64-bit limbs, least significant first; the most significant limb is never
+; zero. Indirect atoms are never freed.
+
+declare i8* @calloc(i64, i64)
+declare i32 @printf(i8*, ...)
+declare void @llvm.trap()
+declare { i64, i1 } @llvm.uadd.with.overflow.i64(i64, i64)
+declare { i64, i1 } @llvm.usub.with.overflow.i64(i64, i64)
+
+@mach_hex = private constant [5 x i8] c"%llx\00"
+@mach_hex16 = private constant [8 x i8] c"%016llx\00"
+@mach_newline = private constant [2 x i8] c"\0A\00"
+
+; mach_ptr returns the limbs of the indirect atom x, preceded by its length.
+define private i64* @mach_ptr(i64 %x) {
+  %m = and i64 %x, 9223372036854775807
+  %p = inttoptr i64 %m to i64*
+  ret i64* %p
+}
+
+; mach_len returns the number of limbs in x.
+define private i64 @mach_len(i64 %x) {
+entry:
+  %direct = icmp sge i64 %x, 0
+  br i1 %direct, label %one, label %indirect
+one:
+  ret i64 1
+indirect:
+  %p = call i64* @mach_ptr(i64 %x)
+  %n = load i64, i64* %p
+  ret i64 %n
+}
+
+; mach_limb returns limb i of x, or 0 if x has no such limb.
+define private i64 @mach_limb(i64 %x, i64 %i) {
+entry:
+  %direct = icmp sge i64 %x, 0
+  br i1 %direct, label %small, label %indirect
+small:
+  %first = icmp eq i64 %i, 0
+  %v = select i1 %first, i64 %x, i64 0
+  ret i64 %v
+indirect:
+  %p = call i64* @mach_ptr(i64 %x)
+  %n = load i64, i64* %p
+  %in = icmp ult i64 %i, %n
+  br i1 %in, label %load, label %zero
+load:
+  %j = add i64 %i, 1
+  %q = getelementptr i64, i64* %p, i64 %j
+  %l = load i64, i64* %q
+  ret i64 %l
+zero:
+  ret i64 0
+}
+
+; mach_alloc returns a zeroed atom of n limbs.
+define private i64* @mach_alloc(i64 %n) {
+  %size = add i64 %n, 1
+  %b = call i8* @calloc(i64 %size, i64 8)
+  %p = bitcast i8* %b to i64*
+  store i64 %n, i64* %p
+  ret i64* %p
+}
+
+; mach_norm trims the leading zero limbs of p, returning it as an atom,
+; which is direct if it is small enough.
+define private i64 @mach_norm(i64* %p) {
+entry:
+  %n = load i64, i64* %p
+  br label %trim
+trim:
+  %m = phi i64 [ %n, %entry ], [ %m1, %zero ]
+  %empty = icmp eq i64 %m, 0
+  br i1 %empty, label %null, label %check
+check:
+  %q = getelementptr i64, i64* %p, i64 %m
+  %top = load i64, i64* %q
+  %m1 = sub i64 %m, 1
+  %z = icmp eq i64 %top, 0
+  br i1 %z, label %zero, label %done
+zero:
+  br label %trim
+null:
+  ret i64 0
+done:
+  %one = icmp eq i64 %m, 1
+  %small = icmp sge i64 %top, 0
+  %direct = and i1 %one, %small
+  br i1 %direct, label %ret.direct, label %ret.indirect
+ret.direct:
+  ret i64 %top
+ret.indirect:
+  store i64 %m, i64* %p
+  %x = ptrtoint i64* %p to i64
+  %t = or i64 %x, -9223372036854775808
+  ret i64 %t
+}
+
+; mach_cmp returns -1, 0, or 1 as a is less than, equal to, or greater than b.
+define i32 @mach_cmp(i64 %a, i64 %b) {
+entry:
+  %la = call i64 @mach_len(i64 %a)
+  %lb = call i64 @mach_len(i64 %b)
+  %gt = icmp ugt i64 %la, %lb
+  %l = select i1 %gt, i64 %la, i64 %lb
+  br label %loop
+loop:
+  %i = phi i64 [ %l, %entry ], [ %i1, %next ]
+  %end = icmp eq i64 %i, 0
+  br i1 %end, label %equal, label %body
+body:
+  %i1 = sub i64 %i, 1
+  %x = call i64 @mach_limb(i64 %a, i64 %i1)
+  %y = call i64 @mach_limb(i64 %b, i64 %i1)
+  %less = icmp ult i64 %x, %y
+  br i1 %less, label %lt, label %notless
+notless:
+  %more = icmp ugt i64 %x, %y
+  br i1 %more, label %gt.ret, label %next
+next:
+  br label %loop
+lt:
+  ret i32 -1
+gt.ret:
+  ret i32 1
+equal:
+  ret i32 0
+}
+
+; mach_eq reports whether a and b are equal.
+define i1 @mach_eq(i64 %a, i64 %b) {
+  %c = call i32 @mach_cmp(i64 %a, i64 %b)
+  %eq = icmp eq i32 %c, 0
+  ret i1 %eq
+}
+
+; mach_add returns a + b.
+define i64 @mach_add(i64 %a, i64 %b) {
+entry:
+  %la = call i64 @mach_len(i64 %a)
+  %lb = call i64 @mach_len(i64 %b)
+  %gt = icmp ugt i64 %la, %lb
+  %l = select i1 %gt, i64 %la, i64 %lb
+  %n = add i64 %l, 1
+  %p = call i64* @mach_alloc(i64 %n)
+  br label %loop
+loop:
+  %i = phi i64 [ 0, %entry ], [ %i1, %loop ]
+  %c = phi i64 [ 0, %entry ], [ %c1, %loop ]
+  %x = call i64 @mach_limb(i64 %a, i64 %i)
+  %y = call i64 @mach_limb(i64 %b, i64 %i)
+  %s = call { i64, i1 } @llvm.uadd.with.overflow.i64(i64 %x, i64 %y)
+  %sv = extractvalue { i64, i1 } %s, 0
+  %so = extractvalue { i64, i1 } %s, 1
+  %t = call { i64, i1 } @llvm.uadd.with.overflow.i64(i64 %sv, i64 %c)
+  %tv = extractvalue { i64, i1 } %t, 0
+  %to = extractvalue { i64, i1 } %t, 1
+  %o = or i1 %so, %to
+  %c1 = zext i1 %o to i64
+  %i1 = add i64 %i, 1
+  %q = getelementptr i64, i64* %p, i64 %i1
+  store i64 %tv, i64* %q
+  %more = icmp ult i64 %i1, %n
+  br i1 %more, label %loop, label %done
+done:
+  %r = call i64 @mach_norm(i64* %p)
+  ret i64 %r
+}
+
+; mach_sub returns a - b, crashing if b is greater than a.
+define i64 @mach_sub(i64 %a, i64 %b) {
+entry:
+  %cmp = call i32 @mach_cmp(i64 %a, i64 %b)
+  %neg = icmp slt i32 %cmp, 0
+  br i1 %neg, label %crash, label %start
+crash:
+  call void @llvm.trap()
+  unreachable
+start:
+  %n = call i64 @mach_len(i64 %a)
+  %p = call i64* @mach_alloc(i64 %n)
+  br label %loop
+loop:
+  %i = phi i64 [ 0, %start ], [ %i1, %loop ]
+  %c = phi i64 [ 0, %start ], [ %c1, %loop ]
+  %x = call i64 @mach_limb(i64 %a, i64 %i)
+  %y = call i64 @mach_limb(i64 %b, i64 %i)
+  %s = call { i64, i1 } @llvm.usub.with.overflow.i64(i64 %x, i64 %y)
+  %sv = extractvalue { i64, i1 } %s, 0
+  %so = extractvalue { i64, i1 } %s, 1
+  %t = call { i64, i1 } @llvm.usub.with.overflow.i64(i64 %sv, i64 %c)
+  %tv = extractvalue { i64, i1 } %t, 0
+  %to = extractvalue { i64, i1 } %t, 1
+  %o = or i1 %so, %to
+  %c1 = zext i1 %o to i64
+  %i1 = add i64 %i, 1
+  %q = getelementptr i64, i64* %p, i64 %i1
+  store i64 %tv, i64* %q
+  %more = icmp ult i64 %i1, %n
+  br i1 %more, label %loop, label %done
+done:
+  %r = call i64 @mach_norm(i64* %p)
+  ret i64 %r
+}
+
+; mach_mul returns a * b.
+define i64 @mach_mul(i64 %a, i64 %b) {
+entry:
+  %la = call i64 @mach_len(i64 %a)
+  %lb = call i64 @mach_len(i64 %b)
+  %n = add i64 %la, %lb
+  %p = call i64* @mach_alloc(i64 %n)
+  br label %outer
+outer:
+  %i = phi i64 [ 0, %entry ], [ %i1, %row ]
+  %x = call i64 @mach_limb(i64 %a, i64 %i)
+  %xw = zext i64 %x to i128
+  br label %inner
+inner:
+  %j = phi i64 [ 0, %outer ], [ %j1, %inner ]
+  %c = phi i128 [ 0, %outer ], [ %c1, %inner ]
+  %y = call i64 @mach_limb(i64 %b, i64 %j)
+  %yw = zext i64 %y to i128
+  %ij = add i64 %i, %j
+  %k = add i64 %ij, 1
+  %q = getelementptr i64, i64* %p, i64 %k
+  %r = load i64, i64* %q
+  %rw = zext i64 %r to i128
+  %prod = mul i128 %xw, %yw
+  %s0 = add i128 %prod, %rw
+  %s = add i128 %s0, %c
+  %lo = trunc i128 %s to i64
+  store i64 %lo, i64* %q
+  %c1 = lshr i128 %s, 64
+  %j1 = add i64 %j, 1
+  %more = icmp ult i64 %j1, %lb
+  br i1 %more, label %inner, label %row
+row:
+  %ilb = add i64 %i, %lb
+  %k2 = add i64 %ilb, 1
+  %q2 = getelementptr i64, i64* %p, i64 %k2
+  %hi = trunc i128 %c1 to i64
+  store i64 %hi, i64* %q2
+  %i1 = add i64 %i, 1
+  %more2 = icmp ult i64 %i1, %la
+  br i1 %more2, label %outer, label %done
+done:
+  %res = call i64 @mach_norm(i64* %p)
+  ret i64 %res
+}
+
+; mach_print prints a in hexadecimal, followed by a newline.
+define void @mach_print(i64 %a) {
+entry:
+  %n = call i64 @mach_len(i64 %a)
+  %top = sub i64 %n, 1
+  %x = call i64 @mach_limb(i64 %a, i64 %top)
+  %hex = getelementptr [5 x i8], [5 x i8]* @mach_hex, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %hex, i64 %x)
+  br label %loop
+loop:
+  %i = phi i64 [ %top, %entry ], [ %i1, %body ]
+  %end = icmp eq i64 %i, 0
+  br i1 %end, label %done, label %body
+body:
+  %i1 = sub i64 %i, 1
+  %y = call i64 @mach_limb(i64 %a, i64 %i1)
+  %hex16 = getelementptr [8 x i8], [8 x i8]* @mach_hex16, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %hex16, i64 %y)
+  br label %loop
+done:
+  %nl = getelementptr [2 x i8], [2 x i8]* @mach_newline, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %nl)
+  ret void
+}
+`
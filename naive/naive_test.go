@@ -0,0 +1,278 @@
+package naive
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lukechampine.com/urbit/azimuth"
+	"lukechampine.com/urbit/ob"
+)
+
+func TestPersonalSign(t *testing.T) {
+	// from the web3.js documentation for eth.accounts.sign
+	key, _ := hex.DecodeString("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	sig := signHash(personalHash([]byte("Some data")), key)
+	exp := "b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c"
+	if hex.EncodeToString(sig[:]) != exp {
+		t.Errorf("wrong signature: got %x", sig)
+	}
+	if a := KeyAddress(key).String(); a != strings.ToLower("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23") {
+		t.Errorf("wrong address: got %v", a)
+	}
+}
+
+func TestTxRoundTrip(t *testing.T) {
+	addr := azimuth.Address{1, 2, 3}
+	actions := []Action{
+		TransferPoint{Address: addr, Reset: true},
+		TransferPoint{Address: addr},
+		Spawn{Ship: 65792, Address: addr},
+		ConfigureKeys{Encrypt: [32]byte{4}, Auth: [32]byte{5}, CryptoSuite: 1, Breach: true},
+		Escape{Parent: 512},
+		CancelEscape{Parent: 512},
+		Adopt{Ship: 65792},
+		Reject{Ship: 65792},
+		Detach{Ship: 65792},
+		SetManagementProxy{Address: addr},
+		SetSpawnProxy{Address: addr},
+		SetTransferProxy{Address: addr},
+	}
+	var txs []RawTx
+	for i, a := range actions {
+		tx := Tx{Ship: 256, Proxy: Proxy(i % 5), Action: a}
+		b, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tx2 Tx
+		if err := tx2.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(tx, tx2) {
+			t.Errorf("round trip failed: %v != %v", tx, tx2)
+		}
+		txs = append(txs, RawTx{Sig: [65]byte{byte(i)}, Tx: tx})
+	}
+	batch, err := EncodeBatch(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs2, err := DecodeBatch(batch)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(txs, txs2) {
+		t.Error("batch round trip failed")
+	}
+	if _, err := DecodeBatch(batch[:len(batch)-1]); err == nil {
+		t.Error("expected error for truncated batch")
+	}
+
+	bad := [][]byte{
+		{},
+		{0, 0, 0, 1, 0, 11},               // unknown opcode
+		{5, 0, 0, 1, 0, 3, 0, 0, 2, 0},    // invalid proxy
+		{0, 0, 0, 1, 0, 3, 0, 0, 2, 0, 0}, // trailing bytes
+	}
+	for _, b := range bad {
+		var tx Tx
+		if err := tx.UnmarshalBinary(b); err == nil {
+			t.Errorf("expected error for %x", b)
+		}
+	}
+}
+
+func TestTxLayout(t *testing.T) {
+	// assembled by hand, following parse-tx in naive.hoon
+	addr := azimuth.Address{0xAA, 19: 0xBB}
+	addrBytes := append([]byte(nil), addr[:]...)
+	cat := func(bs ...[]byte) []byte { return bytes.Join(bs, nil) }
+	var enc, auth [32]byte
+	copy(enc[:], bytes.Repeat([]byte{1}, 32))
+	copy(auth[:], bytes.Repeat([]byte{2}, 32))
+	tests := []struct {
+		b  []byte
+		tx Tx
+	}{
+		{
+			cat([]byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x01, 0x01, 0x00}, addrBytes),
+			Tx{Ship: 256, Proxy: ProxyOwn, Action: Spawn{Ship: 65792, Address: addr}},
+		},
+		{
+			cat([]byte{0x04, 0x00, 0x01, 0x01, 0x00, 0x80}, addrBytes),
+			Tx{Ship: 65792, Proxy: ProxyTransfer, Action: TransferPoint{Address: addr, Reset: true}},
+		},
+		{
+			cat([]byte{0x02, 0x00, 0x00, 0x01, 0x00, 0x82}, enc[:], auth[:], []byte{0, 0, 0, 1}),
+			Tx{Ship: 256, Proxy: ProxyManage, Action: ConfigureKeys{Encrypt: enc, Auth: auth, CryptoSuite: 1, Breach: true}},
+		},
+		{
+			[]byte{0x00, 0x00, 0x01, 0x01, 0x00, 0x03, 0x00, 0x00, 0x02, 0x00},
+			Tx{Ship: 65792, Proxy: ProxyOwn, Action: Escape{Parent: 512}},
+		},
+	}
+	for _, test := range tests {
+		if b, err := test.tx.MarshalBinary(); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(b, test.b) {
+			t.Errorf("%+v: expected %x, got %x", test.tx, test.b, b)
+		}
+		var tx Tx
+		if err := tx.UnmarshalBinary(test.b); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(tx, test.tx) {
+			t.Errorf("%x: expected %+v, got %+v", test.b, test.tx, tx)
+		}
+	}
+
+	// the high bits of the proxy byte are padding
+	var tx Tx
+	if err := tx.UnmarshalBinary([]byte{0xF9, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x02, 0x00}); err != nil {
+		t.Fatal(err)
+	} else if tx.Proxy != ProxySpawn {
+		t.Errorf("expected spawn proxy, got %v", tx.Proxy)
+	}
+}
+
+var (
+	starOwner  = bytes.Repeat([]byte{1}, 32)
+	starManage = bytes.Repeat([]byte{2}, 32)
+	planetKey  = bytes.Repeat([]byte{3}, 32)
+	otherStar  = bytes.Repeat([]byte{4}, 32)
+)
+
+const (
+	marzod = ob.AzimuthPoint(256)
+	binzod = ob.AzimuthPoint(512)
+	planet = ob.AzimuthPoint(65792) // ~wicdev-wisryt, child of ~marzod
+)
+
+func testState() *State {
+	s := NewState(ChainLocal)
+	s.Points[marzod] = &Point{Dominion: DominionL2, Owner: KeyAddress(starOwner)}
+	s.Points[binzod] = &Point{Dominion: DominionL2, Owner: KeyAddress(otherStar)}
+	return s
+}
+
+func testBatch(t *testing.T) []RawTx {
+	sign := func(ship ob.AzimuthPoint, proxy Proxy, a Action, nonce uint32, key []byte) RawTx {
+		t.Helper()
+		rtx, err := Sign(Tx{Ship: ship, Proxy: proxy, Action: a}, nonce, ChainLocal, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rtx
+	}
+	keys := ConfigureKeys{Encrypt: [32]byte{1}, Auth: [32]byte{2}, CryptoSuite: 1}
+	return []RawTx{
+		sign(marzod, ProxyOwn, SetManagementProxy{KeyAddress(starManage)}, 0, starOwner),
+		sign(marzod, ProxyOwn, Spawn{planet, KeyAddress(planetKey)}, 1, starOwner),
+		sign(marzod, ProxyManage, keys, 0, starManage),
+		sign(planet, ProxyTransfer, TransferPoint{KeyAddress(planetKey), false}, 0, planetKey),
+		sign(planet, ProxyOwn, keys, 0, planetKey),
+		sign(planet, ProxyOwn, Escape{binzod}, 0, planetKey), // wrong nonce
+		sign(planet, ProxyOwn, Escape{binzod}, 1, planetKey),
+		sign(marzod, ProxyOwn, Adopt{planet}, 2, starOwner), // not escaping to marzod
+		sign(binzod, ProxyOwn, Adopt{planet}, 0, otherStar),
+		sign(marzod, ProxyManage, SetSpawnProxy{azimuth.Address{9}}, 1, starManage), // unauthorized
+		sign(planet, ProxyOwn, TransferPoint{KeyAddress(otherStar), true}, 2, planetKey),
+	}
+}
+
+func TestApplyBatch(t *testing.T) {
+	golden, err := os.ReadFile("testdata/batch.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stripped strings.Builder
+	for _, line := range strings.Split(string(golden), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			stripped.WriteString(strings.Join(strings.Fields(line), ""))
+		}
+	}
+	batch, err := hex.DecodeString(stripped.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txs, err := DecodeBatch(batch); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(txs, testBatch(t)) {
+		t.Fatal("testdata/batch.hex does not decode to the expected txs")
+	}
+
+	s := testState()
+	errs, err := s.ApplyBatch(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, err := range errs {
+		wantErr := i == 5 || i == 7 || i == 9
+		if (err != nil) != wantErr {
+			t.Errorf("tx %v: unexpected result %v", i, err)
+		}
+	}
+	if !errors.Is(errs[5], ErrInvalidSignature) {
+		t.Errorf("expected invalid signature, got %v", errs[5])
+	}
+
+	star := s.Points[marzod]
+	if star.Nonces != [5]uint32{3, 0, 2, 0, 0} {
+		t.Errorf("wrong star nonces: %v", star.Nonces)
+	} else if star.Life != 1 || star.Auth != [32]byte{2} || star.SpawnProxy != (azimuth.Address{}) {
+		t.Errorf("wrong star state: %+v", star)
+	}
+	p := s.Points[planet]
+	if p.Owner != KeyAddress(otherStar) || p.TransferProxy != (azimuth.Address{}) || p.Nonces != [5]uint32{3, 0, 0, 0, 1} {
+		t.Errorf("wrong planet ownership: %+v", p)
+	} else if p.Sponsor != binzod || !p.HasSponsor || p.HasEscape {
+		t.Errorf("wrong planet sponsorship: %+v", p)
+	} else if p.Life != 2 || p.Rift != 1 || p.Auth != ([32]byte{}) {
+		t.Errorf("planet keys were not reset: %+v", p)
+	}
+	if got := s.SortedPoints(); !reflect.DeepEqual(got, []ob.AzimuthPoint{marzod, binzod, planet}) {
+		t.Errorf("wrong points: %v", got)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	s := testState()
+	s.Points[ob.AzimuthPoint(768)] = &Point{Dominion: DominionSpawn, Owner: KeyAddress(starOwner)}
+	tests := []struct {
+		ship  ob.AzimuthPoint
+		proxy Proxy
+		a     Action
+	}{
+		{ob.AzimuthPoint(1), ProxyOwn, SetManagementProxy{}},   // not on L2
+		{marzod, ProxyVote, SetManagementProxy{}},              // voting proxy
+		{marzod, ProxyTransfer, SetManagementProxy{}},          // no transfer proxy
+		{marzod, ProxyOwn, Spawn{Ship: 65793}},                 // not a child
+		{marzod, ProxyOwn, Escape{Parent: binzod}},             // stars escape to galaxies
+		{marzod, ProxyOwn, CancelEscape{}},                     // no escape
+		{marzod, ProxyOwn, Detach{Ship: planet}},               // not spawned
+		{ob.AzimuthPoint(768), ProxyOwn, SetManagementProxy{}}, // spawn dominion
+		{marzod, ProxyOwn, Reject{Ship: binzod}},               // not escaping
+	}
+	for i, test := range tests {
+		pt, ok := s.Points[test.ship]
+		var nonce uint32
+		if ok && test.proxy <= ProxyTransfer {
+			nonce = pt.Nonces[test.proxy]
+		}
+		rtx, err := Sign(Tx{Ship: test.ship, Proxy: test.proxy, Action: test.a}, nonce, ChainLocal, starOwner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Apply(rtx); err == nil {
+			t.Errorf("%v: expected error", i)
+		}
+	}
+
+	// signatures are bound to the chain ID
+	rtx, _ := Sign(Tx{Ship: marzod, Proxy: ProxyOwn, Action: SetManagementProxy{}}, s.Points[marzod].Nonces[ProxyOwn], ChainMainnet, starOwner)
+	if err := s.Apply(rtx); err != ErrInvalidSignature {
+		t.Errorf("expected invalid signature, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package naive
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/urbit/azimuth"
+)
+
+// Chain IDs of the networks on which the rollup is deployed.
+const (
+	ChainMainnet = 1
+	ChainRopsten = 3
+	ChainLocal   = 1337
+)
+
+// SigningMessage returns the message that is signed to authorize tx: the
+// string "UrbitIDV1Chain", the decimal chain ID, a colon, the big-endian
+// 4-byte nonce, and the encoded tx.
+func SigningMessage(tx Tx, nonce uint32, chainID uint64) ([]byte, error) {
+	b, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	msg := []byte("UrbitIDV1Chain" + strconv.FormatUint(chainID, 10) + ":")
+	msg = appendUint32(msg, nonce)
+	return append(msg, b...), nil
+}
+
+// personalHash returns the hash signed by Ethereum's personal_sign method.
+func personalHash(msg []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte("\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))))
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// Sign signs tx with the provided secp256k1 private key.
+func Sign(tx Tx, nonce uint32, chainID uint64, key []byte) (RawTx, error) {
+	msg, err := SigningMessage(tx, nonce, chainID)
+	if err != nil {
+		return RawTx{}, err
+	} else if len(key) != 32 {
+		return RawTx{}, errors.New("private key must be 32 bytes")
+	}
+	return RawTx{Sig: signHash(personalHash(msg), key), Tx: tx}, nil
+}
+
+func signHash(hash, key []byte) (sig [65]byte) {
+	sk := secp256k1.PrivKeyFromBytes(key)
+	defer sk.Zero()
+	// SignCompact returns v || r || s; Ethereum uses r || s || v
+	compact := ecdsa.SignCompact(sk, hash, false)
+	copy(sig[:64], compact[1:])
+	sig[64] = compact[0]
+	return
+}
+
+// Signer returns the address that signed rtx.
+func (rtx RawTx) Signer(nonce uint32, chainID uint64) (azimuth.Address, error) {
+	msg, err := SigningMessage(rtx.Tx, nonce, chainID)
+	if err != nil {
+		return azimuth.Address{}, err
+	}
+	v := rtx.Sig[64]
+	if v < 27 {
+		v += 27 // some signers use 0/1 instead of 27/28
+	}
+	if v != 27 && v != 28 {
+		return azimuth.Address{}, errors.New("invalid signature recovery byte")
+	}
+	compact := append([]byte{v}, rtx.Sig[:64]...)
+	pub, _, err := ecdsa.RecoverCompact(compact, personalHash(msg))
+	if err != nil {
+		return azimuth.Address{}, err
+	}
+	return pubkeyAddress(pub), nil
+}
+
+// KeyAddress returns the Ethereum address of a secp256k1 private key.
+func KeyAddress(key []byte) azimuth.Address {
+	return pubkeyAddress(secp256k1.PrivKeyFromBytes(key).PubKey())
+}
+
+func pubkeyAddress(pub *secp256k1.PublicKey) (a azimuth.Address) {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(pub.SerializeUncompressed()[1:])
+	copy(a[:], h.Sum(nil)[12:])
+	return
+}
@@ -0,0 +1,274 @@
+package naive
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"lukechampine.com/urbit/azimuth"
+	"lukechampine.com/urbit/ob"
+)
+
+// A Dominion indicates which layer controls a point.
+type Dominion uint8
+
+// Dominions.
+const (
+	DominionL1    Dominion = iota // controlled entirely by the Azimuth contract
+	DominionSpawn                 // spawns on L2, otherwise controlled by L1
+	DominionL2                    // controlled entirely by the rollup
+)
+
+// A Point is the rollup state of a single point.
+type Point struct {
+	Dominion Dominion
+
+	// ownership
+	Owner           azimuth.Address
+	SpawnProxy      azimuth.Address
+	ManagementProxy azimuth.Address
+	VotingProxy     azimuth.Address
+	TransferProxy   azimuth.Address
+	Nonces          [5]uint32 // indexed by Proxy
+
+	// networking keys
+	Rift  uint32
+	Life  uint32
+	Suite uint32
+	Auth  [32]byte
+	Crypt [32]byte
+
+	// sponsorship
+	Sponsor    ob.AzimuthPoint
+	HasSponsor bool
+	Escape     ob.AzimuthPoint
+	HasEscape  bool
+}
+
+// Address returns the address of the specified proxy.
+func (p *Point) Address(proxy Proxy) azimuth.Address {
+	switch proxy {
+	case ProxyOwn:
+		return p.Owner
+	case ProxySpawn:
+		return p.SpawnProxy
+	case ProxyManage:
+		return p.ManagementProxy
+	case ProxyVote:
+		return p.VotingProxy
+	case ProxyTransfer:
+		return p.TransferProxy
+	}
+	return azimuth.Address{}
+}
+
+// PointFromAzimuth returns the rollup state of a point deposited from L1.
+func PointFromAzimuth(ps *azimuth.PointState, d Dominion) *Point {
+	return &Point{
+		Dominion:        d,
+		Owner:           ps.Owner,
+		SpawnProxy:      ps.SpawnProxy,
+		ManagementProxy: ps.ManagementProxy,
+		VotingProxy:     ps.VotingProxy,
+		TransferProxy:   ps.TransferProxy,
+		Rift:            ps.ContinuityNumber,
+		Life:            ps.KeyRevision,
+		Suite:           ps.CryptoSuite,
+		Auth:            ps.AuthenticationKey,
+		Crypt:           ps.EncryptionKey,
+		Sponsor:         ps.Sponsor,
+		HasSponsor:      ps.HasSponsor,
+		Escape:          ps.EscapeRequestedTo,
+		HasEscape:       ps.EscapeRequested,
+	}
+}
+
+// ErrInvalidSignature is returned by Apply when a transaction was not signed
+// by the specified proxy of its point, or was signed with the wrong nonce.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// A State is a table of points, built by applying rollup transactions in
+// order.
+type State struct {
+	ChainID uint64
+	Points  map[ob.AzimuthPoint]*Point
+}
+
+// NewState returns an empty State for the specified chain.
+func NewState(chainID uint64) *State {
+	return &State{
+		ChainID: chainID,
+		Points:  make(map[ob.AzimuthPoint]*Point),
+	}
+}
+
+// SortedPoints returns the points in s, in ascending order.
+func (s *State) SortedPoints() []ob.AzimuthPoint {
+	ps := make([]ob.AzimuthPoint, 0, len(s.Points))
+	for p := range s.Points {
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i] < ps[j] })
+	return ps
+}
+
+// Apply applies rtx to s. If the signature is valid, the signing proxy's nonce
+// is incremented, even if the transaction itself is then rejected.
+func (s *State) Apply(rtx RawTx) error {
+	tx := rtx.Tx
+	pt, ok := s.Points[tx.Ship]
+	if !ok {
+		return fmt.Errorf("%v is not on L2", tx.Ship)
+	} else if tx.Proxy > ProxyTransfer || tx.Proxy == ProxyVote {
+		return fmt.Errorf("proxy %v cannot sign L2 transactions", tx.Proxy)
+	}
+	addr := pt.Address(tx.Proxy)
+	if addr == (azimuth.Address{}) {
+		return fmt.Errorf("%v has no %v proxy", tx.Ship, tx.Proxy)
+	}
+	if signer, err := rtx.Signer(pt.Nonces[tx.Proxy], s.ChainID); err != nil || signer != addr {
+		return ErrInvalidSignature
+	}
+	pt.Nonces[tx.Proxy]++
+
+	if !canSign(tx.Action, tx.Proxy) {
+		return fmt.Errorf("proxy %v cannot perform %T", tx.Proxy, tx.Action)
+	}
+	switch pt.Dominion {
+	case DominionL1:
+		return fmt.Errorf("%v is not on L2", tx.Ship)
+	case DominionSpawn:
+		switch tx.Action.(type) {
+		case Spawn, SetSpawnProxy:
+		default:
+			return fmt.Errorf("%v is only on L2 for spawning", tx.Ship)
+		}
+	}
+
+	switch a := tx.Action.(type) {
+	case TransferPoint:
+		pt.Owner = a.Address
+		pt.TransferProxy = azimuth.Address{}
+		if a.Reset {
+			if pt.Life != 0 {
+				pt.Life++
+				pt.Rift++
+				pt.Suite, pt.Auth, pt.Crypt = 0, [32]byte{}, [32]byte{}
+			}
+			pt.SpawnProxy = azimuth.Address{}
+			pt.ManagementProxy = azimuth.Address{}
+			pt.VotingProxy = azimuth.Address{}
+		}
+
+	case Spawn:
+		if a.Ship.IsGalaxy() || a.Ship.Parent() != tx.Ship {
+			return fmt.Errorf("%v cannot spawn %v", tx.Ship, a.Ship)
+		} else if _, ok := s.Points[a.Ship]; ok {
+			return fmt.Errorf("%v has already been spawned", a.Ship)
+		}
+		child := &Point{
+			Dominion:   DominionL2,
+			Owner:      a.Address,
+			Sponsor:    tx.Ship,
+			HasSponsor: true,
+		}
+		if a.Address != addr {
+			// spawning to someone else makes them the transfer proxy
+			child.Owner = addr
+			child.TransferProxy = a.Address
+		}
+		s.Points[a.Ship] = child
+
+	case ConfigureKeys:
+		if a.Encrypt != pt.Crypt || a.Auth != pt.Auth || a.CryptoSuite != pt.Suite {
+			pt.Life++
+			pt.Crypt, pt.Auth, pt.Suite = a.Encrypt, a.Auth, a.CryptoSuite
+		}
+		if a.Breach {
+			pt.Rift++
+		}
+
+	case Escape:
+		if !canSponsor(a.Parent, tx.Ship) {
+			return fmt.Errorf("%v cannot escape to %v", tx.Ship, a.Parent)
+		}
+		pt.Escape, pt.HasEscape = a.Parent, true
+
+	case CancelEscape:
+		if !pt.HasEscape {
+			return fmt.Errorf("%v has no escape to cancel", tx.Ship)
+		}
+		pt.Escape, pt.HasEscape = 0, false
+
+	case Adopt:
+		child, ok := s.Points[a.Ship]
+		if !ok || !child.HasEscape || child.Escape != tx.Ship {
+			return fmt.Errorf("%v has not escaped to %v", a.Ship, tx.Ship)
+		}
+		child.Sponsor, child.HasSponsor = tx.Ship, true
+		child.Escape, child.HasEscape = 0, false
+
+	case Reject:
+		child, ok := s.Points[a.Ship]
+		if !ok || !child.HasEscape || child.Escape != tx.Ship {
+			return fmt.Errorf("%v has not escaped to %v", a.Ship, tx.Ship)
+		}
+		child.Escape, child.HasEscape = 0, false
+
+	case Detach:
+		child, ok := s.Points[a.Ship]
+		if !ok || !child.HasSponsor || child.Sponsor != tx.Ship {
+			return fmt.Errorf("%v is not sponsored by %v", a.Ship, tx.Ship)
+		}
+		child.HasSponsor = false
+
+	case SetManagementProxy:
+		pt.ManagementProxy = a.Address
+
+	case SetSpawnProxy:
+		if tx.Ship.IsPlanet() {
+			return fmt.Errorf("%v cannot spawn", tx.Ship)
+		}
+		pt.SpawnProxy = a.Address
+
+	case SetTransferProxy:
+		pt.TransferProxy = a.Address
+	}
+	return nil
+}
+
+// ApplyBatch decodes batch and applies each transaction to s, returning the
+// result of each. Invalid transactions are skipped, but an error decoding the
+// batch causes the entire batch to be rejected.
+func (s *State) ApplyBatch(batch []byte) ([]error, error) {
+	txs, err := DecodeBatch(batch)
+	if err != nil {
+		return nil, err
+	}
+	errs := make([]error, len(txs))
+	for i, rtx := range txs {
+		errs[i] = s.Apply(rtx)
+	}
+	return errs, nil
+}
+
+// canSign reports whether proxy is permitted to perform a.
+func canSign(a Action, proxy Proxy) bool {
+	if proxy == ProxyOwn {
+		return true
+	}
+	switch a.(type) {
+	case TransferPoint, SetTransferProxy:
+		return proxy == ProxyTransfer
+	case Spawn, SetSpawnProxy:
+		return proxy == ProxySpawn
+	default:
+		return proxy == ProxyManage
+	}
+}
+
+// canSponsor reports whether parent is of the appropriate rank to sponsor
+// child.
+func canSponsor(parent, child ob.AzimuthPoint) bool {
+	return (child.IsPlanet() && parent.IsStar()) || (child.IsStar() && parent.IsGalaxy())
+}
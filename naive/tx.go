@@ -0,0 +1,294 @@
+// Package naive implements the "naive rollup" (Layer 2) of Azimuth: encoding,
+// decoding, and signing of transaction batches, and a state machine that
+// applies them to a table of points.
+package naive
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"lukechampine.com/urbit/azimuth"
+	"lukechampine.com/urbit/ob"
+)
+
+// A Proxy identifies the role under which a transaction is signed.
+type Proxy uint8
+
+// Proxies.
+const (
+	ProxyOwn Proxy = iota
+	ProxySpawn
+	ProxyManage
+	ProxyVote
+	ProxyTransfer
+)
+
+// String implements fmt.Stringer.
+func (p Proxy) String() string {
+	switch p {
+	case ProxyOwn:
+		return "own"
+	case ProxySpawn:
+		return "spawn"
+	case ProxyManage:
+		return "manage"
+	case ProxyVote:
+		return "vote"
+	case ProxyTransfer:
+		return "transfer"
+	}
+	return fmt.Sprintf("Proxy(%d)", uint8(p))
+}
+
+// An Action is the operation performed by a transaction.
+type Action interface {
+	opcode() (op uint8, flag bool)
+}
+
+func (a TransferPoint) opcode() (uint8, bool)    { return 0, a.Reset }
+func (Spawn) opcode() (uint8, bool)              { return 1, false }
+func (a ConfigureKeys) opcode() (uint8, bool)    { return 2, a.Breach }
+func (Escape) opcode() (uint8, bool)             { return 3, false }
+func (CancelEscape) opcode() (uint8, bool)       { return 4, false }
+func (Adopt) opcode() (uint8, bool)              { return 5, false }
+func (Reject) opcode() (uint8, bool)             { return 6, false }
+func (Detach) opcode() (uint8, bool)             { return 7, false }
+func (SetManagementProxy) opcode() (uint8, bool) { return 8, false }
+func (SetSpawnProxy) opcode() (uint8, bool)      { return 9, false }
+func (SetTransferProxy) opcode() (uint8, bool)   { return 10, false }
+
+// TransferPoint transfers the signing point to a new owner, optionally
+// resetting its keys and proxies.
+type TransferPoint struct {
+	Address azimuth.Address
+	Reset   bool
+}
+
+// Spawn spawns a child of the signing point.
+type Spawn struct {
+	Ship    ob.AzimuthPoint
+	Address azimuth.Address
+}
+
+// ConfigureKeys sets the networking keys of the signing point, optionally
+// breaching (incrementing its continuity number).
+type ConfigureKeys struct {
+	Encrypt     [32]byte
+	Auth        [32]byte
+	CryptoSuite uint32
+	Breach      bool
+}
+
+// Escape requests a new sponsor for the signing point.
+type Escape struct {
+	Parent ob.AzimuthPoint
+}
+
+// CancelEscape cancels the signing point's escape request.
+type CancelEscape struct {
+	Parent ob.AzimuthPoint
+}
+
+// Adopt accepts a point's escape request to the signing point.
+type Adopt struct {
+	Ship ob.AzimuthPoint
+}
+
+// Reject rejects a point's escape request to the signing point.
+type Reject struct {
+	Ship ob.AzimuthPoint
+}
+
+// Detach stops the signing point from sponsoring a point.
+type Detach struct {
+	Ship ob.AzimuthPoint
+}
+
+// SetManagementProxy sets the management proxy of the signing point.
+type SetManagementProxy struct {
+	Address azimuth.Address
+}
+
+// SetSpawnProxy sets the spawn proxy of the signing point.
+type SetSpawnProxy struct {
+	Address azimuth.Address
+}
+
+// SetTransferProxy sets the transfer proxy of the signing point.
+type SetTransferProxy struct {
+	Address azimuth.Address
+}
+
+// A Tx is an unsigned transaction.
+type Tx struct {
+	Ship   ob.AzimuthPoint
+	Proxy  Proxy
+	Action Action
+}
+
+// A RawTx is a signed transaction.
+type RawTx struct {
+	Sig [65]byte // r || s || v
+	Tx  Tx
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. As in naive.hoon, the
+// encoding is one byte holding the proxy (low 3 bits, with the rest padding),
+// followed by the signing ship (4 bytes), one byte holding the opcode (low 7
+// bits) and flag (high bit), and the action's fields. All integers are
+// big-endian.
+func (tx Tx) MarshalBinary() ([]byte, error) {
+	op, flag := tx.Action.opcode()
+	if flag {
+		op |= 0x80
+	}
+	b := []byte{byte(tx.Proxy)}
+	b = appendUint32(b, uint32(tx.Ship))
+	b = append(b, op)
+	switch a := tx.Action.(type) {
+	case TransferPoint:
+		b = append(b, a.Address[:]...)
+	case Spawn:
+		b = appendUint32(b, uint32(a.Ship))
+		b = append(b, a.Address[:]...)
+	case ConfigureKeys:
+		b = append(b, a.Encrypt[:]...)
+		b = append(b, a.Auth[:]...)
+		b = appendUint32(b, a.CryptoSuite)
+	case Escape:
+		b = appendUint32(b, uint32(a.Parent))
+	case CancelEscape:
+		b = appendUint32(b, uint32(a.Parent))
+	case Adopt:
+		b = appendUint32(b, uint32(a.Ship))
+	case Reject:
+		b = appendUint32(b, uint32(a.Ship))
+	case Detach:
+		b = appendUint32(b, uint32(a.Ship))
+	case SetManagementProxy:
+		b = append(b, a.Address[:]...)
+	case SetSpawnProxy:
+		b = append(b, a.Address[:]...)
+	case SetTransferProxy:
+		b = append(b, a.Address[:]...)
+	default:
+		return nil, fmt.Errorf("unknown action type %T", a)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (tx *Tx) UnmarshalBinary(b []byte) error {
+	d := decoder{b: b}
+	d.decodeTx(tx)
+	if d.err == nil && len(d.b) != 0 {
+		d.err = errors.New("trailing bytes after tx")
+	}
+	return d.err
+}
+
+// EncodeBatch encodes a batch of signed transactions.
+func EncodeBatch(txs []RawTx) ([]byte, error) {
+	var batch []byte
+	for _, rtx := range txs {
+		b, err := rtx.Tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, rtx.Sig[:]...)
+		batch = append(batch, b...)
+	}
+	return batch, nil
+}
+
+// DecodeBatch decodes a batch of signed transactions.
+func DecodeBatch(batch []byte) ([]RawTx, error) {
+	d := decoder{b: batch}
+	var txs []RawTx
+	for len(d.b) > 0 && d.err == nil {
+		var rtx RawTx
+		copy(rtx.Sig[:], d.take(len(rtx.Sig)))
+		d.decodeTx(&rtx.Tx)
+		txs = append(txs, rtx)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("tx %v: %w", len(txs)-1, d.err)
+	}
+	return txs, nil
+}
+
+func appendUint32(b []byte, u uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], u)
+	return append(b, buf[:]...)
+}
+
+// decoder decodes fields from a byte stream. The first error encountered is
+// stored in err.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return make([]byte, n)
+	} else if len(d.b) < n {
+		d.err = errors.New("unexpected end of batch")
+		return make([]byte, n)
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) point() ob.AzimuthPoint { return ob.AzimuthPoint(binary.BigEndian.Uint32(d.take(4))) }
+
+func (d *decoder) address() (a azimuth.Address) {
+	copy(a[:], d.take(len(a)))
+	return
+}
+
+func (d *decoder) key() (k [32]byte) {
+	copy(k[:], d.take(len(k)))
+	return
+}
+
+func (d *decoder) decodeTx(tx *Tx) {
+	tx.Proxy = Proxy(d.take(1)[0] & 0x07)
+	if tx.Proxy > ProxyTransfer && d.err == nil {
+		d.err = fmt.Errorf("invalid proxy %v", tx.Proxy)
+	}
+	tx.Ship = d.point()
+	opByte := d.take(1)[0]
+	op, flag := opByte&0x7F, opByte&0x80 != 0
+	switch op {
+	case 0:
+		tx.Action = TransferPoint{Address: d.address(), Reset: flag}
+	case 1:
+		tx.Action = Spawn{Ship: d.point(), Address: d.address()}
+	case 2:
+		tx.Action = ConfigureKeys{Encrypt: d.key(), Auth: d.key(), CryptoSuite: binary.BigEndian.Uint32(d.take(4)), Breach: flag}
+	case 3:
+		tx.Action = Escape{Parent: d.point()}
+	case 4:
+		tx.Action = CancelEscape{Parent: d.point()}
+	case 5:
+		tx.Action = Adopt{Ship: d.point()}
+	case 6:
+		tx.Action = Reject{Ship: d.point()}
+	case 7:
+		tx.Action = Detach{Ship: d.point()}
+	case 8:
+		tx.Action = SetManagementProxy{Address: d.address()}
+	case 9:
+		tx.Action = SetSpawnProxy{Address: d.address()}
+	case 10:
+		tx.Action = SetTransferProxy{Address: d.address()}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown opcode %v", op)
+		}
+	}
+}
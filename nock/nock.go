@@ -0,0 +1,194 @@
+// Package nock implements Nock, the combinator calculus that Hoon compiles
+// to.
+//
+// Nock evaluates a formula against a subject, both nouns. This package
+// implements Nock 4K, the current version: opcodes 0 through 11, where 10
+// edits a noun and 11 is a hint.
+package nock
+
+import (
+	"fmt"
+	"math/big"
+
+	"lukechampine.com/urbit/atom"
+	"lukechampine.com/urbit/noun"
+)
+
+// Loobeans, Nock's booleans: 0 is yes, and 1 is no.
+var (
+	Yes = noun.Uint(0)
+	No  = noun.Uint(1)
+)
+
+// An Error is a Nock crash.
+type Error struct {
+	Msg string
+}
+
+func (e *Error) Error() string { return "nock: " + e.Msg }
+
+// Nock evaluates the formula fol against the subject sub. Hints are evaluated
+// and discarded; scrys (opcode 12) crash. If the computation crashes, Nock
+// returns an *Error. A computation that does not terminate does not return.
+func Nock(sub, fol noun.Noun) (res noun.Noun, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	return eval(sub, fol), nil
+}
+
+func crashf(format string, args ...interface{}) {
+	panic(&Error{Msg: fmt.Sprintf(format, args...)})
+}
+
+// eval evaluates fol against sub. Formulas in tail position are evaluated
+// in a loop, so that loops in Nock, which are tail calls, run in constant
+// stack space.
+func eval(sub, fol noun.Noun) noun.Noun {
+	for {
+		f, ok := fol.(noun.Cell)
+		if !ok {
+			crashf("formula is an atom")
+		}
+		if h, ok := f.Head.(noun.Cell); ok {
+			// a cell of formulas produces a cell of their products
+			return noun.Cell{Head: eval(sub, h), Tail: eval(sub, f.Tail)}
+		}
+		switch opcode(f.Head) {
+		case 0:
+			return slot(sub, toAxis(f.Tail))
+		case 1:
+			return f.Tail
+		case 2:
+			b, c := split(f.Tail)
+			sub, fol = eval(sub, b), eval(sub, c)
+		case 3:
+			if _, ok := eval(sub, f.Tail).(noun.Cell); ok {
+				return Yes
+			}
+			return No
+		case 4:
+			a, ok := eval(sub, f.Tail).(noun.Atom)
+			if !ok {
+				crashf("cannot increment a cell")
+			}
+			i := new(big.Int).SetBytes(a.Bytes())
+			return noun.Atom{Atom: atom.New(i.Add(i, big.NewInt(1)))}
+		case 5:
+			b, c := split(f.Tail)
+			if noun.Equal(eval(sub, b), eval(sub, c)) {
+				return Yes
+			}
+			return No
+		case 6:
+			b, cd := split(f.Tail)
+			c, d := split(cd)
+			switch t := eval(sub, b); {
+			case noun.Equal(t, Yes):
+				fol = c
+			case noun.Equal(t, No):
+				fol = d
+			default:
+				crashf("branch on %v, which is not a loobean", t)
+			}
+		case 7:
+			b, c := split(f.Tail)
+			sub, fol = eval(sub, b), c
+		case 8:
+			b, c := split(f.Tail)
+			sub, fol = noun.Cell{Head: eval(sub, b), Tail: sub}, c
+		case 9:
+			b, c := split(f.Tail)
+			sub = eval(sub, c)
+			fol = slot(sub, toAxis(b))
+		case 10:
+			bc, d := split(f.Tail)
+			b, c := split(bc)
+			v := eval(sub, c)
+			return edit(eval(sub, d), toAxis(b), v)
+		case 11:
+			b, d := split(f.Tail)
+			if bc, ok := b.(noun.Cell); ok {
+				eval(sub, bc.Tail) // dynamic hint
+			}
+			fol = d
+		default:
+			crashf("unknown opcode %v", f.Head)
+		}
+	}
+}
+
+func opcode(n noun.Noun) int {
+	a, ok := n.(noun.Atom)
+	if !ok || len(a.Bytes()) > 1 {
+		return -1
+	} else if len(a.Bytes()) == 0 {
+		return 0
+	}
+	return int(a.Bytes()[0])
+}
+
+func split(n noun.Noun) (noun.Noun, noun.Noun) {
+	c, ok := n.(noun.Cell)
+	if !ok {
+		crashf("expected cell, got %v", n)
+	}
+	return c.Head, c.Tail
+}
+
+func toAxis(n noun.Noun) *big.Int {
+	a, ok := n.(noun.Atom)
+	if !ok {
+		crashf("axis is a cell")
+	}
+	return new(big.Int).SetBytes(a.Bytes())
+}
+
+// slot returns the noun at axis within n, as in Nock's / operator: 1 is n
+// itself, 2 its head, 3 its tail, 6 the head of its tail, and so on.
+func slot(n noun.Noun, axis *big.Int) noun.Noun {
+	if axis.Sign() <= 0 {
+		crashf("bad axis %v", axis)
+	}
+	for i := axis.BitLen() - 2; i >= 0; i-- {
+		c, ok := n.(noun.Cell)
+		if !ok {
+			crashf("no axis %v in atom", axis)
+		}
+		if axis.Bit(i) == 0 {
+			n = c.Head
+		} else {
+			n = c.Tail
+		}
+	}
+	return n
+}
+
+// edit returns n with the noun at axis replaced by v, as in Nock's #
+// operator.
+func edit(n noun.Noun, axis *big.Int, v noun.Noun) noun.Noun {
+	if axis.Sign() <= 0 {
+		crashf("bad axis %v", axis)
+	}
+	var rebuild func(n noun.Noun, i int) noun.Noun
+	rebuild = func(n noun.Noun, i int) noun.Noun {
+		if i < 0 {
+			return v
+		}
+		c, ok := n.(noun.Cell)
+		if !ok {
+			crashf("no axis %v in atom", axis)
+		}
+		if axis.Bit(i) == 0 {
+			return noun.Cell{Head: rebuild(c.Head, i-1), Tail: c.Tail}
+		}
+		return noun.Cell{Head: c.Head, Tail: rebuild(c.Tail, i-1)}
+	}
+	return rebuild(n, axis.BitLen()-2)
+}
@@ -0,0 +1,71 @@
+package nock
+
+import (
+	"testing"
+
+	"lukechampine.com/urbit/noun"
+)
+
+func TestNock(t *testing.T) {
+	tests := []struct {
+		sub, fol string
+		exp      string
+	}{
+		{"[[4 5] [6 14] 15]", "[0 3]", "[[6 14] 15]"},
+		{"[[4 5] [6 14] 15]", "[0 6]", "[6 14]"},
+		{"42", "[1 153 218]", "[153 218]"},
+		{"[132 19]", "[4 0 3]", "20"},
+		{"[132 19]", "[[4 0 3] [0 2]]", "[20 132]"},
+		{"42", "[3 0 1]", "1"},
+		{"[1 2]", "[3 0 1]", "0"},
+		{"[1 1]", "[5 [0 2] [0 3]]", "0"},
+		{"[1 2]", "[5 [0 2] [0 3]]", "1"},
+		{"42", "[6 [1 0] [4 0 1] [1 233]]", "43"},
+		{"42", "[6 [1 1] [4 0 1] [1 233]]", "233"},
+		{"42", "[7 [4 0 1] [4 0 1]]", "44"},
+		{"42", "[8 [4 0 1] [0 1]]", "[43 42]"},
+		{"42", "[8 [4 0 1] [4 0 3]]", "43"},
+		{"42", "[2 [0 1] [1 4 0 1]]", "43"},
+		{"[[1 2] 3]", "[10 [5 [1 9]] [0 1]]", "[[1 9] 3]"},
+		{"[[1 2] 3]", "[10 [1 [1 9]] [0 1]]", "9"},
+		{"42", "[11 %foo [4 0 1]]", "43"},
+		{"42", "[11 [%foo [4 0 1]] [4 0 1]]", "43"},
+		// a core whose arm, at axis 2, increments its sample, at axis 6
+		{"~", "[9 2 [1 4 0 6] [1 41] 0 1]", "42"},
+		// decrement: push a counter b=0 and a core whose arm returns b if
+		// a=+(b), and otherwise recurses with b incremented
+		{"42", "[8 [1 0] 8 [1 6 [5 [0 7] 4 0 6] [0 6] 9 2 10 [6 4 0 6] 0 1] 9 2 0 1]", "41"},
+	}
+	for _, test := range tests {
+		sub, err := noun.Parse(test.sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fol, err := noun.Parse(test.fol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Nock(sub, fol)
+		if err != nil {
+			t.Errorf("*[%v %v]: %v", test.sub, test.fol, err)
+		} else if s := noun.Format(res, nil, 0); s != test.exp {
+			t.Errorf("*[%v %v]: expected %v, got %v", test.sub, test.fol, test.exp, s)
+		}
+	}
+
+	for _, test := range []struct{ sub, fol string }{
+		{"42", "42"},
+		{"42", "[0 2]"},
+		{"42", "[0 0]"},
+		{"[1 2]", "[4 0 1]"},
+		{"42", "[6 [1 2] [1 0] [1 1]]"},
+		{"42", "[12 [1 0] [1 0]]"},
+		{"42", "[10 [2 [1 0]] [0 1]]"},
+	} {
+		sub, _ := noun.Parse(test.sub)
+		fol, _ := noun.Parse(test.fol)
+		if res, err := Nock(sub, fol); err == nil {
+			t.Errorf("*[%v %v]: expected crash, got %v", test.sub, test.fol, res)
+		}
+	}
+}
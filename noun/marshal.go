@@ -0,0 +1,737 @@
+package noun
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"lukechampine.com/urbit/atom"
+	"lukechampine.com/urbit/ob"
+)
+
+// A Marshaler can encode itself as a noun.
+type Marshaler interface {
+	MarshalNoun() (Noun, error)
+}
+
+// An Unmarshaler can decode itself from a noun.
+type Unmarshaler interface {
+	UnmarshalNoun(Noun) error
+}
+
+var (
+	nounType        = reflect.TypeOf((*Noun)(nil)).Elem()
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	atomType        = reflect.TypeOf(atom.Atom{})
+	bigIntType      = reflect.TypeOf((*big.Int)(nil))
+	timeType        = reflect.TypeOf(time.Time{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+	pointType       = reflect.TypeOf(ob.AzimuthPoint(0))
+	cometType       = reflect.TypeOf(ob.Comet{})
+)
+
+var unions struct {
+	sync.RWMutex
+	tags     map[reflect.Type]map[reflect.Type]string // union -> variant -> tag
+	variants map[reflect.Type]map[string]reflect.Type // union -> tag -> variant
+}
+
+// RegisterVariant registers variant as a member of a tagged union. union must
+// be a pointer to an interface type, e.g. (*Action)(nil), and the type of
+// variant must implement that interface. When marshaled as the interface
+// type, values of the variant's type are encoded as [%tag fields...], or as
+// %tag alone if the variant has no fields. It panics if the tag or variant is
+// already registered for the union.
+func RegisterVariant(union interface{}, tag string, variant interface{}) {
+	ut := reflect.TypeOf(union)
+	if ut == nil || ut.Kind() != reflect.Ptr || ut.Elem().Kind() != reflect.Interface {
+		panic("noun: RegisterVariant union must be a pointer to an interface")
+	}
+	ut = ut.Elem()
+	vt := reflect.TypeOf(variant)
+	if vt == nil || !vt.Implements(ut) {
+		panic(fmt.Sprintf("noun: %v does not implement %v", vt, ut))
+	} else if _, err := atom.Term(tag); err != nil || tag == "" {
+		panic(fmt.Sprintf("noun: invalid tag %q", tag))
+	}
+
+	unions.Lock()
+	defer unions.Unlock()
+	if unions.tags == nil {
+		unions.tags = make(map[reflect.Type]map[reflect.Type]string)
+		unions.variants = make(map[reflect.Type]map[string]reflect.Type)
+	}
+	if unions.tags[ut] == nil {
+		unions.tags[ut] = make(map[reflect.Type]string)
+		unions.variants[ut] = make(map[string]reflect.Type)
+	}
+	if _, ok := unions.tags[ut][vt]; ok {
+		panic(fmt.Sprintf("noun: %v registered twice for %v", vt, ut))
+	} else if _, ok := unions.variants[ut][tag]; ok {
+		panic(fmt.Sprintf("noun: tag %%%v registered twice for %v", tag, ut))
+	}
+	unions.tags[ut][vt] = tag
+	unions.variants[ut][tag] = vt
+}
+
+func lookupTag(union, variant reflect.Type) (string, bool) {
+	unions.RLock()
+	defer unions.RUnlock()
+	tag, ok := unions.tags[union][variant]
+	return tag, ok
+}
+
+func lookupVariant(union reflect.Type, tag string) (reflect.Type, bool) {
+	unions.RLock()
+	defer unions.RUnlock()
+	vt, ok := unions.variants[union][tag]
+	return vt, ok
+}
+
+// tagOpts are the options specified by a `noun` struct tag.
+type tagOpts struct {
+	aura atom.Aura // if non-empty, the aura of encoded atoms
+	tape bool      // encode strings as tapes rather than cords
+}
+
+// parseTag parses a struct tag such as `noun:"@ux"` or `noun:"tape"`.
+func parseTag(tag string) (opts tagOpts, skip bool, err error) {
+	if tag == "-" {
+		return tagOpts{}, true, nil
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		switch {
+		case opt == "":
+		case opt == "tape":
+			opts.tape = true
+		case strings.HasPrefix(opt, "@") && len(opt) > 1:
+			opts.aura = atom.Aura(opt[1:])
+		default:
+			return tagOpts{}, false, fmt.Errorf("noun: invalid struct tag option %q", opt)
+		}
+	}
+	return opts, false, nil
+}
+
+// Marshal encodes v as a noun:
+//
+//   - Nouns, atom.Atoms, and Marshalers are encoded as themselves.
+//   - Unsigned integers and *big.Ints are encoded as @ud atoms; signed
+//     integers are too, unless a signed aura is specified, in which case
+//     they are zigzag-encoded (negative values are otherwise an error).
+//   - Booleans are encoded as loobeans (@f), where & (0) is true.
+//   - Floats are encoded as @rs or @rd; strings as cords (@t); []byte as an
+//     atom with the bytes in little-endian order; time.Time as @da;
+//     time.Duration as @dr; and ob.AzimuthPoint and ob.Comet as @p.
+//   - Slices and arrays are encoded as null-terminated lists.
+//   - Maps are encoded as Hoon maps; maps with struct{} values are encoded
+//     as Hoon sets.
+//   - Structs are encoded as right-nested tuples of their exported fields.
+//   - Pointers are encoded as units: nil is ~, and a non-nil p is [~ *p].
+//   - Interface values whose dynamic type was registered with
+//     RegisterVariant are encoded as [%tag fields...]. The dynamic value of
+//     an empty interface is encoded as itself; conversely, nouns are decoded
+//     into empty interfaces as Nouns.
+//
+// Struct fields may be annotated with a `noun` tag: `noun:"@ux"` sets the
+// aura of the field's atoms (including atoms within lists, maps, and units),
+// `noun:"tape"` encodes strings as tapes, and `noun:"-"` skips the field.
+func Marshal(v interface{}) (Noun, error) {
+	if v == nil {
+		return nil, errors.New("noun: cannot marshal nil")
+	}
+	return encode(reflect.ValueOf(v), tagOpts{})
+}
+
+func encodeAtom(a atom.Atom, def atom.Aura, opts tagOpts) Noun {
+	if opts.aura != "" {
+		return Atom{a.Cast(opts.aura)}
+	}
+	return Atom{a.Cast(def)}
+}
+
+func encode(v reflect.Value, opts tagOpts) (Noun, error) {
+	t := v.Type()
+	if t.Implements(marshalerType) {
+		if t.Kind() == reflect.Ptr && v.IsNil() {
+			return Null, nil
+		}
+		return v.Interface().(Marshaler).MarshalNoun()
+	} else if v.CanAddr() && reflect.PtrTo(t).Implements(marshalerType) {
+		return v.Addr().Interface().(Marshaler).MarshalNoun()
+	} else if t.Implements(nounType) && t.Kind() != reflect.Interface {
+		return v.Interface().(Noun), nil
+	}
+
+	switch t {
+	case nounType:
+		if v.IsNil() {
+			return nil, errors.New("noun: cannot marshal nil Noun")
+		}
+		return v.Interface().(Noun), nil
+	case atomType:
+		a := v.Interface().(atom.Atom)
+		if opts.aura != "" {
+			a = a.Cast(opts.aura)
+		}
+		return Atom{a}, nil
+	case bigIntType:
+		i := v.Interface().(*big.Int)
+		if i == nil {
+			return nil, errors.New("noun: cannot marshal nil *big.Int")
+		} else if i.Sign() < 0 {
+			if opts.aura.NestsIn(atom.AuraS) {
+				return Atom{atom.NewSigned(i).Cast(opts.aura)}, nil
+			}
+			return nil, fmt.Errorf("noun: cannot marshal negative integer %v as unsigned atom", i)
+		}
+		return encodeAtom(atom.New(i), atom.AuraUD, opts), nil
+	case timeType:
+		return encodeAtom(atom.FromTime(v.Interface().(time.Time)), atom.AuraDA, opts), nil
+	case durationType:
+		d := v.Interface().(time.Duration)
+		if d < 0 {
+			return nil, fmt.Errorf("noun: cannot marshal negative duration %v", d)
+		}
+		return encodeAtom(atom.FromDuration(d), atom.AuraDR, opts), nil
+	case pointType:
+		return encodeAtom(atom.New64(v.Uint()), atom.AuraP, opts), nil
+	case cometType:
+		c := v.Interface().(ob.Comet)
+		return encodeAtom(atom.FromBytes(c[:]), atom.AuraP, opts), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return encodeAtom(atom.New64(0), atom.AuraF, opts), nil
+		}
+		return encodeAtom(atom.New64(1), atom.AuraF, opts), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return encodeAtom(atom.New64(v.Uint()), atom.AuraUD, opts), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if opts.aura.NestsIn(atom.AuraS) {
+			return Atom{atom.NewSigned64(v.Int()).Cast(opts.aura)}, nil
+		} else if v.Int() < 0 {
+			return nil, fmt.Errorf("noun: cannot marshal negative integer %v as unsigned atom", v.Int())
+		}
+		return encodeAtom(atom.New64(uint64(v.Int())), atom.AuraUD, opts), nil
+	case reflect.Float32:
+		return encodeAtom(atom.NewFloat32(float32(v.Float())), atom.AuraRS, opts), nil
+	case reflect.Float64:
+		return encodeAtom(atom.NewFloat64(v.Float()), atom.AuraRD, opts), nil
+	case reflect.String:
+		if opts.tape {
+			return Tape(v.String()), nil
+		}
+		return encodeAtom(textAtom([]byte(v.String())), atom.AuraT, opts), nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return encodeAtom(textAtom(v.Bytes()), atom.AuraAtom, opts), nil
+		}
+		fallthrough
+	case reflect.Array:
+		ns := make([]Noun, v.Len())
+		for i := range ns {
+			n, err := encode(v.Index(i), opts)
+			if err != nil {
+				return nil, err
+			}
+			ns[i] = n
+		}
+		return List(ns...), nil
+	case reflect.Map:
+		isSet := t.Elem().Kind() == reflect.Struct && t.Elem().NumField() == 0
+		var tr *treeNode
+		for _, k := range sortedKeys(v) {
+			kn, err := encode(k, opts)
+			if err != nil {
+				return nil, err
+			}
+			if isSet {
+				tr = tr.put(kn, kn)
+				continue
+			}
+			vn, err := encode(v.MapIndex(k), opts)
+			if err != nil {
+				return nil, err
+			}
+			tr = tr.put(kn, Cell{kn, vn})
+		}
+		return tr.noun(), nil
+	case reflect.Struct:
+		return encodeStruct(v)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return Null, nil
+		}
+		n, err := encode(v.Elem(), opts)
+		if err != nil {
+			return nil, err
+		}
+		return Cell{Null, n}, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, fmt.Errorf("noun: cannot marshal nil %v", t)
+		} else if t.NumMethod() == 0 {
+			return encode(v.Elem(), opts)
+		}
+		e := v.Elem()
+		tag, ok := lookupTag(t, e.Type())
+		if !ok {
+			return nil, fmt.Errorf("noun: %v is not a registered variant of %v", e.Type(), t)
+		}
+		if e.Kind() == reflect.Ptr {
+			if e.IsNil() {
+				return nil, fmt.Errorf("noun: cannot marshal nil %v as %v", e.Type(), t)
+			}
+			e = e.Elem()
+		}
+		head := Atom{atom.FromBytes([]byte(reverse(tag))).Cast(atom.AuraTAS)}
+		var body Noun
+		var err error
+		if e.Kind() == reflect.Struct {
+			var fields []structField
+			if fields, err = structFields(e.Type()); err != nil {
+				return nil, err
+			} else if len(fields) == 0 {
+				return head, nil
+			}
+			body, err = encodeStruct(e)
+		} else {
+			body, err = encode(e, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return Cell{head, body}, nil
+	}
+	return nil, fmt.Errorf("noun: cannot marshal value of type %v", t)
+}
+
+func encodeStruct(v reflect.Value) (Noun, error) {
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return nil, err
+	} else if len(fields) == 0 {
+		return Null, nil
+	}
+	ns := make([]Noun, len(fields))
+	for i, f := range fields {
+		n, err := encode(v.Field(f.index), f.opts)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %w", v.Type(), f.name, err)
+		}
+		ns[i] = n
+	}
+	return tuple(ns), nil
+}
+
+// tuple returns the right-nested tuple [ns[0] ns[1] ... ns[len-1]].
+func tuple(ns []Noun) Noun {
+	n := ns[len(ns)-1]
+	for i := len(ns) - 2; i >= 0; i-- {
+		n = Cell{ns[i], n}
+	}
+	return n
+}
+
+type structField struct {
+	name  string
+	index int
+	opts  tagOpts
+}
+
+func structFields(t reflect.Type) ([]structField, error) {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		opts, skip, err := parseTag(f.Tag.Get("noun"))
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %w", t, f.Name, err)
+		} else if skip {
+			continue
+		}
+		fields = append(fields, structField{f.Name, i, opts})
+	}
+	return fields, nil
+}
+
+// sortedKeys returns the keys of m in a deterministic order. The order does
+// not affect the encoded map, but it makes errors reproducible.
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	strs := make([]string, len(keys))
+	for i, k := range keys {
+		strs[i] = fmt.Sprint(k.Interface())
+	}
+	sort.Sort(keySorter{keys, strs})
+	return keys
+}
+
+type keySorter struct {
+	keys []reflect.Value
+	strs []string
+}
+
+func (s keySorter) Len() int           { return len(s.keys) }
+func (s keySorter) Less(i, j int) bool { return s.strs[i] < s.strs[j] }
+func (s keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.strs[i], s.strs[j] = s.strs[j], s.strs[i]
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// textAtom returns the atom whose little-endian bytes are b.
+func textAtom(b []byte) atom.Atom {
+	return atom.FromBytes([]byte(reverse(string(b))))
+}
+
+// Unmarshal decodes n into the value pointed to by v, reversing the mapping
+// described in Marshal. Atoms decoded into atom.Atom keep their aura unless
+// the field specifies one.
+func Unmarshal(n Noun, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("noun: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+	return decode(n, rv.Elem(), tagOpts{})
+}
+
+// An UnmarshalTypeError describes a noun that could not be decoded into a
+// value of a particular Go type.
+type UnmarshalTypeError struct {
+	Noun Noun
+	Type reflect.Type
+	Msg  string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	kind := "atom"
+	if _, ok := e.Noun.(Cell); ok {
+		kind = "cell"
+	}
+	s := fmt.Sprintf("noun: cannot unmarshal %v into Go value of type %v", kind, e.Type)
+	if e.Msg != "" {
+		s += ": " + e.Msg
+	}
+	return s
+}
+
+func decodeAtom(n Noun, t reflect.Type) (atom.Atom, error) {
+	a, ok := n.(Atom)
+	if !ok {
+		return atom.Atom{}, &UnmarshalTypeError{Noun: n, Type: t}
+	}
+	return a.Atom, nil
+}
+
+func decode(n Noun, v reflect.Value, opts tagOpts) error {
+	t := v.Type()
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return v.Addr().Interface().(Unmarshaler).UnmarshalNoun(n)
+	} else if t.Kind() == reflect.Ptr && t.Implements(unmarshalerType) {
+		if IsNull(n) {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		v.Set(reflect.New(t.Elem()))
+		return v.Interface().(Unmarshaler).UnmarshalNoun(n)
+	}
+	if t == nounType || (t.Implements(nounType) && t.Kind() != reflect.Interface) {
+		nv := reflect.ValueOf(n)
+		if !nv.Type().AssignableTo(t) {
+			return &UnmarshalTypeError{Noun: n, Type: t}
+		}
+		v.Set(nv)
+		return nil
+	}
+
+	switch t {
+	case atomType:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		} else if opts.aura != "" {
+			a = a.Cast(opts.aura)
+		}
+		v.Set(reflect.ValueOf(a))
+		return nil
+	case bigIntType:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		i := new(big.Int).SetBytes(a.Bytes())
+		if opts.aura.NestsIn(atom.AuraS) {
+			i = a.Signed()
+		}
+		v.Set(reflect.ValueOf(i))
+		return nil
+	case timeType:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(a.Time()))
+		return nil
+	case durationType:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		} else if a.Cmp(atom.FromDuration(math.MaxInt64)) > 0 {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "duration overflows time.Duration"}
+		}
+		v.Set(reflect.ValueOf(a.Duration()))
+		return nil
+	case cometType:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		b := a.Bytes()
+		if len(b) > 16 {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "atom is too large"}
+		}
+		var c ob.Comet
+		copy(c[16-len(b):], b)
+		v.Set(reflect.ValueOf(c))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		switch {
+		case a.Cmp(atom.New64(0)) == 0:
+			v.SetBool(true)
+		case a.Cmp(atom.New64(1)) == 0:
+			v.SetBool(false)
+		default:
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "atom is not a loobean"}
+		}
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		i := new(big.Int).SetBytes(a.Bytes())
+		if i.BitLen() > t.Bits() {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "atom overflows " + t.String()}
+		}
+		v.SetUint(i.Uint64())
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		i := new(big.Int).SetBytes(a.Bytes())
+		if opts.aura.NestsIn(atom.AuraS) {
+			i = a.Signed()
+		}
+		if !i.IsInt64() || v.OverflowInt(i.Int64()) {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "atom overflows " + t.String()}
+		}
+		v.SetInt(i.Int64())
+		return nil
+	case reflect.Float32, reflect.Float64:
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		i := new(big.Int).SetBytes(a.Bytes())
+		if i.BitLen() > t.Bits() {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "atom overflows " + t.String()}
+		}
+		if t.Kind() == reflect.Float32 {
+			v.SetFloat(float64(math.Float32frombits(uint32(i.Uint64()))))
+		} else {
+			v.SetFloat(math.Float64frombits(i.Uint64()))
+		}
+		return nil
+	case reflect.String:
+		if opts.tape {
+			c, err := TapeToCord(n)
+			if err != nil {
+				return &UnmarshalTypeError{Noun: n, Type: t, Msg: err.Error()}
+			}
+			v.SetString(c.Text())
+			return nil
+		}
+		a, err := decodeAtom(n, t)
+		if err != nil {
+			return err
+		}
+		v.SetString(a.Text())
+		return nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			a, err := decodeAtom(n, t)
+			if err != nil {
+				return err
+			}
+			v.SetBytes([]byte(a.Text()))
+			return nil
+		}
+		ns, err := Slice(n)
+		if err != nil {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: err.Error()}
+		}
+		s := reflect.MakeSlice(t, len(ns), len(ns))
+		for i := range ns {
+			if err := decode(ns[i], s.Index(i), opts); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		ns, err := Slice(n)
+		if err != nil {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: err.Error()}
+		} else if len(ns) != t.Len() {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: fmt.Sprintf("list has %v elements", len(ns))}
+		}
+		for i := range ns {
+			if err := decode(ns[i], v.Index(i), opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		ns, err := TreeNodes(n)
+		if err != nil {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: err.Error()}
+		}
+		isSet := t.Elem().Kind() == reflect.Struct && t.Elem().NumField() == 0
+		m := reflect.MakeMapWithSize(t, len(ns))
+		for _, kv := range ns {
+			kn, vn := kv, Noun(Null)
+			if !isSet {
+				c, ok := kv.(Cell)
+				if !ok {
+					return &UnmarshalTypeError{Noun: n, Type: t, Msg: "map node is not a pair"}
+				}
+				kn, vn = c.Head, c.Tail
+			}
+			k := reflect.New(t.Key()).Elem()
+			e := reflect.New(t.Elem()).Elem()
+			if err := decode(kn, k, opts); err != nil {
+				return err
+			} else if !isSet {
+				if err := decode(vn, e, opts); err != nil {
+					return err
+				}
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Struct:
+		return decodeStruct(n, v)
+	case reflect.Ptr:
+		if IsNull(n) {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		c, ok := n.(Cell)
+		if !ok || !IsNull(c.Head) {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "noun is not a unit"}
+		}
+		p := reflect.New(t.Elem())
+		if err := decode(c.Tail, p.Elem(), opts); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(n))
+			return nil
+		}
+		head, body := n, Noun(nil)
+		if c, ok := n.(Cell); ok {
+			head, body = c.Head, c.Tail
+		}
+		a, ok := head.(Atom)
+		if !ok {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: "missing union tag"}
+		}
+		vt, ok := lookupVariant(t, a.Text())
+		if !ok {
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: fmt.Sprintf("unknown union tag %q", a.Text())}
+		}
+		e := reflect.New(vt).Elem()
+		target := e
+		if vt.Kind() == reflect.Ptr {
+			e.Set(reflect.New(vt.Elem()))
+			target = e.Elem()
+		}
+		var fields []structField
+		if target.Kind() == reflect.Struct {
+			var err error
+			if fields, err = structFields(target.Type()); err != nil {
+				return err
+			}
+		}
+		switch {
+		case body == nil && target.Kind() == reflect.Struct && len(fields) == 0:
+		case body == nil:
+			return &UnmarshalTypeError{Noun: n, Type: t, Msg: fmt.Sprintf("missing body for tag %%%v", a.Text())}
+		case target.Kind() == reflect.Struct:
+			if err := decodeStruct(body, target); err != nil {
+				return err
+			}
+		default:
+			if err := decode(body, target, opts); err != nil {
+				return err
+			}
+		}
+		v.Set(e)
+		return nil
+	}
+	return fmt.Errorf("noun: cannot unmarshal into value of type %v", t)
+}
+
+func decodeStruct(n Noun, v reflect.Value) error {
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return err
+	}
+	for i, f := range fields {
+		fn := n
+		if i < len(fields)-1 {
+			c, ok := n.(Cell)
+			if !ok {
+				return &UnmarshalTypeError{Noun: n, Type: v.Type(), Msg: fmt.Sprintf("tuple is too short for field %v", f.name)}
+			}
+			fn, n = c.Head, c.Tail
+		}
+		if err := decode(fn, v.Field(f.index), f.opts); err != nil {
+			return fmt.Errorf("%v.%v: %w", v.Type(), f.name, err)
+		}
+	}
+	return nil
+}
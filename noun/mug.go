@@ -0,0 +1,87 @@
+package noun
+
+import (
+	"github.com/spaolacci/murmur3"
+	"lukechampine.com/urbit/atom"
+)
+
+// Mug returns the 31-bit hash of n, as in Hoon's ++mug.
+func Mug(n Noun) uint32 {
+	switch n := n.(type) {
+	case Atom:
+		return mum(0xcafebabe, 0x7fff, n.Bytes())
+	case Cell:
+		h, t := Mug(n.Head), Mug(n.Tail)
+		b := atom.New64(uint64(t)<<32 | uint64(h)).Bytes()
+		return mum(0xdeadbeef, 0xfffe, b)
+	}
+	panic("unreachable")
+}
+
+// mum hashes the big-endian bytes b, retrying with successive seeds until the
+// (folded) hash is non-zero.
+func mum(seed, fallback uint32, b []byte) uint32 {
+	key := make([]byte, len(b))
+	for i := range b {
+		key[i] = b[len(b)-i-1]
+	}
+	for i := 0; i < 8; i++ {
+		h := murmur3.Sum32WithSeed(key, seed+uint32(i))
+		if h = h>>31 ^ h&0x7fffffff; h != 0 {
+			return h
+		}
+	}
+	return fallback
+}
+
+// Equal reports whether a and b are the same noun.
+func Equal(a, b Noun) bool {
+	switch a := a.(type) {
+	case Atom:
+		b, ok := b.(Atom)
+		return ok && a.Cmp(b.Atom) == 0
+	case Cell:
+		b, ok := b.(Cell)
+		return ok && Equal(a.Head, b.Head) && Equal(a.Tail, b.Tail)
+	}
+	return false
+}
+
+// dor is Hoon's depth-first total order on nouns.
+func dor(a, b Noun) bool {
+	if Equal(a, b) {
+		return true
+	}
+	switch a := a.(type) {
+	case Atom:
+		b, ok := b.(Atom)
+		return !ok || a.Cmp(b.Atom) < 0
+	case Cell:
+		b, ok := b.(Cell)
+		if !ok {
+			return false
+		} else if Equal(a.Head, b.Head) {
+			return dor(a.Tail, b.Tail)
+		}
+		return dor(a.Head, b.Head)
+	}
+	return false
+}
+
+// gor orders nouns by their mug, falling back to dor.
+func gor(a, b Noun) bool {
+	c, d := Mug(a), Mug(b)
+	if c == d {
+		return dor(a, b)
+	}
+	return c < d
+}
+
+// mor orders nouns by the mug of their mug, falling back to dor.
+func mor(a, b Noun) bool {
+	c, d := Mug(Uint(uint64(Mug(a)))), Mug(Uint(uint64(Mug(b))))
+	if c == d {
+		return dor(a, b)
+	}
+	return c < d
+}
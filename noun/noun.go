@@ -0,0 +1,100 @@
+// Package noun implements nouns, the data model of Nock and Hoon.
+package noun
+
+import (
+	"errors"
+
+	"lukechampine.com/urbit/atom"
+)
+
+// A Noun is either an Atom or a Cell.
+type Noun interface {
+	isNoun()
+}
+
+func (Atom) isNoun() {}
+func (Cell) isNoun() {}
+
+// An Atom is a noun that is a natural number.
+type Atom struct {
+	atom.Atom
+}
+
+// A Cell is a noun that is an ordered pair of nouns.
+type Cell struct {
+	Head Noun
+	Tail Noun
+}
+
+// Null is the atom 0, denoted ~ in Hoon.
+var Null = Atom{atom.New64(0)}
+
+// Uint returns u as an Atom.
+func Uint(u uint64) Atom {
+	return Atom{atom.New64(u)}
+}
+
+// IsNull reports whether n is the atom 0.
+func IsNull(n Noun) bool {
+	a, ok := n.(Atom)
+	return ok && a.Cmp(Null.Atom) == 0
+}
+
+// List returns the null-terminated list of ns.
+func List(ns ...Noun) Noun {
+	var l Noun = Null
+	for i := len(ns) - 1; i >= 0; i-- {
+		l = Cell{ns[i], l}
+	}
+	return l
+}
+
+// Slice returns the elements of the null-terminated list l.
+func Slice(l Noun) ([]Noun, error) {
+	var ns []Noun
+	for !IsNull(l) {
+		c, ok := l.(Cell)
+		if !ok {
+			return nil, errors.New("list is not null-terminated")
+		}
+		ns = append(ns, c.Head)
+		l = c.Tail
+	}
+	return ns, nil
+}
+
+// Tape returns s as a tape: a list of @tD atoms, one per byte.
+func Tape(s string) Noun {
+	ns := make([]Noun, len(s))
+	for i := range ns {
+		ns[i] = Atom{atom.New64(uint64(s[i])).Cast("tD")}
+	}
+	return List(ns...)
+}
+
+// CordToTape converts a cord to a tape, like Hoon's ++trip.
+func CordToTape(c atom.Atom) Noun {
+	return Tape(c.Text())
+}
+
+// TapeToCord converts a tape to a cord, like Hoon's ++crip. As in ++crip,
+// the elements of the tape may be any atoms; their bytes are concatenated,
+// so zeros are dropped and wider atoms contribute more than one byte.
+func TapeToCord(n Noun) (atom.Atom, error) {
+	ns, err := Slice(n)
+	if err != nil {
+		return atom.Atom{}, err
+	}
+	var b []byte // little-endian
+	for _, n := range ns {
+		a, ok := n.(Atom)
+		if !ok {
+			return atom.Atom{}, errors.New("tape element is not an atom")
+		}
+		b = append(b, a.Text()...)
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return atom.FromBytes(b).Cast(atom.AuraT), nil
+}
@@ -0,0 +1,380 @@
+package noun
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"lukechampine.com/urbit/atom"
+	"lukechampine.com/urbit/ob"
+)
+
+func TestTape(t *testing.T) {
+	c, _ := atom.Cord("hi ツ")
+	tape := CordToTape(c)
+	ns, err := Slice(tape)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ns) != 6 || ns[0].(Atom).String() != "'h'" {
+		t.Fatalf("wrong tape: %v", ns)
+	}
+	c2, err := TapeToCord(tape)
+	if err != nil {
+		t.Fatal(err)
+	} else if c2.String() != "'hi ツ'" || c2.Cmp(c) != 0 {
+		t.Errorf("round trip failed: %v", c2)
+	}
+
+	// like ++crip, zeros are dropped and wide atoms are concatenated
+	abc, _ := atom.Cord("abc")
+	if c, err := TapeToCord(List(Uint('a'), Uint(0), Uint('c'<<8|'b'))); err != nil {
+		t.Fatal(err)
+	} else if c.Cmp(abc) != 0 {
+		t.Errorf("wrong cord: %v", c)
+	}
+
+	bad := []Noun{
+		Cell{Uint('a'), Uint(1)},
+		List(Cell{Null, Null}),
+	}
+	for _, n := range bad {
+		if _, err := TapeToCord(n); err == nil {
+			t.Errorf("expected error for %v", n)
+		}
+	}
+}
+
+func TestMug(t *testing.T) {
+	tests := []struct {
+		n   Noun
+		mug uint32
+	}{
+		{Uint(0), 0x79ff04e8},
+		{Uint(1), 0x715c2a60},
+		{Cell{Null, Null}, 0x192f5588},
+	}
+	for _, test := range tests {
+		if m := Mug(test.n); m != test.mug {
+			t.Errorf("wrong mug for %v: expected %x, got %x", test.n, test.mug, m)
+		}
+	}
+}
+
+// checkTree verifies that t is a valid treap.
+func checkTree(t *testing.T, tr Noun, key func(Noun) Noun) {
+	var walk func(tr Noun) (Noun, bool)
+	walk = func(tr Noun) (Noun, bool) {
+		if IsNull(tr) {
+			return nil, true
+		}
+		c := tr.(Cell)
+		k := key(c.Head)
+		lr := c.Tail.(Cell)
+		for i, sub := range []Noun{lr.Head, lr.Tail} {
+			sk, ok := walk(sub)
+			if !ok {
+				return nil, false
+			} else if sk == nil {
+				continue
+			} else if !mor(k, sk) || gor(sk, k) != (i == 0) {
+				t.Errorf("invalid treap node %v", c.Head)
+				return nil, false
+			}
+		}
+		return k, true
+	}
+	walk(tr)
+}
+
+type testAction interface{ isTestAction() }
+type testSpawn struct {
+	Ship ob.AzimuthPoint
+	To   string `noun:"@ux"`
+}
+type testNoop struct{}
+
+func (testSpawn) isTestAction() {}
+func (*testNoop) isTestAction() {}
+
+func init() {
+	RegisterVariant((*testAction)(nil), "spawn", testSpawn{})
+	RegisterVariant((*testAction)(nil), "noop", &testNoop{})
+}
+
+func TestMarshal(t *testing.T) {
+	type inner struct {
+		Name  string `noun:"@tas"`
+		Tape  string `noun:"tape"`
+		Count int
+		Skip  int `noun:"-"`
+		skip  int
+	}
+	type outer struct {
+		Flag    bool
+		Delta   int `noun:"@sd"`
+		Inner   inner
+		List    []uint16 `noun:"@ux"`
+		Unit    *uint64
+		None    *uint64
+		Actions []testAction
+		Big     *big.Int
+		Bytes   []byte
+		Time    time.Time
+		Any     interface{}
+	}
+	u := uint64(7)
+	v := outer{
+		Flag:    true,
+		Delta:   -3,
+		Inner:   inner{"foo", "hi", 9, 1, 2},
+		List:    []uint16{1, 2},
+		Unit:    &u,
+		Actions: []testAction{testSpawn{256, "\x01"}, &testNoop{}},
+		Big:     new(big.Int).Lsh(big.NewInt(1), 100),
+		Bytes:   []byte{1, 2},
+		Time:    time.Unix(0, 0).UTC(),
+		Any:     Cell{Uint(1), Uint(2)},
+	}
+	foo, _ := atom.Term("foo")
+	exp := List(
+		Uint(0),
+		Uint(5),
+		List(Atom{foo}, Cell{Uint('h'), Cell{Uint('i'), Null}}),
+		List(Uint(1), Uint(2)),
+		Cell{Null, Uint(7)},
+		Null,
+		List(
+			Cell{Atom{fromText("spawn")}, Cell{Uint(256), Uint(1)}},
+			Atom{fromText("noop")},
+		),
+		Atom{atom.New(new(big.Int).Lsh(big.NewInt(1), 100))},
+		Uint(0x0201),
+		Atom{atom.FromTime(time.Unix(0, 0))},
+		Cell{Uint(1), Uint(2)},
+	)
+	// structs are tuples, not lists
+	expCells, _ := Slice(exp)
+	exp = tuple(expCells)
+	expCells[2] = tuple([]Noun{Atom{foo}, Tape("hi"), Uint(9)})
+	exp = tuple(expCells)
+
+	n, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	} else if !Equal(n, exp) {
+		t.Fatalf("wrong encoding:\n%v\n%v", n, exp)
+	}
+	ns, _ := Slice(n.(Cell).Tail.(Cell).Tail.(Cell).Tail.(Cell).Head)
+	if ns[0].(Atom).String() != "0x1" {
+		t.Error("aura not applied to list elements:", ns[0])
+	}
+
+	var got outer
+	if err := Unmarshal(n, &got); err != nil {
+		t.Fatal(err)
+	}
+	v.Inner.Skip, v.Inner.skip = 0, 0
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("round trip failed:\n%+v\n%+v", got, v)
+	}
+
+	bad := []struct {
+		n Noun
+		v interface{}
+	}{
+		{Cell{Null, Null}, new(int)},
+		{Uint(256), new(uint8)},
+		{Uint(2), new(bool)},
+		{Cell{Uint(1), Null}, new(*int)},
+		{List(Uint(1), Uint(2)), new([3]int)},
+		{Cell{Atom{fromText("bogus")}, Null}, new(testAction)},
+		{Atom{fromText("spawn")}, new(testAction)},
+		{Uint(1), new(struct{ A, B int })},
+	}
+	for _, test := range bad {
+		if err := Unmarshal(test.n, test.v); err == nil {
+			t.Errorf("expected error unmarshaling %v into %T", test.n, test.v)
+		}
+	}
+	if _, err := Marshal(-1); err == nil {
+		t.Error("expected error marshaling negative integer")
+	} else if _, err := Marshal(struct{ C chan int }{}); err == nil {
+		t.Error("expected error marshaling channel")
+	} else if _, err := Marshal([]testAction{(*testNoop)(nil)}); err == nil {
+		t.Error("expected error marshaling nil variant")
+	}
+	type badTag struct {
+		A int `noun:"bogus"`
+	}
+	if _, err := Marshal(badTag{}); err == nil {
+		t.Error("expected error marshaling invalid struct tag")
+	} else if err := Unmarshal(Uint(1), new(badTag)); err == nil {
+		t.Error("expected error unmarshaling invalid struct tag")
+	}
+}
+
+func TestMarshalMap(t *testing.T) {
+	m := make(map[string]int)
+	var pairs []Cell
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprint("key", i)
+		m[k] = i
+		pairs = append(pairs, Cell{Atom{fromText(k)}, Uint(uint64(i))})
+	}
+	n, err := Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTree(t, n, func(n Noun) Noun { return n.(Cell).Head })
+	// the treap is independent of insertion order
+	for i := range pairs {
+		j := (i * 37) % len(pairs)
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+	if !Equal(n, Map(pairs...)) {
+		t.Error("map encoding depends on insertion order")
+	}
+	var got map[string]int
+	if err := Unmarshal(n, &got); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, m) {
+		t.Error("map did not round-trip")
+	}
+
+	set := map[uint16]struct{}{1: {}, 2: {}, 3: {}, 1000: {}}
+	n, err = Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTree(t, n, func(n Noun) Noun { return n })
+	if !Equal(n, Set(Uint(1000), Uint(3), Uint(2), Uint(1))) {
+		t.Error("wrong set encoding")
+	}
+	var gotSet map[uint16]struct{}
+	if err := Unmarshal(n, &gotSet); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(gotSet, set) {
+		t.Error("set did not round-trip")
+	}
+}
+
+func fromText(s string) atom.Atom {
+	a, _ := atom.Cord(s)
+	return a
+}
+
+func TestFormat(t *testing.T) {
+	ux := func(u uint64) Noun { return Atom{atom.New64(u).Cast(atom.AuraUX)} }
+	term := func(s string) Noun { a, _ := atom.Term(s); return Atom{a} }
+	tests := []struct {
+		n    Noun
+		hint string
+		exp  string
+	}{
+		{Uint(1000), "", "1.000"},
+		{Uint(1000), "@ux", "0x3e8"},
+		{List(Uint(1), Uint(2), Uint(3)), "", "[1 2 3 0]"},
+		{List(Uint(1), Uint(2), Uint(3)), "(list @ud)", "~[1 2 3]"},
+		{Null, "(list @ud)", "~"},
+		{Cell{Cell{Uint(1), Uint(2)}, Cell{Uint(3), Uint(4)}}, "", "[[1 2] 3 4]"},
+		{Cell{ux(1), term("foo")}, "", "[0x1 %foo]"},
+		{Tape("hi \"there\"\n"), "", `"hi \"there\"\0a"`},
+		{List(Uint('h'), Uint('i')), "tape", `"hi"`},
+		{Cell{Uint(1), Tape("ok")}, "[@ud tape]", `[1 "ok"]`},
+		{Cell{Null, Uint(5)}, "(unit @ux)", "[~ 0x5]"},
+		{Null, "(unit @ux)", "~"},
+		{Cell{Uint(1), Cell{Null, Uint(5)}}, "[@ud (unit @ud)]", "[1 ~ 5]"},
+		{Cell{Uint(1), Uint(2)}, "[a=@ud b=@ux]", "[a=1 b=0x2]"},
+		{Map(Cell{term("a"), Uint(1)}), "(map @tas @ud)", "{[p=%a q=1]}"},
+		{Null, "(map @tas @ud)", "{}"},
+		{Set(Uint(1)), "(set @ux)", "{0x1}"},
+		{Uint(5), "[@ud @ud]", "5"},
+		{Cell{Uint(1), Uint(2)}, "(list @ud)", "[1 2]"},
+		{Cell{Uint(1), Uint(2)}, "@ud", "[1 2]"},
+	}
+	for _, test := range tests {
+		var hint Type
+		if test.hint != "" {
+			var err error
+			if hint, err = ParseType(test.hint); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if s := Format(test.n, hint, 80); s != test.exp {
+			t.Errorf("Format(%v, %q): expected %v, got %v", test.n, test.hint, test.exp, s)
+		}
+	}
+
+	// sets are printed in ++tap order
+	set := Set(Uint(1), Uint(2), Uint(3))
+	ns, _ := TreeNodes(set)
+	if s := Format(set, SetType{AtomType{"ud"}}, 0); s != fmt.Sprintf("{%v %v %v}", ns[2], ns[1], ns[0]) {
+		t.Error("set not printed in tap order:", s)
+	}
+
+	long := make([]Noun, 30)
+	for i := range long {
+		long[i] = ux(uint64(0x1000 + i))
+	}
+	s := Format(Cell{Uint(1), Cell{List(long...), Uint(2)}}, TupleType{AtomType{"ud"}, ListType{AtomType{"ux"}}, AtomType{"ud"}}, 40)
+	lines := strings.Split(s, "\n")
+	if lines[0] != "[ 1" || lines[1] != "  ~[" || lines[2] != "    0x1000" || lines[len(lines)-3] != "  ]" || lines[len(lines)-2] != "  2" || lines[len(lines)-1] != "]" {
+		t.Errorf("wrong tall rendering:\n%v", s)
+	}
+	for _, l := range lines {
+		if len(l) > 40 {
+			t.Errorf("line exceeds width: %q", l)
+		}
+	}
+
+	for _, bad := range []string{"", "[@ud]", "(list)", "(foo @ud)", "(map @ud)", "[@ud @ud", "@ud)"} {
+		if _, err := ParseType(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+	}{
+		{"1", "1"},
+		{"[1 2]", "[1 2]"},
+		{"[1 [%foo ~zod] ~[1 2 3]]", "[1 [%foo ~zod] 1 2 3 0]"},
+		{"~", "~"},
+		{"[~ ~]", "[~ ~]"},
+		{"~[~ ~]", "[~ ~ 0]"},
+		{`"hi \"there\"\0a"`, `"hi \"there\"\0a"`},
+		{`'it\'s'`, `'it\'s'`},
+		{"[0x1.0000 0b101 0v1f 0w-]", "[0x1.0000 0b101 0v1f 0w-]"},
+		{"[--5 -0x5 .1.5 .~1.5 .~~~1.5]", "[--5 -0x5 .1.5 .~1.5 .~~~1.5]"},
+		{"[%.y & | %$ %foo-bar1]", "[& & | %$ %foo-bar1]"},
+		{"[~.foo .~marzod ~-a .127.0.0.1]", "[~.foo .~marzod ~-a .127.0.0.1]"},
+		{"[~2020.1.1 ~s30 ~d1.h2 ~marzod ~dozsun-dapfel]", "[~2020.1.1 ~s30 ~d1.h2 ~marzod ~dozsun-dapfel]"},
+		{"  [ 1\n    2 ]  ", "[1 2]"},
+		{"[[1 2] 3]", "[[1 2] 3]"},
+		{"'ツ'", "'ツ'"},
+		{`"ツ"`, `"ツ"`},
+	}
+	for _, test := range tests {
+		n, err := Parse(test.s)
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+		} else if s := Format(n, nil, 0); s != test.exp {
+			t.Errorf("%q: expected %v, got %v", test.s, test.exp, s)
+		}
+	}
+	if n, _ := Parse("[1 2 3]"); !Equal(n, Cell{Uint(1), Cell{Uint(2), Uint(3)}}) {
+		t.Error("wrong cell structure:", n)
+	}
+
+	for _, s := range []string{"", "[1]", "[1 2", "~[]", "[1 2]]", "foo", "(add 1 2)", "[1 2][3 4]", "1.00", `"a{b}"`, "'a", "+(1)", "0xg"} {
+		if n, err := Parse(s); err == nil {
+			t.Errorf("%q: expected error, got %v", s, n)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package noun
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lukechampine.com/urbit/atom"
+	"lukechampine.com/urbit/hoon/scanner"
+	"lukechampine.com/urbit/hoon/token"
+)
+
+// Parse parses a noun written in Hoon literal syntax, such as
+// [1 [%foo ~zod] ~[1 2 3]]. It supports cells, lists (~[...]), tapes, cords,
+// ~, and atom literals of any aura supported by atom.Parse. Atoms retain the
+// aura indicated by their literal. Non-literal expressions are an error.
+func Parse(s string) (Noun, error) {
+	p := &nounParser{s: scanner.New([]byte(s))}
+	p.next()
+	p.skipSpace()
+	n, err := p.parseNoun()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.tok != token.EOF {
+		return nil, fmt.Errorf("unexpected %q after noun", p.lit)
+	}
+	return n, nil
+}
+
+type nounParser struct {
+	s   *scanner.Scanner
+	tok token.Token
+	lit string
+}
+
+func (p *nounParser) next() {
+	p.tok, p.lit = p.s.Scan()
+}
+
+func (p *nounParser) skipSpace() bool {
+	skipped := false
+	for p.tok == token.Ace || p.tok == token.Gap {
+		p.next()
+		skipped = true
+	}
+	return skipped
+}
+
+// parseSeq parses space-separated nouns until a closing ].
+func (p *nounParser) parseSeq() ([]Noun, error) {
+	var ns []Noun
+	for {
+		p.skipSpace()
+		if p.tok == token.Ser {
+			p.next()
+			return ns, nil
+		}
+		n, err := p.parseNoun()
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+		if !p.skipSpace() && p.tok != token.Ser {
+			if p.tok == token.EOF {
+				return nil, fmt.Errorf("missing ]")
+			}
+			return nil, fmt.Errorf("unexpected %q", p.lit)
+		}
+	}
+}
+
+// glued reports whether tok can be part of an atom literal.
+func glued(tok token.Token) bool {
+	switch tok {
+	case token.Ace, token.Gap, token.Sel, token.Ser, token.EOF, token.Comment, token.Cord, token.Tape:
+		return false
+	}
+	return true
+}
+
+func (p *nounParser) parseNoun() (Noun, error) {
+	switch p.tok {
+	case token.EOF:
+		return nil, fmt.Errorf("unexpected end of input")
+	case token.Sel:
+		p.next()
+		ns, err := p.parseSeq()
+		if err != nil {
+			return nil, err
+		} else if len(ns) < 2 {
+			return nil, fmt.Errorf("cell must have at least two elements")
+		}
+		return tuple(ns), nil
+	case token.Tape:
+		s, err := unescapeTape(p.lit)
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		return Tape(s), nil
+	case token.Cord:
+		a, err := atom.ParseAs(atom.AuraT, p.lit)
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		return Atom{a}, nil
+	case token.Sig:
+		p.next()
+		if p.tok == token.Sel {
+			p.next()
+			ns, err := p.parseSeq()
+			if err != nil {
+				return nil, err
+			} else if len(ns) == 0 {
+				return nil, fmt.Errorf("list must have at least one element")
+			}
+			return List(ns...), nil
+		} else if !glued(p.tok) {
+			return Atom{atom.New64(0).Cast(atom.AuraN)}, nil
+		}
+		return p.parseAtom("~")
+	case token.ILLEGAL:
+		return nil, fmt.Errorf("invalid literal %q", p.lit)
+	}
+	return p.parseAtom("")
+}
+
+func (p *nounParser) parseAtom(prefix string) (Noun, error) {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for glued(p.tok) {
+		sb.WriteString(p.lit)
+		p.next()
+	}
+	a, err := atom.Parse(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a literal: %w", sb.String(), err)
+	}
+	return Atom{a}, nil
+}
+
+// unescapeTape interprets the escapes in a quoted tape literal: \\, \", and
+// \hh. Interpolation is not supported.
+func unescapeTape(s string) (string, error) {
+	var sb strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		switch c := s[i]; c {
+		case '{':
+			return "", fmt.Errorf("tape interpolation is not supported in %v", s)
+		case '\\':
+			if i+1 < len(s)-1 && strings.IndexByte(`\"{`, s[i+1]) >= 0 {
+				sb.WriteByte(s[i+1])
+				i++
+				continue
+			} else if i+2 >= len(s)-1 {
+				return "", fmt.Errorf("invalid escape in %v", s)
+			}
+			b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape in %v", s)
+			}
+			sb.WriteByte(byte(b))
+			i += 2
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,209 @@
+package noun
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"lukechampine.com/urbit/atom"
+)
+
+// DefaultWidth is the line width used by Print, matching the Dojo.
+const DefaultWidth = 80
+
+// A tank is a rendered noun, which can be laid out either on one line (wide)
+// or across several (tall), like Hoon's $tank.
+type tank struct {
+	leaf   string
+	face   string // printed as face=..., if non-empty
+	open   string
+	close  string
+	inline bool // in tall mode, place the first child on the opening line
+	kids   []tank
+}
+
+func (t tank) wide() string {
+	if t.kids == nil {
+		return t.face + t.leaf
+	}
+	parts := make([]string, len(t.kids))
+	for i, k := range t.kids {
+		parts[i] = k.wide()
+	}
+	return t.face + t.open + strings.Join(parts, " ") + t.close
+}
+
+// layout writes t to sb, assuming the cursor is at column indent, breaking it
+// across lines if it does not fit within width. A width <= 0 is unlimited.
+func (t tank) layout(sb *strings.Builder, indent, width int) {
+	w := t.wide()
+	if t.kids == nil || width <= 0 || indent+utf8.RuneCountInString(w) <= width {
+		sb.WriteString(w)
+		return
+	}
+	open := t.face + t.open
+	sb.WriteString(open)
+	kidIndent := indent + 2
+	if t.inline {
+		kidIndent = indent + len(open) + 1
+	}
+	for i, k := range t.kids {
+		if i == 0 && t.inline {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteString("\n" + strings.Repeat(" ", kidIndent))
+		}
+		k.layout(sb, kidIndent, width)
+	}
+	sb.WriteString("\n" + strings.Repeat(" ", indent) + t.close)
+}
+
+// Format renders n as the Dojo would, wrapping lines longer than width (a
+// width <= 0 disables wrapping). The hint, which may be nil, describes the
+// shape of n; without it, atoms are rendered according to their auras, cells
+// are rendered as tuples, and lists of @tD are rendered as tapes. If n does
+// not match the hint, the mismatched portion is rendered without it.
+func Format(n Noun, hint Type, width int) string {
+	var sb strings.Builder
+	render(n, hint).layout(&sb, 0, width)
+	return sb.String()
+}
+
+// Print writes the rendering of n, wrapped at DefaultWidth, to w, followed by
+// a newline.
+func Print(w io.Writer, n Noun, hint Type) error {
+	_, err := io.WriteString(w, Format(n, hint, DefaultWidth)+"\n")
+	return err
+}
+
+// String implements fmt.Stringer.
+func (c Cell) String() string {
+	return Format(c, nil, 0)
+}
+
+func render(n Noun, hint Type) tank {
+	switch t := hint.(type) {
+	case AtomType:
+		if a, ok := n.(Atom); ok {
+			return tank{leaf: a.Cast(t.Aura).String()}
+		}
+	case FaceType:
+		k := render(n, t.Type)
+		k.face = t.Face + "=" + k.face
+		return k
+	case ListType:
+		if IsNull(n) {
+			return tank{leaf: "~"}
+		}
+		ns, err := Slice(n)
+		if err != nil {
+			break
+		}
+		if et, ok := t.Elem.(AtomType); ok && isTapeAura(et.Aura) {
+			if s, ok := tapeString(ns, false); ok {
+				return tank{leaf: s}
+			}
+		}
+		return seq("~[", "]", ns, t.Elem)
+	case UnitType:
+		if IsNull(n) {
+			return tank{leaf: "~"}
+		} else if c, ok := n.(Cell); ok && IsNull(c.Head) {
+			return tank{open: "[", close: "]", inline: true, kids: []tank{{leaf: "~"}, render(c.Tail, t.Elem)}}
+		}
+	case MapType:
+		ns, err := TreeNodes(n)
+		if err != nil {
+			break
+		}
+		return seq("{", "}", tapOrder(ns), TupleType{FaceType{"p", t.Key}, FaceType{"q", t.Val}})
+	case SetType:
+		ns, err := TreeNodes(n)
+		if err != nil {
+			break
+		}
+		return seq("{", "}", tapOrder(ns), t.Elem)
+	case TupleType:
+		var kids []tank
+		for _, et := range t[:len(t)-1] {
+			c, ok := n.(Cell)
+			if !ok {
+				return render(n, nil)
+			}
+			kids = append(kids, render(c.Head, et))
+			n = c.Tail
+		}
+		last := render(n, t[len(t)-1])
+		if last.open == "[" && last.face == "" && last.close == "]" {
+			kids = append(kids, last.kids...)
+		} else {
+			kids = append(kids, last)
+		}
+		return tank{open: "[", close: "]", inline: true, kids: kids}
+	}
+
+	// no hint
+	switch n := n.(type) {
+	case Atom:
+		return tank{leaf: n.String()}
+	case Cell:
+		if ns, err := Slice(n); err == nil {
+			if s, ok := tapeString(ns, true); ok {
+				return tank{leaf: s}
+			}
+		}
+		kids := []tank{render(n.Head, nil)}
+		var tail Noun = n.Tail
+		for {
+			c, ok := tail.(Cell)
+			if !ok {
+				break
+			}
+			kids = append(kids, render(c.Head, nil))
+			tail = c.Tail
+		}
+		kids = append(kids, render(tail, nil))
+		return tank{open: "[", close: "]", inline: true, kids: kids}
+	}
+	panic("unreachable")
+}
+
+func seq(open, close string, ns []Noun, elem Type) tank {
+	kids := make([]tank, len(ns))
+	for i, n := range ns {
+		kids[i] = render(n, elem)
+	}
+	if len(kids) == 0 {
+		return tank{leaf: open + close}
+	}
+	return tank{open: open, close: close, kids: kids}
+}
+
+// tapOrder reorders the in-order nodes of a tree to match Hoon's ++tap,
+// which lists nodes in reverse order.
+func tapOrder(ns []Noun) []Noun {
+	r := make([]Noun, len(ns))
+	for i := range ns {
+		r[len(ns)-i-1] = ns[i]
+	}
+	return r
+}
+
+func isTapeAura(a atom.Aura) bool {
+	return a == "tD" || a == "td"
+}
+
+// tapeString renders ns as a tape, if every element is a byte atom (with a
+// tape aura, if checkAura is set).
+func tapeString(ns []Noun, checkAura bool) (string, bool) {
+	b := make([]byte, len(ns))
+	for i, n := range ns {
+		a, ok := n.(Atom)
+		if !ok || (checkAura && !isTapeAura(a.Aura())) || len(a.Bytes()) > 1 {
+			return "", false
+		} else if len(a.Bytes()) == 1 {
+			b[i] = a.Bytes()[0]
+		}
+	}
+	return atom.Quote(string(b), '"'), true
+}
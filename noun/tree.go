@@ -0,0 +1,92 @@
+package noun
+
+import "errors"
+
+// Hoon maps and sets are treaps: binary trees ordered by gor and
+// heap-ordered by mor. Each node is the noun [n l r], where n is the node's
+// value (a [key value] pair for maps) and l and r are subtrees; the empty
+// tree is ~.
+
+type treeNode struct {
+	key  Noun
+	n    Noun
+	l, r *treeNode
+}
+
+// put inserts n, keyed by key, into t, as in Hoon's ++put:by.
+func (t *treeNode) put(key, n Noun) *treeNode {
+	if t == nil {
+		return &treeNode{key: key, n: n}
+	} else if Equal(key, t.key) {
+		t.n = n
+		return t
+	}
+	if gor(key, t.key) {
+		d := t.l.put(key, n)
+		if mor(t.key, d.key) {
+			t.l = d
+			return t
+		}
+		t.l, d.r = d.r, t
+		return d
+	}
+	d := t.r.put(key, n)
+	if mor(t.key, d.key) {
+		t.r = d
+		return t
+	}
+	t.r, d.l = d.l, t
+	return d
+}
+
+func (t *treeNode) noun() Noun {
+	if t == nil {
+		return Null
+	}
+	return Cell{t.n, Cell{t.l.noun(), t.r.noun()}}
+}
+
+// Map returns the Hoon map containing the specified [key value] pairs.
+func Map(pairs ...Cell) Noun {
+	var t *treeNode
+	for _, p := range pairs {
+		t = t.put(p.Head, p)
+	}
+	return t.noun()
+}
+
+// Set returns the Hoon set containing the specified nouns.
+func Set(ns ...Noun) Noun {
+	var t *treeNode
+	for _, n := range ns {
+		t = t.put(n, n)
+	}
+	return t.noun()
+}
+
+// TreeNodes returns the values stored in the Hoon map or set t: [key value]
+// pairs in the case of a map, or elements in the case of a set. The values
+// are returned in tree order.
+func TreeNodes(t Noun) ([]Noun, error) {
+	var ns []Noun
+	var walk func(t Noun) error
+	walk = func(t Noun) error {
+		if IsNull(t) {
+			return nil
+		}
+		c, ok := t.(Cell)
+		if !ok {
+			return errors.New("tree is not a cell")
+		}
+		lr, ok := c.Tail.(Cell)
+		if !ok {
+			return errors.New("tree node is missing subtrees")
+		}
+		if err := walk(lr.Head); err != nil {
+			return err
+		}
+		ns = append(ns, c.Head)
+		return walk(lr.Tail)
+	}
+	return ns, walk(t)
+}
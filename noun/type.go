@@ -0,0 +1,176 @@
+package noun
+
+import (
+	"fmt"
+	"strings"
+
+	"lukechampine.com/urbit/atom"
+)
+
+// A Type is a hint describing the shape of a noun, used when printing. A nil
+// Type describes any noun.
+type Type interface {
+	isType()
+}
+
+func (AtomType) isType()  {}
+func (ListType) isType()  {}
+func (UnitType) isType()  {}
+func (MapType) isType()   {}
+func (SetType) isType()   {}
+func (TupleType) isType() {}
+func (FaceType) isType()  {}
+
+// An AtomType is an atom with the specified aura.
+type AtomType struct {
+	Aura atom.Aura
+}
+
+// A ListType is a null-terminated list. A list of @tD is printed as a tape.
+type ListType struct {
+	Elem Type
+}
+
+// A UnitType is either ~ or [~ u], where u has type Elem.
+type UnitType struct {
+	Elem Type
+}
+
+// A MapType is a Hoon map from Key to Val.
+type MapType struct {
+	Key, Val Type
+}
+
+// A SetType is a Hoon set of Elem.
+type SetType struct {
+	Elem Type
+}
+
+// A TupleType is a right-nested tuple; its final element describes the
+// remainder of the tuple.
+type TupleType []Type
+
+// A FaceType is a Type with a name, printed as face=value.
+type FaceType struct {
+	Face string
+	Type Type
+}
+
+// TapeType is the type of a tape.
+var TapeType = ListType{AtomType{"tD"}}
+
+// ParseType parses a type hint written in a subset of Hoon's type syntax:
+// * (any noun), @ and auras such as @ux, tape, (list T), (unit T), (map K V),
+// (set T), tuples [T1 T2 ...], and faces face=T.
+func ParseType(s string) (Type, error) {
+	p := &typeParser{s: s}
+	t := p.parse()
+	if p.err == nil && p.s != "" {
+		p.err = fmt.Errorf("unexpected %q", p.s)
+	}
+	if p.err != nil {
+		return nil, fmt.Errorf("invalid type %q: %w", s, p.err)
+	}
+	return t, nil
+}
+
+type typeParser struct {
+	s   string
+	err error
+}
+
+func (p *typeParser) fail(format string, args ...interface{}) Type {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+	return nil
+}
+
+func (p *typeParser) word() string {
+	i := 0
+	for i < len(p.s) && ('a' <= p.s[i] && p.s[i] <= 'z' || 'A' <= p.s[i] && p.s[i] <= 'Z' || '0' <= p.s[i] && p.s[i] <= '9' || p.s[i] == '-') {
+		i++
+	}
+	w := p.s[:i]
+	p.s = p.s[i:]
+	return w
+}
+
+// list parses space-separated types until the closing delimiter.
+func (p *typeParser) list(close byte) []Type {
+	var ts []Type
+	for p.err == nil {
+		if p.s == "" {
+			p.fail("missing %q", close)
+			return nil
+		} else if p.s[0] == close {
+			p.s = p.s[1:]
+			return ts
+		} else if len(ts) > 0 {
+			if p.s[0] != ' ' {
+				return []Type{p.fail("expected space, got %q", p.s)}
+			}
+			p.s = p.s[1:]
+		}
+		ts = append(ts, p.parse())
+	}
+	return nil
+}
+
+func (p *typeParser) parse() Type {
+	switch {
+	case p.s == "":
+		return p.fail("unexpected end of input")
+	case p.s[0] == '*':
+		p.s = p.s[1:]
+		return nil
+	case p.s[0] == '@':
+		p.s = p.s[1:]
+		return AtomType{atom.Aura(p.word())}
+	case p.s[0] == '[':
+		p.s = p.s[1:]
+		ts := p.list(']')
+		if p.err == nil && len(ts) < 2 {
+			return p.fail("tuple must have at least two elements")
+		}
+		return TupleType(ts)
+	case p.s[0] == '(':
+		p.s = p.s[1:]
+		mold := p.word()
+		if p.s == "" || p.s[0] != ' ' {
+			return p.fail("expected arguments to %q", mold)
+		}
+		p.s = p.s[1:]
+		args := p.list(')')
+		if p.err != nil {
+			return nil
+		}
+		arity := map[string]int{"list": 1, "unit": 1, "set": 1, "map": 2}
+		if n, ok := arity[mold]; !ok {
+			return p.fail("unknown mold %q", mold)
+		} else if len(args) != n {
+			return p.fail("%v takes %v arguments, got %v", mold, n, len(args))
+		}
+		switch mold {
+		case "list":
+			return ListType{args[0]}
+		case "unit":
+			return UnitType{args[0]}
+		case "set":
+			return SetType{args[0]}
+		default:
+			return MapType{args[0], args[1]}
+		}
+	}
+	w := p.word()
+	switch {
+	case w == "tape":
+		return TapeType
+	case w == "cord":
+		return AtomType{atom.AuraT}
+	case w != "" && strings.HasPrefix(p.s, "="):
+		p.s = p.s[1:]
+		return FaceType{w, p.parse()}
+	}
+	return p.fail("unexpected %q", p.s)
+}
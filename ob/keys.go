@@ -0,0 +1,181 @@
+package ob
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"lukechampine.com/urbit/atom"
+)
+
+// NetworkKeys are the encryption and authentication keypairs that a ship uses
+// to communicate over Ames.
+type NetworkKeys struct {
+	Crypt ed25519.PrivateKey
+	Auth  ed25519.PrivateKey
+}
+
+// Pass returns the public half of k.
+func (k NetworkKeys) Pass() Pass {
+	return PassFromKeys(k.Crypt.Public().(ed25519.PublicKey), k.Auth.Public().(ed25519.PublicKey))
+}
+
+// Ring returns the private half of k.
+func (k NetworkKeys) Ring() Ring {
+	var r Ring
+	r[0] = 'B'
+	copy(r[1:33], k.Auth.Seed())
+	copy(r[33:], k.Crypt.Seed())
+	return r
+}
+
+// DeriveNetworkKeys derives a set of NetworkKeys from a seed, as +pit:nu:crub
+// does.
+func DeriveNetworkKeys(seed []byte) NetworkKeys {
+	bits := sha512.Sum512(seed)
+	return NetworkKeys{
+		Crypt: ed25519.NewKeyFromSeed(bits[32:]),
+		Auth:  ed25519.NewKeyFromSeed(bits[:32]),
+	}
+}
+
+// NetworkKeysFromTicket derives a set of NetworkKeys, using the specified @q
+// ticket as the seed.
+func NetworkKeysFromTicket(ticket string) (NetworkKeys, error) {
+	seed, err := ParseTicket(ticket)
+	if err != nil {
+		return NetworkKeys{}, err
+	}
+	return DeriveNetworkKeys(seed), nil
+}
+
+// A Pass is the public half of a ship's NetworkKeys: a 'b' tag byte followed
+// by the public authentication and encryption keys.
+type Pass [65]byte
+
+// Crypt returns the public encryption key of p.
+func (p Pass) Crypt() ed25519.PublicKey { return ed25519.PublicKey(p[33:]) }
+
+// Auth returns the public authentication key of p.
+func (p Pass) Auth() ed25519.PublicKey { return ed25519.PublicKey(p[1:33]) }
+
+// Atom returns p as an atom, in the form used by Jael and Ames.
+func (p Pass) Atom() atom.Atom { return atom.FromBytes(flip(p[:])) }
+
+// Fingerprint returns the 128-bit fingerprint of p. For comets, the fingerprint
+// is the comet's address.
+func (p Pass) Fingerprint() [16]byte {
+	pubsum := sha256.Sum256(p[:])
+	binary.LittleEndian.PutUint32(pubsum[:], binary.LittleEndian.Uint32(pubsum[:])^0x67696662)
+	h := sha256.Sum256(pubsum[:])
+	var fp [16]byte
+	for i := range fp {
+		fp[15-i] = h[i] ^ h[16+i]
+	}
+	return fp
+}
+
+// PassFromKeys returns the Pass formed from the specified public encryption
+// and authentication keys, such as those stored in Azimuth.
+func PassFromKeys(crypt, auth ed25519.PublicKey) Pass {
+	var p Pass
+	p[0] = 'b'
+	copy(p[1:33], auth)
+	copy(p[33:], crypt)
+	return p
+}
+
+// ParsePass parses a Pass from its atom form.
+func ParsePass(a atom.Atom) (Pass, error) {
+	var p Pass
+	if err := parseKey(p[:], a, 'b'); err != nil {
+		return Pass{}, err
+	}
+	return p, nil
+}
+
+// A Ring is the private half of a ship's NetworkKeys: a 'B' tag byte followed
+// by the private authentication and encryption seeds.
+type Ring [65]byte
+
+// Atom returns r as an atom, in the form used by Jael and Ames.
+func (r Ring) Atom() atom.Atom { return atom.FromBytes(flip(r[:])) }
+
+// NetworkKeys returns the NetworkKeys that r encodes.
+func (r Ring) NetworkKeys() NetworkKeys {
+	return NetworkKeys{
+		Crypt: ed25519.NewKeyFromSeed(r[33:]),
+		Auth:  ed25519.NewKeyFromSeed(r[1:33]),
+	}
+}
+
+// ParseRing parses a Ring from its atom form.
+func ParseRing(a atom.Atom) (Ring, error) {
+	var r Ring
+	if err := parseKey(r[:], a, 'B'); err != nil {
+		return Ring{}, err
+	}
+	return r, nil
+}
+
+func parseKey(dst []byte, a atom.Atom, tag byte) error {
+	// the atom is little-endian, so a key whose last byte is zero is shorter
+	b := a.Bytes()
+	if len(b) > len(dst) {
+		return errors.New("invalid key length")
+	}
+	copy(dst, flip(b))
+	if dst[0] != tag {
+		return errors.New("invalid key tag")
+	}
+	return nil
+}
+
+// Matches returns whether c is the comet whose address is derived from p.
+func (c Comet) Matches(p Pass) bool {
+	return p.Fingerprint() == c
+}
+
+// ParseTicket parses a @q-encoded ticket, e.g. ~sampel-ticlyt-migfun-falmel,
+// returning its big-endian bytes.
+func ParseTicket(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "~") {
+		return nil, errors.New("missing ~ prefix")
+	}
+	words := strings.Split(s[1:], "-")
+	var buf []byte
+	for i, w := range words {
+		var syls []string
+		switch {
+		case len(w) == 6:
+			syls = []string{w[:3], w[3:]}
+		case len(w) == 3 && i == 0:
+			syls = []string{w}
+		default:
+			return nil, errors.New("invalid ticket word")
+		}
+		for j, syl := range syls {
+			table := &suffixes
+			if j == 0 && len(syls) == 2 {
+				table = &prefixes
+			}
+			b, ok := phonemeIndex[syl]
+			if !ok || table[b] != syl {
+				return nil, errors.New("invalid phoneme")
+			}
+			buf = append(buf, b)
+		}
+	}
+	return buf, nil
+}
+
+func flip(b []byte) []byte {
+	f := make([]byte, len(b))
+	for i := range f {
+		f[i] = b[len(b)-i-1]
+	}
+	return f
+}
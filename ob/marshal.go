@@ -0,0 +1,191 @@
+package ob
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lukechampine.com/urbit/internal/fmtutil"
+)
+
+// ParseComet parses a comet name, such as
+// ~dasres-ragnep-lislyt-ritpur--fasrun-pocsel-nopfel-fabnec.
+func ParseComet(n string) (Comet, error) {
+	halves := strings.Split(strings.TrimPrefix(n, "~"), "--")
+	if !strings.HasPrefix(n, "~") || len(halves) != 2 {
+		return Comet{}, fmt.Errorf("invalid comet name %q", n)
+	}
+	words := append(strings.Split(halves[0], "-"), strings.Split(halves[1], "-")...)
+	if len(words) != 8 {
+		return Comet{}, fmt.Errorf("invalid comet name %q", n)
+	}
+	var c Comet
+	for i, w := range words {
+		p, err := PointFromName("~" + w)
+		if err != nil || p.IsPlanet() {
+			return Comet{}, fmt.Errorf("invalid comet name %q", n)
+		}
+		binary.BigEndian.PutUint16(c[i*2:], uint16(p))
+	}
+	if c.String() != n {
+		// PointFromName accepts prefixes and suffixes interchangeably
+		return Comet{}, fmt.Errorf("invalid comet name %q", n)
+	}
+	return c, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p AzimuthPoint) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *AzimuthPoint) UnmarshalText(b []byte) error {
+	q, err := PointFromName(string(b))
+	if err != nil {
+		return err
+	}
+	*p = q
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Points are encoded as their name.
+func (p AzimuthPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts names as well as
+// integers, either bare or in a string.
+func (p *AzimuthPoint) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	return p.parse(s)
+}
+
+// Value implements driver.Valuer. Points are stored as integers.
+func (p AzimuthPoint) Value() (driver.Value, error) {
+	return int64(p), nil
+}
+
+// Scan implements sql.Scanner. It accepts integers, as well as names and
+// decimal strings.
+func (p *AzimuthPoint) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case int64:
+		if src < 0 || src > 1<<32-1 {
+			return fmt.Errorf("point %v out of range", src)
+		}
+		*p = AzimuthPoint(src)
+		return nil
+	case string:
+		return p.parse(src)
+	case []byte:
+		return p.parse(string(src))
+	case nil:
+		return errors.New("cannot scan NULL into AzimuthPoint")
+	default:
+		return fmt.Errorf("cannot scan %T into AzimuthPoint", src)
+	}
+}
+
+func (p *AzimuthPoint) parse(s string) error {
+	if strings.HasPrefix(s, "~") {
+		return p.UnmarshalText([]byte(s))
+	}
+	u, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid point %q", s)
+	}
+	*p = AzimuthPoint(u)
+	return nil
+}
+
+// Format implements fmt.Formatter. The %v and %s verbs print the point's name,
+// %q prints it as a quoted Go string, and the integer verbs %d, %x, %X, %o,
+// %O, and %b format its numeric value.
+func (p AzimuthPoint) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's', 'q':
+		fmt.Fprintf(f, fmtutil.FormatString(f, verb), p.String())
+	case 'd', 'x', 'X', 'o', 'O', 'b':
+		fmt.Fprintf(f, fmtutil.FormatString(f, verb), uint32(p))
+	default:
+		fmt.Fprintf(f, "%%!%c(ob.AzimuthPoint=%v)", verb, p.String())
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Comet) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Comet) UnmarshalText(b []byte) error {
+	d, err := ParseComet(string(b))
+	if err != nil {
+		return err
+	}
+	*c = d
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Comets are encoded as their name.
+func (c Comet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Comet) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return c.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer. Comets are stored as their name.
+func (c Comet) Value() (driver.Value, error) {
+	return c.String(), nil
+}
+
+// Scan implements sql.Scanner. It accepts comet names.
+func (c *Comet) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return c.UnmarshalText([]byte(src))
+	case []byte:
+		return c.UnmarshalText(src)
+	case nil:
+		return errors.New("cannot scan NULL into Comet")
+	default:
+		return fmt.Errorf("cannot scan %T into Comet", src)
+	}
+}
+
+// Format implements fmt.Formatter. The %v and %s verbs print the comet's
+// name, %q prints it as a quoted Go string, and %x and %X print its bytes in
+// hex.
+func (c Comet) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's', 'q':
+		fmt.Fprintf(f, fmtutil.FormatString(f, verb), c.String())
+	case 'x', 'X':
+		fmt.Fprintf(f, fmtutil.FormatString(f, verb), c[:])
+	default:
+		fmt.Fprintf(f, "%%!%c(ob.Comet=%v)", verb, c.String())
+	}
+}
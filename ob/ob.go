@@ -2,10 +2,7 @@ package ob
 
 import (
 	"bytes"
-	"crypto/ed25519"
 	"crypto/rand"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -27,19 +24,23 @@ func (p AzimuthPoint) IsPlanet() bool { return 65536 <= p }
 func (p AzimuthPoint) ChildStar(i uint8) AzimuthPoint {
 	if !p.IsGalaxy() {
 		panic("only galaxies can spawn stars")
-	} else if i == 0 {
-		panic("child index must be greater than 0")
 	}
-	return p | (AzimuthPoint(i) << 8)
+	c, err := p.Child(uint16(i))
+	if err != nil {
+		panic(err)
+	}
+	return c
 }
 
 func (p AzimuthPoint) ChildPlanet(i uint16) AzimuthPoint {
 	if !p.IsStar() {
 		panic("only stars can spawn planets")
-	} else if i == 0 {
-		panic("child index must be greater than 0")
 	}
-	return p | (AzimuthPoint(i) << 16)
+	c, err := p.Child(i)
+	if err != nil {
+		panic(err)
+	}
+	return c
 }
 
 func (p AzimuthPoint) Parent() AzimuthPoint {
@@ -54,6 +55,96 @@ func (p AzimuthPoint) Parent() AzimuthPoint {
 	panic("unreachable")
 }
 
+// Child returns the i'th child of p: a star if p is a galaxy, or a planet if p
+// is a star.
+func (p AzimuthPoint) Child(i uint16) (AzimuthPoint, error) {
+	switch {
+	case i == 0:
+		return 0, errors.New("child index must be greater than 0")
+	case p.IsGalaxy() && i > 0xFF:
+		return 0, errors.New("galaxies only have 255 children")
+	case p.IsGalaxy():
+		return p | (AzimuthPoint(i) << 8), nil
+	case p.IsStar():
+		return p | (AzimuthPoint(i) << 16), nil
+	default:
+		return 0, errors.New("planets do not have children")
+	}
+}
+
+// ChildIndex returns the index of p within its parent, i.e. the inverse of
+// Child. Galaxies have an index of 0.
+func (p AzimuthPoint) ChildIndex() uint16 {
+	switch {
+	case p.IsStar():
+		return uint16(p >> 8)
+	case p.IsPlanet():
+		return uint16(p >> 16)
+	}
+	return 0
+}
+
+// NumChildren returns the number of points that p can spawn.
+func (p AzimuthPoint) NumChildren() int {
+	switch {
+	case p.IsGalaxy():
+		return 0xFF
+	case p.IsStar():
+		return 0xFFFF
+	}
+	return 0
+}
+
+// Children calls fn on each child of p, in order, until fn returns false.
+func (p AzimuthPoint) Children(fn func(AzimuthPoint) bool) error {
+	if p.IsPlanet() {
+		return errors.New("planets do not have children")
+	}
+	for i := 1; i <= p.NumChildren(); i++ {
+		c, _ := p.Child(uint16(i))
+		if !fn(c) {
+			break
+		}
+	}
+	return nil
+}
+
+// Siblings calls fn on each point (other than p itself) that shares p's parent,
+// in order, until fn returns false. The siblings of a galaxy are the other
+// galaxies.
+func (p AzimuthPoint) Siblings(fn func(AzimuthPoint) bool) {
+	visit := func(q AzimuthPoint) bool { return q == p || fn(q) }
+	if p.IsGalaxy() {
+		for q := AzimuthPoint(0); q.IsGalaxy(); q++ {
+			if !visit(q) {
+				return
+			}
+		}
+		return
+	}
+	p.Parent().Children(visit)
+}
+
+// Ancestors returns the chain of points above p, starting with its parent.
+func (p AzimuthPoint) Ancestors() []AzimuthPoint {
+	var as []AzimuthPoint
+	for !p.IsGalaxy() {
+		p = p.Parent()
+		as = append(as, p)
+	}
+	return as
+}
+
+// IsDescendantOf returns whether q is an ancestor of p.
+func (p AzimuthPoint) IsDescendantOf(q AzimuthPoint) bool {
+	for _, a := range p.Ancestors() {
+		if a == q {
+			return true
+		}
+	}
+	return false
+}
+
 func (p AzimuthPoint) String() string {
 	buf := make([]byte, 4)
 	binary.BigEndian.PutUint32(buf, fein(p))
@@ -105,26 +196,12 @@ func FindComet(star AzimuthPoint) (Comet, string) {
 	}
 	seed := make([]byte, 32)
 	rand.Read(seed)
-	pubbuf, secbuf := [65]byte{'b'}, [65]byte{'B'}
 	for ; ; *(*uint64)(unsafe.Pointer(&seed[0]))++ {
-		// derive keypair
-		bits := sha512.Sum512(seed)
-		cry := ed25519.NewKeyFromSeed(bits[:32])
-		sgn := ed25519.NewKeyFromSeed(bits[32:])
-		pub := append(append(pubbuf[:1], cry[32:]...), sgn[32:]...)
-		sec := append(append(secbuf[:1], cry[:32]...), sgn[:32]...)
-
-		// fingerprint
-		pubsum := sha256.Sum256(pub)
-		*(*uint32)(unsafe.Pointer(&pubsum)) ^= 0x67696662
-		h := sha256.Sum256(pubsum[:])
-		var c Comet
-		for i := range c {
-			c[15-i] = h[i] ^ h[16+i]
-		}
-
+		keys := DeriveNetworkKeys(seed)
+		c := Comet(keys.Pass().Fingerprint())
 		if c.Parent() == star {
-			return c, atom.FromBytes(jamComet(c, sec)).Format("uw")
+			ring := keys.Ring()
+			return c, atom.FromBytes(jamComet(c, ring[:])).Format("uw")
 		}
 	}
 }
@@ -160,10 +237,7 @@ func jamComet(who Comet, key []byte) []byte {
 	}
 
 	// flip key endianness
-	flippedKey := make([]byte, len(key))
-	for i := range flippedKey {
-		flippedKey[i] = key[len(key)-i-1]
-	}
+	flippedKey := flip(key)
 
 	// jam to binary, pad to byte-boundary, decode to bytes
 	bits := jam(nil, flippedKey, []byte{1}, who[:])
@@ -2,8 +2,12 @@ package ob
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"testing"
+
+	"lukechampine.com/urbit/atom"
 )
 
 func TestComet(t *testing.T) {
@@ -16,7 +20,7 @@ func TestComet(t *testing.T) {
 	}
 
 	sk, _ := hex.DecodeString("4230e39bc7a387ec3b9f4f08d68c0ea0093e0bb4ef1f5c618494ae6c7fb4f4e2c2604f698a5e28a63996eb6886d04816188d538864883083d98fa549be5e5bfc55")
-	jam := formatUW(jamComet(c, sk))
+	jam := atom.FromBytes(jamComet(c, sk)).Format("uw")
 	if jam != "0w2.G~ySL.nOjiN.-P1C4.gOh2D.6z0IA.q4cQt.sIsQN.gLhji.DI65N.uBE~J.Btagz.2K3~v.q1pY4.Q0t6q.MgDPV.TSgZ7.zPv6o.8g7w0.svYA~.tetGV.buTc~.89PRD.EO-M1" {
 		t.Fatal("bad jam for comet")
 	}
@@ -70,3 +74,176 @@ func TestInjectivity(t *testing.T) {
 		}
 	}
 }
+
+func TestNetworkKeys(t *testing.T) {
+	var c Comet
+	hex.Decode(c[:], []byte("1fe49fba73b5725bdb99f904e7acf465"))
+	var ring Ring
+	hex.Decode(ring[:], []byte("4230e39bc7a387ec3b9f4f08d68c0ea0093e0bb4ef1f5c618494ae6c7fb4f4e2c2604f698a5e28a63996eb6886d04816188d538864883083d98fa549be5e5bfc55"))
+
+	keys := ring.NetworkKeys()
+	if keys.Ring() != ring {
+		t.Fatal("ring did not round-trip through NetworkKeys")
+	} else if !c.Matches(keys.Pass()) {
+		t.Fatal("comet should match its pass")
+	} else if c.Matches(DeriveNetworkKeys([]byte("foo")).Pass()) {
+		t.Fatal("comet should not match a different pass")
+	}
+
+	pass := keys.Pass()
+	if p, err := ParsePass(pass.Atom()); err != nil || p != pass {
+		t.Fatal("pass did not round-trip through atom:", err)
+	} else if r, err := ParseRing(ring.Atom()); err != nil || r != ring {
+		t.Fatal("ring did not round-trip through atom:", err)
+	} else if _, err := ParsePass(ring.Atom()); err == nil {
+		t.Fatal("expected ring to be rejected as a pass")
+	} else if PassFromKeys(pass.Crypt(), pass.Auth()) != pass {
+		t.Fatal("pass did not round-trip through keys")
+	}
+
+	// keys whose last byte is zero have shorter atoms
+	pass[64], ring[64] = 0, 0
+	if p, err := ParsePass(pass.Atom()); err != nil || p != pass {
+		t.Fatal("pass with zero top byte did not round-trip:", err)
+	} else if r, err := ParseRing(ring.Atom()); err != nil || r != ring {
+		t.Fatal("ring with zero top byte did not round-trip:", err)
+	} else if _, err := ParseRing(atom.FromBytes(append([]byte{1}, flip(ring[:])...))); err == nil {
+		t.Fatal("expected overlong ring to be rejected")
+	}
+}
+
+func TestParseTicket(t *testing.T) {
+	tests := []struct {
+		ticket string
+		hex    string
+	}{
+		{"~zod", "00"},
+		{"~nec-marzod", "010100"},
+		{"~marbud-wannec", "01020301"},
+	}
+	for _, test := range tests {
+		b, err := ParseTicket(test.ticket)
+		if err != nil {
+			t.Error(err)
+		} else if hex.EncodeToString(b) != test.hex {
+			t.Errorf("wrong bytes for %v: expected %v, got %x", test.ticket, test.hex, b)
+		}
+	}
+	for _, bad := range []string{"", "zod", "~marzod-nec", "~marzo", "~zodzod"} {
+		if _, err := ParseTicket(bad); err == nil {
+			t.Error("expected error for", bad)
+		}
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	marzod, _ := PointFromName("~marzod")
+	if _, err := marzod.Child(0); err == nil {
+		t.Error("expected error for child index 0")
+	} else if _, err := AzimuthPoint(0).Child(256); err == nil {
+		t.Error("expected error for out-of-range star index")
+	} else if _, err := marzod.ChildPlanet(1).Child(1); err == nil {
+		t.Error("expected error for child of planet")
+	} else if c, err := marzod.Child(7); err != nil || c.Parent() != marzod || c.ChildIndex() != 7 {
+		t.Error("bad child:", c, err)
+	}
+
+	// list unspawned planets, given a set of spawned ones
+	spawned := map[AzimuthPoint]bool{
+		marzod.ChildPlanet(1): true,
+		marzod.ChildPlanet(3): true,
+	}
+	var unspawned []AzimuthPoint
+	marzod.Children(func(p AzimuthPoint) bool {
+		if !spawned[p] {
+			unspawned = append(unspawned, p)
+		}
+		return len(unspawned) < 3
+	})
+	if len(unspawned) != 3 || unspawned[0] != marzod.ChildPlanet(2) || unspawned[1] != marzod.ChildPlanet(4) || unspawned[2] != marzod.ChildPlanet(5) {
+		t.Error("wrong unspawned planets:", unspawned)
+	}
+
+	var n int
+	if err := AzimuthPoint(0).Children(func(AzimuthPoint) bool { n++; return true }); err != nil || n != 255 {
+		t.Error("galaxy should have 255 children, got", n, err)
+	} else if err := marzod.ChildPlanet(1).Children(func(AzimuthPoint) bool { return true }); err == nil {
+		t.Error("expected error iterating children of planet")
+	}
+
+	n = 0
+	marzod.Siblings(func(p AzimuthPoint) bool {
+		if p == marzod || p.Parent() != marzod.Parent() {
+			t.Fatal("bad sibling:", p)
+		}
+		n++
+		return true
+	})
+	if n != 254 {
+		t.Error("star should have 254 siblings, got", n)
+	}
+	n = 0
+	AzimuthPoint(0).Siblings(func(p AzimuthPoint) bool { n++; return true })
+	if n != 255 {
+		t.Error("galaxy should have 255 siblings, got", n)
+	}
+
+	planet := marzod.ChildPlanet(1000)
+	if as := planet.Ancestors(); len(as) != 2 || as[0] != marzod || as[1] != 0 {
+		t.Error("wrong ancestors:", as)
+	} else if !planet.IsDescendantOf(0) || planet.IsDescendantOf(1) || marzod.IsDescendantOf(marzod) {
+		t.Error("wrong descent")
+	} else if len(AzimuthPoint(0).Ancestors()) != 0 {
+		t.Error("galaxies should have no ancestors")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	p := AzimuthPoint(65792)
+	if s := fmt.Sprintf("%v %d %#x %q %12s", p, p, p, p, AzimuthPoint(256)); s != `~wicdev-wisryt 65792 0x10100 "~wicdev-wisryt"      ~marzod` {
+		t.Error("wrong formatting:", s)
+	}
+	js, _ := json.Marshal([]AzimuthPoint{0, p})
+	if string(js) != `["~zod","~wicdev-wisryt"]` {
+		t.Error("wrong JSON:", string(js))
+	}
+	var ps []AzimuthPoint
+	if err := json.Unmarshal([]byte(`["~zod", 256, "65792"]`), &ps); err != nil {
+		t.Fatal(err)
+	} else if len(ps) != 3 || ps[0] != 0 || ps[1] != 256 || ps[2] != p {
+		t.Error("wrong unmarshaled points:", ps)
+	}
+	if err := json.Unmarshal([]byte(`"~foo"`), &p); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	var q AzimuthPoint
+	for _, src := range []interface{}{int64(65792), "~wicdev-wisryt", []byte("65792")} {
+		if err := q.Scan(src); err != nil || q != 65792 {
+			t.Errorf("failed to scan %v: %v %v", src, q, err)
+		}
+	}
+	if err := q.Scan(int64(-1)); err == nil {
+		t.Error("expected error for negative point")
+	} else if v, _ := q.Value(); v != int64(65792) {
+		t.Error("wrong value:", v)
+	}
+
+	var c Comet
+	hex.Decode(c[:], []byte("1fe49fba73b5725bdb99f904e7acf465"))
+	var c2 Comet
+	if err := c2.UnmarshalText([]byte(c.String())); err != nil || c2 != c {
+		t.Error("comet did not round-trip:", c2, err)
+	}
+	c = Comet{}
+	if err := c2.Scan(c.String()); err != nil || c2 != c {
+		t.Error("comet did not round-trip:", c2, err)
+	}
+	if s := fmt.Sprintf("%x", c2); s != "00000000000000000000000000000000" {
+		t.Error("wrong hex:", s)
+	}
+	for _, n := range []string{"~zod", "~zod-zod-zod-zod-zod-zod-zod-zod", "~zod-zod-zod-zod--zod-zod-zod-zodzod"} {
+		if _, err := ParseComet(n); err == nil {
+			t.Error("expected error for", n)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Argon2 variants. argon2u is Urbit's variant: it uses data-dependent
+// addressing (like argon2d), but is domain-separated by its own type code.
+const (
+	argon2d  = 0
+	argon2i  = 1
+	argon2id = 2
+	argon2u  = 10
+)
+
+const (
+	argon2Version    = 0x13
+	argon2BlockWords = 128
+	argon2SyncPoints = 4
+)
+
+type argon2Block [argon2BlockWords]uint64
+
+type argon2Params struct {
+	mode    uint32
+	time    uint32
+	memory  uint32 // in KiB
+	threads uint32
+	keyLen  uint32
+	secret  []byte
+	data    []byte
+}
+
+// argon2 computes the Argon2 hash of password, as specified in RFC 9106. Unlike
+// golang.org/x/crypto/argon2, it supports data-dependent addressing, which
+// argon2u requires.
+func argon2(password, salt []byte, p argon2Params) []byte {
+	var h0 [blake2b.Size + 8]byte
+	h, _ := blake2b.New512(nil)
+	writeLE32 := func(v uint32) {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], v)
+		h.Write(buf[:])
+	}
+	writeBytes := func(b []byte) {
+		writeLE32(uint32(len(b)))
+		h.Write(b)
+	}
+	writeLE32(p.threads)
+	writeLE32(p.keyLen)
+	writeLE32(p.memory)
+	writeLE32(p.time)
+	writeLE32(argon2Version)
+	writeLE32(p.mode)
+	writeBytes(password)
+	writeBytes(salt)
+	writeBytes(p.secret)
+	writeBytes(p.data)
+	h.Sum(h0[:0])
+
+	// allocate memory, rounding down to a multiple of 4*threads blocks
+	memory := p.memory / (argon2SyncPoints * p.threads) * (argon2SyncPoints * p.threads)
+	if memory < 2*argon2SyncPoints*p.threads {
+		memory = 2 * argon2SyncPoints * p.threads
+	}
+	laneLen := memory / p.threads
+	segLen := laneLen / argon2SyncPoints
+	B := make([]argon2Block, memory)
+
+	// initialize the first two blocks of each lane
+	var buf [1024]byte
+	for lane := uint32(0); lane < p.threads; lane++ {
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2b.Size:], i)
+			argon2Hash(buf[:], h0[:])
+			for j := range B[lane*laneLen+i] {
+				B[lane*laneLen+i][j] = binary.LittleEndian.Uint64(buf[j*8:])
+			}
+		}
+	}
+
+	fillSegment := func(pass, slice, lane uint32) {
+		dataIndependent := p.mode == argon2i || (p.mode == argon2id && pass == 0 && slice < argon2SyncPoints/2)
+		var addresses, input, zero argon2Block
+		nextAddresses := func() {
+			input[6]++
+			argon2Compress(&addresses, &zero, &input, false)
+			argon2Compress(&addresses, &zero, &addresses, false)
+		}
+		if dataIndependent {
+			input[0] = uint64(pass)
+			input[1] = uint64(lane)
+			input[2] = uint64(slice)
+			input[3] = uint64(memory)
+			input[4] = uint64(p.time)
+			input[5] = uint64(p.mode)
+		}
+		start := uint32(0)
+		if pass == 0 && slice == 0 {
+			start = 2 // first two blocks are already initialized
+			if dataIndependent {
+				nextAddresses()
+			}
+		}
+		for index := start; index < segLen; index++ {
+			cur := lane*laneLen + slice*segLen + index
+			prev := cur - 1
+			if slice == 0 && index == 0 {
+				prev = lane*laneLen + laneLen - 1
+			}
+			var rand uint64
+			if dataIndependent {
+				if index%argon2BlockWords == 0 {
+					nextAddresses()
+				}
+				rand = addresses[index%argon2BlockWords]
+			} else {
+				rand = B[prev][0]
+			}
+
+			// determine reference block
+			refLane := uint32(rand>>32) % p.threads
+			if pass == 0 && slice == 0 {
+				refLane = lane
+			}
+			var area, startPos uint32
+			if pass == 0 {
+				area = slice * segLen
+				if refLane == lane {
+					area += index
+				}
+			} else {
+				area = laneLen - segLen
+				if refLane == lane {
+					area += index
+				}
+				startPos = ((slice + 1) % argon2SyncPoints) * segLen
+			}
+			if refLane == lane || index == 0 {
+				area--
+			}
+			x := (rand & 0xFFFFFFFF) * (rand & 0xFFFFFFFF) >> 32
+			y := uint64(area) * x >> 32
+			rel := uint64(area) - 1 - y
+			ref := refLane*laneLen + uint32((uint64(startPos)+rel)%uint64(laneLen))
+
+			argon2Compress(&B[cur], &B[prev], &B[ref], pass > 0)
+		}
+	}
+	for pass := uint32(0); pass < p.time; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < p.threads; lane++ {
+				wg.Add(1)
+				go func(lane uint32) {
+					defer wg.Done()
+					fillSegment(pass, slice, lane)
+				}(lane)
+			}
+			wg.Wait()
+		}
+	}
+
+	// xor the final column together and hash it
+	final := B[laneLen-1]
+	for lane := uint32(1); lane < p.threads; lane++ {
+		for i, w := range B[lane*laneLen+laneLen-1] {
+			final[i] ^= w
+		}
+	}
+	for i, w := range final {
+		binary.LittleEndian.PutUint64(buf[i*8:], w)
+	}
+	key := make([]byte, p.keyLen)
+	argon2Hash(key, buf[:])
+	return key
+}
+
+// argon2Hash is the variable-length hash function H' of RFC 9106.
+func argon2Hash(out, in []byte) {
+	var prefix [4]byte
+	binary.LittleEndian.PutUint32(prefix[:], uint32(len(out)))
+	if len(out) <= blake2b.Size {
+		h, _ := blake2b.New(len(out), nil)
+		h.Write(prefix[:])
+		h.Write(in)
+		h.Sum(out[:0])
+		return
+	}
+	h, _ := blake2b.New512(nil)
+	h.Write(prefix[:])
+	h.Write(in)
+	v := h.Sum(nil)
+	for len(out) > blake2b.Size {
+		n := copy(out, v[:32])
+		out = out[n:]
+		if len(out) > blake2b.Size {
+			next := blake2b.Sum512(v)
+			v = next[:]
+		}
+	}
+	h, _ = blake2b.New(len(out), nil)
+	h.Write(v)
+	h.Sum(out[:0])
+}
+
+// argon2Compress is the compression function G of RFC 9106. If xor is true, the
+// result is xored into out rather than overwriting it.
+func argon2Compress(out, x, y *argon2Block, xor bool) {
+	var r, q argon2Block
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	q = r
+	var v [16]uint64
+	for i := 0; i < argon2BlockWords; i += 16 {
+		copy(v[:], q[i:i+16])
+		blamkaRound(&v)
+		copy(q[i:i+16], v[:])
+	}
+	for i := 0; i < 16; i += 2 {
+		for j := 0; j < 8; j++ {
+			v[2*j], v[2*j+1] = q[16*j+i], q[16*j+i+1]
+		}
+		blamkaRound(&v)
+		for j := 0; j < 8; j++ {
+			q[16*j+i], q[16*j+i+1] = v[2*j], v[2*j+1]
+		}
+	}
+	for i := range q {
+		if xor {
+			out[i] ^= q[i] ^ r[i]
+		} else {
+			out[i] = q[i] ^ r[i]
+		}
+	}
+}
+
+func blamkaRound(v *[16]uint64) {
+	blamkaG(v, 0, 4, 8, 12)
+	blamkaG(v, 1, 5, 9, 13)
+	blamkaG(v, 2, 6, 10, 14)
+	blamkaG(v, 3, 7, 11, 15)
+	blamkaG(v, 0, 5, 10, 15)
+	blamkaG(v, 1, 6, 11, 12)
+	blamkaG(v, 2, 7, 8, 13)
+	blamkaG(v, 3, 4, 9, 14)
+}
+
+func blamkaG(v *[16]uint64, a, b, c, d int) {
+	fBlaMka := func(x, y uint64) uint64 {
+		return x + y + 2*uint64(uint32(x))*uint64(uint32(y))
+	}
+	v[a] = fBlaMka(v[a], v[b])
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] = fBlaMka(v[c], v[d])
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] = fBlaMka(v[a], v[b])
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] = fBlaMka(v[c], v[d])
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
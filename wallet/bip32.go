@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+const hardened = 0x80000000
+
+// An extendedKey is a BIP32 extended private key.
+type extendedKey struct {
+	key   [32]byte
+	chain [32]byte
+}
+
+func (k extendedKey) privateKey() *secp256k1.PrivateKey {
+	return secp256k1.PrivKeyFromBytes(k.key[:])
+}
+
+// newMasterKey derives a BIP32 master key from a seed.
+func newMasterKey(seed []byte) (extendedKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	return splitKey(mac.Sum(nil))
+}
+
+// child derives the i'th child of k.
+func (k extendedKey) child(i uint32) (extendedKey, error) {
+	mac := hmac.New(sha512.New, k.chain[:])
+	if i >= hardened {
+		mac.Write([]byte{0})
+		mac.Write(k.key[:])
+	} else {
+		mac.Write(k.privateKey().PubKey().SerializeCompressed())
+	}
+	binary.Write(mac, binary.BigEndian, i)
+	c, err := splitKey(mac.Sum(nil))
+	if err != nil {
+		return extendedKey{}, err
+	}
+	var parent, tweak secp256k1.ModNScalar
+	parent.SetBytes(&k.key)
+	tweak.SetBytes(&c.key)
+	c.key = tweak.Add(&parent).Bytes()
+	if c.key == ([32]byte{}) {
+		return extendedKey{}, errors.New("invalid child key")
+	}
+	return c, nil
+}
+
+// derivePath derives the descendant of k at the specified path, e.g.
+// m/44'/60'/0'/0/0.
+func (k extendedKey) derivePath(path string) (extendedKey, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return extendedKey{}, errors.New("derivation path must begin with m")
+	}
+	for _, p := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(p, "'") {
+			p, offset = p[:len(p)-1], hardened
+		}
+		i, err := strconv.ParseUint(p, 10, 31)
+		if err != nil {
+			return extendedKey{}, errors.New("invalid derivation path component")
+		}
+		if k, err = k.child(uint32(i) + offset); err != nil {
+			return extendedKey{}, err
+		}
+	}
+	return k, nil
+}
+
+func splitKey(i []byte) (extendedKey, error) {
+	var k extendedKey
+	copy(k.key[:], i[:32])
+	copy(k.chain[:], i[32:])
+	var s secp256k1.ModNScalar
+	if overflow := s.SetBytes(&k.key); overflow != 0 || s.IsZero() {
+		return extendedKey{}, errors.New("invalid key")
+	}
+	return k, nil
+}
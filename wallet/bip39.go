@@ -0,0 +1,39 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// entropyToMnemonic encodes entropy as a BIP39 mnemonic.
+func entropyToMnemonic(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", errors.New("invalid entropy length")
+	}
+	// append checksum bits, then split into 11-bit words
+	checksumBits := uint(len(entropy) / 4)
+	sum := sha256.Sum256(entropy)
+	n := new(big.Int).SetBytes(entropy)
+	n.Lsh(n, checksumBits)
+	n.Or(n, big.NewInt(int64(sum[0]>>(8-checksumBits))))
+
+	words := make([]string, (len(entropy)*8+int(checksumBits))/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = bip39Words[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// mnemonicToSeed derives a BIP39 seed from a mnemonic and passphrase. The
+// passphrase is not NFKD-normalized, so non-ASCII passphrases must be
+// normalized by the caller.
+func mnemonicToSeed(mnemonic, passphrase string) []byte {
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+}
@@ -0,0 +1,192 @@
+// Package wallet implements the Urbit HD wallet, which derives all of the keys
+// associated with an Azimuth point from a single master ticket.
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/urbit/ob"
+)
+
+// DerivationPath is the BIP32 path used to derive each node's keys.
+const DerivationPath = "m/44'/60'/0'/0/0"
+
+// Node types.
+const (
+	TypeOwnership  = "ownership"
+	TypeTransfer   = "transfer"
+	TypeSpawn      = "spawn"
+	TypeVoting     = "voting"
+	TypeManagement = "management"
+	TypeNetwork    = "network"
+)
+
+// Keys are the Ethereum keys of a wallet node.
+type Keys struct {
+	Public  []byte // compressed secp256k1 public key
+	Private []byte
+	Chain   []byte
+	Address string // EIP-55 checksummed
+}
+
+// A Node is a BIP39 seed, and the keys derived from it, that controls one
+// aspect of an Azimuth point.
+type Node struct {
+	Type string
+	Seed string // BIP39 mnemonic
+	Keys Keys
+}
+
+// A Network is the seed, and the keys derived from it, that a ship uses to
+// communicate over Ames.
+type Network struct {
+	Revision int
+	Seed     []byte
+	Keys     ob.NetworkKeys
+}
+
+// A Wallet is the full set of keys derived from a master ticket.
+type Wallet struct {
+	Ticket     string
+	Ship       ob.AzimuthPoint
+	Ownership  Node
+	Transfer   Node
+	Spawn      Node // only for galaxies and stars
+	Voting     Node // only for galaxies
+	Management Node
+	Network    Network
+}
+
+// MasterSeed stretches a master ticket into the seed from which each wallet
+// node is derived, using argon2u.
+func MasterSeed(ticket []byte, ship ob.AzimuthPoint) []byte {
+	salt := "urbitkeygen" + strconv.FormatUint(uint64(ship), 10)
+	return argon2(ticket, []byte(salt), argon2Params{
+		mode:    argon2u,
+		time:    1,
+		memory:  512000,
+		threads: 4,
+		keyLen:  32,
+	})
+}
+
+// DeriveNode derives the node of the specified type from a master seed. The
+// node's seed is the truncated SHA-256 hash of the type and master seed; its
+// keys are derived from that seed (and the passphrase) at DerivationPath.
+func DeriveNode(master []byte, typ string, passphrase string) (Node, error) {
+	entropy := deriveSeed(master, typ)
+	mnemonic, err := entropyToMnemonic(entropy)
+	if err != nil {
+		return Node{}, err
+	}
+	k, err := newMasterKey(mnemonicToSeed(mnemonic, passphrase))
+	if err != nil {
+		return Node{}, err
+	}
+	k, err = k.derivePath(DerivationPath)
+	if err != nil {
+		return Node{}, err
+	}
+	pub := k.privateKey().PubKey()
+	return Node{
+		Type: typ,
+		Seed: mnemonic,
+		Keys: Keys{
+			Public:  pub.SerializeCompressed(),
+			Private: append([]byte(nil), k.key[:]...),
+			Chain:   append([]byte(nil), k.chain[:]...),
+			Address: address(pub.SerializeUncompressed()),
+		},
+	}, nil
+}
+
+// DeriveNetworkSeed derives the network seed for the specified revision of a
+// point's networking keys from its management mnemonic.
+func DeriveNetworkSeed(management, passphrase string, revision int) []byte {
+	return deriveSeed(mnemonicToSeed(management, passphrase), TypeNetwork+strconv.Itoa(revision))
+}
+
+// DeriveNetwork derives the networking keys for the specified revision from a
+// management mnemonic. The network seed is treated as a big-endian atom, i.e.
+// its bytes are reversed before being passed to ob.DeriveNetworkKeys.
+func DeriveNetwork(management, passphrase string, revision int) Network {
+	seed := DeriveNetworkSeed(management, passphrase, revision)
+	le := make([]byte, len(seed))
+	for i := range le {
+		le[i] = seed[len(seed)-i-1]
+	}
+	return Network{
+		Revision: revision,
+		Seed:     seed,
+		Keys:     ob.DeriveNetworkKeys(le),
+	}
+}
+
+// Generate derives the full Wallet for ship from a @q master ticket.
+func Generate(ticket string, ship ob.AzimuthPoint, passphrase string, revision int) (Wallet, error) {
+	tb, err := ob.ParseTicket(ticket)
+	if err != nil {
+		return Wallet{}, err
+	}
+	master := MasterSeed(tb, ship)
+	w := Wallet{
+		Ticket: ticket,
+		Ship:   ship,
+	}
+	nodes := map[string]*Node{
+		TypeOwnership:  &w.Ownership,
+		TypeTransfer:   &w.Transfer,
+		TypeSpawn:      &w.Spawn,
+		TypeVoting:     &w.Voting,
+		TypeManagement: &w.Management,
+	}
+	if ship.IsPlanet() {
+		delete(nodes, TypeSpawn)
+	}
+	if !ship.IsGalaxy() {
+		delete(nodes, TypeVoting)
+	}
+	for typ, n := range nodes {
+		if *n, err = DeriveNode(master, typ, passphrase); err != nil {
+			return Wallet{}, err
+		}
+	}
+	w.Network = DeriveNetwork(w.Management.Seed, passphrase, revision)
+	return w, nil
+}
+
+func deriveSeed(master []byte, typ string) []byte {
+	h := sha256.New()
+	h.Write([]byte(typ))
+	h.Write(master)
+	seed := h.Sum(nil)
+	if len(master) < len(seed) {
+		seed = seed[:len(master)]
+	}
+	return seed
+}
+
+// address returns the EIP-55 checksummed Ethereum address of an uncompressed
+// secp256k1 public key.
+func address(pub []byte) string {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(pub[1:])
+	addr := hex.EncodeToString(h.Sum(nil)[12:])
+
+	h = sha3.NewLegacyKeccak256()
+	h.Write([]byte(addr))
+	sum := h.Sum(nil)
+	var sb strings.Builder
+	sb.WriteString("0x")
+	for i, c := range addr {
+		if c >= 'a' && sum[i/2]>>(4-uint(i%2)*4)&0xF >= 8 {
+			c -= 'a' - 'A'
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
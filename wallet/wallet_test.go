@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	xargon2 "golang.org/x/crypto/argon2"
+)
+
+func TestArgon2(t *testing.T) {
+	// test vectors from RFC 9106
+	params := argon2Params{
+		time:    3,
+		memory:  32,
+		threads: 4,
+		keyLen:  32,
+		secret:  bytes.Repeat([]byte{3}, 8),
+		data:    bytes.Repeat([]byte{4}, 12),
+	}
+	password := bytes.Repeat([]byte{1}, 32)
+	salt := bytes.Repeat([]byte{2}, 16)
+	tests := []struct {
+		mode uint32
+		exp  string
+	}{
+		{argon2d, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"},
+		{argon2i, "c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8"},
+		{argon2id, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"},
+	}
+	for _, test := range tests {
+		params.mode = test.mode
+		if got := hex.EncodeToString(argon2(password, salt, params)); got != test.exp {
+			t.Errorf("wrong hash for mode %v:\nexp: %v\ngot: %v", test.mode, test.exp, got)
+		}
+	}
+
+	// compare against x/crypto, which lacks secret and data parameters
+	params = argon2Params{time: 2, memory: 1024, threads: 3, keyLen: 100}
+	params.mode = argon2i
+	if !bytes.Equal(argon2(password, salt, params), xargon2.Key(password, salt, 2, 1024, 3, 100)) {
+		t.Error("argon2i mismatch with x/crypto")
+	}
+	params.mode = argon2id
+	if !bytes.Equal(argon2(password, salt, params), xargon2.IDKey(password, salt, 2, 1024, 3, 100)) {
+		t.Error("argon2id mismatch with x/crypto")
+	}
+}
+
+func TestBIP39(t *testing.T) {
+	tests := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+	}
+	for _, test := range tests {
+		entropy, _ := hex.DecodeString(test.entropy)
+		m, err := entropyToMnemonic(entropy)
+		if err != nil {
+			t.Fatal(err)
+		} else if m != test.mnemonic {
+			t.Errorf("wrong mnemonic:\nexp: %v\ngot: %v", test.mnemonic, m)
+		} else if seed := hex.EncodeToString(mnemonicToSeed(m, "TREZOR")); seed != test.seed {
+			t.Errorf("wrong seed:\nexp: %v\ngot: %v", test.seed, seed)
+		}
+	}
+	if _, err := entropyToMnemonic(make([]byte, 15)); err == nil {
+		t.Error("expected error for invalid entropy length")
+	}
+}
+
+func TestBIP32(t *testing.T) {
+	// test vector 1 from BIP32
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	m, err := newMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		key   string
+		chain string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", "c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e"},
+	}
+	for _, test := range tests {
+		k, err := m.derivePath(test.path)
+		if err != nil {
+			t.Fatal(err)
+		} else if hex.EncodeToString(k.key[:]) != test.key {
+			t.Errorf("wrong key for %v: %x", test.path, k.key)
+		} else if hex.EncodeToString(k.chain[:]) != test.chain {
+			t.Errorf("wrong chain code for %v: %x", test.path, k.chain)
+		}
+	}
+	for _, bad := range []string{"", "n/0", "m/x", "m/0''"} {
+		if _, err := m.derivePath(bad); err == nil {
+			t.Error("expected error for path", bad)
+		}
+	}
+}
+
+func TestAddress(t *testing.T) {
+	sk, _ := hex.DecodeString("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	pub := secp256k1.PrivKeyFromBytes(sk).PubKey().SerializeUncompressed()
+	if addr := address(pub); addr != "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23" {
+		t.Fatal("wrong address:", addr)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping wallet generation in short mode")
+	}
+	w, err := Generate("~wacfus-dabpex-danted-mosfep", 65012, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Spawn.Type != TypeSpawn || w.Voting.Type != "" {
+		t.Error("stars should have a spawn node, but no voting node")
+	}
+	if w.Ownership.Seed != "deposit pencil tumble cluster chapter maple before label force ability barrel scrap exact monitor shoot grace maid wrong improve survey swing sunny primary identify" {
+		t.Error("wrong ownership seed:", w.Ownership.Seed)
+	} else if w.Ownership.Keys.Address != "0x28EC555Ef62D355ec3289277fd9dc41020e88884" {
+		t.Error("wrong ownership address:", w.Ownership.Keys.Address)
+	} else if w.Management.Keys.Address != "0x7f69D03316cd7cC780b7cAed583aaDa84E9D8184" {
+		t.Error("wrong management address:", w.Management.Keys.Address)
+	} else if hex.EncodeToString(w.Network.Seed) != "76e62462501049e6ef1e33150872c731337f931f271abb76badd3d736c5aad96" {
+		t.Error("wrong network seed:", hex.EncodeToString(w.Network.Seed))
+	}
+
+	// a new revision should produce new network keys, but leave the rest intact
+	next := DeriveNetwork(w.Management.Seed, "", 1)
+	if bytes.Equal(next.Seed, w.Network.Seed) || next.Keys.Pass() == w.Network.Keys.Pass() {
+		t.Error("network keys should change with revision")
+	}
+}